@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS user_salary_rates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    salary_shift INT DEFAULT 0,
+    salary_hookah DOUBLE DEFAULT 0,
+    hookah_salary_type VARCHAR(10) NOT NULL DEFAULT 'percent',
+    salary_bar DOUBLE DEFAULT 0,
+    effective_from DATE NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    KEY idx_user_salary_rates_user (user_id, effective_from)
+);
+
+-- Текущие ставки считаются действующими с начала истории
+INSERT INTO user_salary_rates (user_id, company_id, branch_id, salary_shift, salary_hookah, hookah_salary_type, salary_bar, effective_from)
+SELECT id, company_id, branch_id, salary_shift, salary_hookah, hookah_salary_type, salary_bar, '2000-01-01' FROM users;
@@ -0,0 +1,23 @@
+ALTER TABLE expenses
+    ADD COLUMN due_date DATE NULL,
+    ADD COLUMN counterparty VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN paid_amount DOUBLE NOT NULL DEFAULT 0;
+
+-- Уже оплаченные расходы считаются погашенными полностью
+UPDATE expenses SET paid_amount = total WHERE paid = 1;
+
+CREATE TABLE IF NOT EXISTS expense_payments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    expense_id INT NOT NULL,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    amount DOUBLE NOT NULL,
+    payment_type_id INT NULL,
+    from_cashbox BOOLEAN NOT NULL DEFAULT FALSE,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    user_id INT NULL,
+    paid_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (expense_id) REFERENCES expenses(id) ON DELETE CASCADE,
+    KEY idx_expense_payments_expense (expense_id)
+);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    title VARCHAR(100) NOT NULL,
+    category_id INT NULL,
+    total DOUBLE NOT NULL DEFAULT 0,
+    description VARCHAR(300),
+    counterparty VARCHAR(255) NOT NULL DEFAULT '',
+    schedule VARCHAR(10) NOT NULL DEFAULT 'monthly',
+    day_of_month INT NOT NULL DEFAULT 1,
+    day_of_week INT NOT NULL DEFAULT 1,
+    cron VARCHAR(100) NOT NULL DEFAULT '',
+    mark_paid BOOLEAN NOT NULL DEFAULT FALSE,
+    due_days INT NOT NULL DEFAULT 0,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    next_run DATE NOT NULL,
+    last_run DATE NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (category_id) REFERENCES expense_categories(id) ON DELETE SET NULL,
+    KEY idx_recurring_expenses_next_run (paused, next_run)
+);
+
+ALTER TABLE expenses ADD COLUMN recurring_expense_id INT NULL;
+-- Один расход на дату для шаблона, чтобы повторный запуск планировщика не дублировал записи
+ALTER TABLE expenses ADD UNIQUE KEY idx_expenses_recurring_date (recurring_expense_id, date);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL,
+    branch_id INT NOT NULL,
+    user_id INT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT '',
+    entity VARCHAR(50) NOT NULL,
+    entity_id INT NOT NULL DEFAULT 0,
+    action VARCHAR(50) NOT NULL,
+    diff JSON NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_audit_log_tenant (company_id, branch_id, created_at),
+    KEY idx_audit_log_entity (entity, entity_id)
+);
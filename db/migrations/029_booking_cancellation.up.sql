@@ -0,0 +1,37 @@
+ALTER TABLE bookings
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN cancel_reason VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN cancelled_at DATETIME NULL,
+    ADD COLUMN cancelled_by INT NULL;
+ALTER TABLE bookings ADD KEY idx_bookings_status (company_id, branch_id, status);
+
+-- Возвраты по отменённым броням в разрезе типов оплат
+CREATE TABLE IF NOT EXISTS booking_refunds (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    booking_id INT NOT NULL,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    payment_type_id INT NULL,
+    amount INT NOT NULL,
+    user_id INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE,
+    KEY idx_booking_refunds_booking (booking_id)
+);
+
+-- Движения остатков и бонусов, выполненные при отмене брони
+CREATE TABLE IF NOT EXISTS booking_ledger (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    booking_id INT NOT NULL,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    entry_type VARCHAR(20) NOT NULL,
+    item_id INT NULL,
+    client_id INT NULL,
+    quantity DOUBLE NOT NULL DEFAULT 0,
+    amount INT NOT NULL DEFAULT 0,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE,
+    KEY idx_booking_ledger_booking (booking_id)
+);
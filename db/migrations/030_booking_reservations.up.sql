@@ -0,0 +1,10 @@
+-- Депозиты по предварительным броням хранятся среди оплат брони
+ALTER TABLE booking_payments
+    ADD COLUMN is_deposit TINYINT(1) NOT NULL DEFAULT 0,
+    ADD COLUMN created_at DATETIME DEFAULT CURRENT_TIMESTAMP;
+
+-- Правила удержания депозита
+ALTER TABLE settings
+    ADD COLUMN deposit_forfeit_percent INT NOT NULL DEFAULT 100,
+    ADD COLUMN reservation_grace_minutes INT NOT NULL DEFAULT 15,
+    ADD COLUMN deposit_refund_hours INT NOT NULL DEFAULT 24;
@@ -0,0 +1,2 @@
+-- Перерыв на уборку между сеансами за одним столом
+ALTER TABLE settings ADD COLUMN cleanup_buffer_minutes INT NOT NULL DEFAULT 0;
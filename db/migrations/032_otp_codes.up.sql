@@ -0,0 +1,14 @@
+-- Одноразовые коды подтверждения телефона
+CREATE TABLE IF NOT EXISTS otp_codes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL,
+    branch_id INT NOT NULL,
+    phone VARCHAR(32) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_otp_codes_phone (company_id, branch_id, phone, purpose)
+);
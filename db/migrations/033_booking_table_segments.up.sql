@@ -0,0 +1,16 @@
+-- История столов брони: при переносе и объединении сеансов
+CREATE TABLE IF NOT EXISTS booking_table_segments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    booking_id INT NOT NULL,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    table_id INT NOT NULL,
+    start_time DATETIME NOT NULL,
+    end_time DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE,
+    KEY idx_booking_table_segments_booking (booking_id),
+    KEY idx_booking_table_segments_table (company_id, branch_id, table_id, start_time)
+);
+
+ALTER TABLE bookings ADD COLUMN merged_into INT NULL;
@@ -0,0 +1,25 @@
+-- Разделение счета брони между несколькими клиентами
+CREATE TABLE IF NOT EXISTS booking_shares (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    booking_id INT NOT NULL,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    client_id INT NULL,
+    amount INT NOT NULL DEFAULT 0,
+    bonus_used INT NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE,
+    KEY idx_booking_shares_booking (booking_id)
+);
+
+-- Позиции счета, выбранные участником при разделении по позициям
+CREATE TABLE IF NOT EXISTS booking_share_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    share_id INT NOT NULL,
+    booking_item_id INT NOT NULL,
+    quantity DOUBLE NOT NULL DEFAULT 0,
+    amount INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (share_id) REFERENCES booking_shares(id) ON DELETE CASCADE
+);
+
+ALTER TABLE booking_payments ADD COLUMN share_id INT NULL;
@@ -0,0 +1,8 @@
+-- Позиции открытого счета: автор, статус и отмена отдельных строк
+ALTER TABLE booking_items
+    ADD COLUMN user_id INT NULL,
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN void_reason VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN voided_at DATETIME NULL,
+    ADD COLUMN voided_by INT NULL;
+ALTER TABLE booking_items ADD KEY idx_booking_items_booking_status (booking_id, status);
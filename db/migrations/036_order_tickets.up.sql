@@ -0,0 +1,42 @@
+-- Станции приготовления (бар, кальянная) и очередь заказов для них
+CREATE TABLE IF NOT EXISTS stations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    name VARCHAR(100) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_stations_name (company_id, branch_id, name)
+);
+
+ALTER TABLE categories
+    ADD COLUMN is_prepared TINYINT(1) NOT NULL DEFAULT 0,
+    ADD COLUMN station_id INT NULL;
+
+-- бар и кальян готовятся на одноименных станциях
+INSERT IGNORE INTO stations (company_id, branch_id, name)
+    SELECT company_id, branch_id, name FROM categories WHERE name IN ('Бар', 'Кальян');
+UPDATE categories c
+    JOIN stations s ON s.company_id = c.company_id AND s.branch_id = c.branch_id AND s.name = c.name
+    SET c.is_prepared = 1, c.station_id = s.id
+    WHERE c.name IN ('Бар', 'Кальян');
+
+CREATE TABLE IF NOT EXISTS order_tickets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    station_id INT NOT NULL,
+    booking_id INT NOT NULL,
+    booking_item_id INT NULL,
+    item_id INT NOT NULL,
+    quantity DOUBLE NOT NULL DEFAULT 0,
+    table_id INT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'new',
+    user_id INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    started_at DATETIME NULL,
+    served_at DATETIME NULL,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE,
+    FOREIGN KEY (station_id) REFERENCES stations(id) ON DELETE CASCADE,
+    KEY idx_order_tickets_station (station_id, status),
+    KEY idx_order_tickets_line (booking_item_id)
+);
@@ -0,0 +1,66 @@
+-- Абонементы: пакеты часов или посещений, которые клиент покупает заранее
+CREATE TABLE IF NOT EXISTS pass_types (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'hours',
+    amount DOUBLE NOT NULL DEFAULT 0,
+    price INT NOT NULL DEFAULT 0,
+    validity_days INT NOT NULL DEFAULT 30,
+    is_active TINYINT(1) NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- категории столов, на которых действует абонемент, пустой список - все
+CREATE TABLE IF NOT EXISTS pass_type_categories (
+    pass_type_id INT NOT NULL,
+    table_category_id INT NOT NULL,
+    PRIMARY KEY (pass_type_id, table_category_id),
+    FOREIGN KEY (pass_type_id) REFERENCES pass_types(id) ON DELETE CASCADE,
+    FOREIGN KEY (table_category_id) REFERENCES table_categories(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS client_passes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    client_id INT NOT NULL,
+    pass_type_id INT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    total DOUBLE NOT NULL DEFAULT 0,
+    balance DOUBLE NOT NULL DEFAULT 0,
+    price INT NOT NULL DEFAULT 0,
+    payment_type_id INT NULL,
+    user_id INT NULL,
+    sold_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE,
+    FOREIGN KEY (pass_type_id) REFERENCES pass_types(id),
+    KEY idx_client_passes_client (company_id, branch_id, client_id),
+    KEY idx_client_passes_sold (company_id, branch_id, sold_at)
+);
+
+-- оплата абонементом не связана с типом оплаты кассы
+ALTER TABLE booking_payments
+    MODIFY payment_type_id INT NULL,
+    ADD COLUMN method VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN client_pass_id INT NULL;
+
+CREATE TABLE IF NOT EXISTS pass_redemptions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    client_pass_id INT NOT NULL,
+    booking_id INT NOT NULL,
+    booking_item_id INT NOT NULL,
+    payment_id INT NULL,
+    units DOUBLE NOT NULL DEFAULT 0,
+    amount INT NOT NULL DEFAULT 0,
+    user_id INT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    returned_at DATETIME NULL,
+    FOREIGN KEY (client_pass_id) REFERENCES client_passes(id) ON DELETE CASCADE,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE,
+    KEY idx_pass_redemptions_line (booking_id, booking_item_id)
+);
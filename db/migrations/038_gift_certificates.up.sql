@@ -0,0 +1,19 @@
+-- Подарочные сертификаты с кодом и остатком номинала
+CREATE TABLE IF NOT EXISTS gift_certificates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    code VARCHAR(32) NOT NULL,
+    nominal INT NOT NULL DEFAULT 0,
+    balance INT NOT NULL DEFAULT 0,
+    purchaser_client_id INT NULL,
+    purchaser_name VARCHAR(255) NOT NULL DEFAULT '',
+    payment_type_id INT NULL,
+    user_id INT NULL,
+    sold_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    UNIQUE KEY uq_gift_certificates_code (company_id, code),
+    KEY idx_gift_certificates_sold (company_id, branch_id, sold_at)
+);
+
+ALTER TABLE booking_payments ADD COLUMN gift_certificate_id INT NULL;
@@ -0,0 +1,47 @@
+-- Акции и промокоды, которые применяются к счету брони автоматически
+CREATE TABLE IF NOT EXISTS promotions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'percent',
+    value INT NOT NULL DEFAULT 0,
+    category_id INT NULL,
+    item_id INT NULL,
+    days_of_week VARCHAR(7) NOT NULL DEFAULT '',
+    time_from VARCHAR(5) NOT NULL DEFAULT '',
+    time_to VARCHAR(5) NOT NULL DEFAULT '',
+    valid_from DATETIME NULL,
+    valid_to DATETIME NULL,
+    min_spend INT NOT NULL DEFAULT 0,
+    min_client_visits INT NOT NULL DEFAULT 0,
+    code VARCHAR(32) NULL,
+    usage_limit INT NOT NULL DEFAULT 0,
+    used_count INT NOT NULL DEFAULT 0,
+    stackable TINYINT(1) NOT NULL DEFAULT 0,
+    priority INT NOT NULL DEFAULT 0,
+    is_active TINYINT(1) NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_promotions_code (company_id, code)
+);
+
+ALTER TABLE bookings
+    ADD COLUMN promo_code VARCHAR(32) NULL,
+    ADD COLUMN promotion_id INT NULL;
+
+ALTER TABLE booking_items ADD COLUMN promotion_id INT NULL;
+
+-- какая акция дала какую скидку, по строке счета или по всей брони
+CREATE TABLE IF NOT EXISTS booking_promotions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    booking_id INT NOT NULL,
+    booking_item_id INT NULL,
+    promotion_id INT NOT NULL,
+    amount INT NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE,
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id) ON DELETE CASCADE,
+    KEY idx_booking_promotions_booking (booking_id)
+);
@@ -0,0 +1,22 @@
+-- Денежный счет клиента (деньги "на счету"), отдельно от бонусов
+ALTER TABLE clients ADD COLUMN wallet_balance INT NOT NULL DEFAULT 0;
+
+-- журнал всех изменений счета клиента
+CREATE TABLE IF NOT EXISTS client_wallet_entries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL DEFAULT 1,
+    branch_id INT NOT NULL DEFAULT 1,
+    client_id INT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    amount INT NOT NULL,
+    balance_after INT NOT NULL,
+    payment_type_id INT NULL,
+    booking_id INT NULL,
+    user_id INT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE,
+    KEY idx_client_wallet_entries_client (client_id, created_at)
+);
+
+ALTER TABLE booking_payments ADD COLUMN wallet_client_id INT NULL;
@@ -0,0 +1,18 @@
+-- Доступ сотрудников к нескольким филиалам компании, в каждом своя роль
+CREATE TABLE IF NOT EXISTS user_branches (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    company_id INT NOT NULL,
+    branch_id INT NOT NULL,
+    role VARCHAR(50) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_user_branches (user_id, branch_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- основной филиал каждого сотрудника
+INSERT IGNORE INTO user_branches (user_id, company_id, branch_id, role)
+SELECT id, company_id, branch_id, role FROM users;
+
+-- филиал, в котором работает сессия refresh-токена
+ALTER TABLE refresh_tokens ADD COLUMN branch_id INT NULL;
@@ -0,0 +1,9 @@
+-- У каждого филиала свой каталог: названия категорий и позиций прайса
+-- уникальны в пределах филиала, а не во всей базе
+ALTER TABLE categories DROP INDEX idx_categories_name, ADD UNIQUE KEY idx_categories_name (company_id, branch_id, name);
+ALTER TABLE price_items DROP INDEX idx_price_items_name, ADD UNIQUE KEY idx_price_items_name (company_id, branch_id, name);
+
+-- касса для филиалов, у которых ее еще нет
+INSERT INTO cashbox (amount, company_id, branch_id)
+SELECT 0, b.company_id, b.id FROM branches b
+WHERE NOT EXISTS (SELECT 1 FROM cashbox c WHERE c.company_id = b.company_id AND c.branch_id = b.id);
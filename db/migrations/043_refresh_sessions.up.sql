@@ -0,0 +1,8 @@
+-- Несколько сессий на пользователя: каждый refresh-токен - отдельное
+-- устройство, при обновлении токена запись сохраняется
+ALTER TABLE refresh_tokens
+    ADD COLUMN device_name VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN ip VARCHAR(45) NOT NULL DEFAULT '',
+    ADD COLUMN user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN last_used_at DATETIME NULL,
+    ADD KEY idx_refresh_tokens_hash (token_hash);
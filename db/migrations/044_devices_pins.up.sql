@@ -0,0 +1,24 @@
+-- Общие планшеты администраторов: директор подключает устройство к филиалу,
+-- на подключенном устройстве сотрудники входят по PIN-коду
+CREATE TABLE IF NOT EXISTS branch_devices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL,
+    branch_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(255) NOT NULL,
+    created_by INT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME NULL,
+    UNIQUE KEY uq_branch_devices_token (token_hash),
+    KEY idx_branch_devices_branch (company_id, branch_id)
+);
+
+-- PIN-код сотрудника и блокировка после неудачных попыток
+CREATE TABLE IF NOT EXISTS user_pins (
+    user_id INT PRIMARY KEY,
+    pin_hash VARCHAR(255) NOT NULL,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    locked_until DATETIME NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
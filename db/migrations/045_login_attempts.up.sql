@@ -0,0 +1,16 @@
+-- Журнал попыток входа: по нему считаются задержки и блокировка входа по
+-- телефону и IP, директор видит попытки сотрудников своей компании
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    phone VARCHAR(50) NOT NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    user_id INT NULL,
+    company_id INT NULL,
+    success TINYINT(1) NOT NULL DEFAULT 0,
+    reason VARCHAR(50) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    KEY idx_login_attempts_phone (phone, created_at),
+    KEY idx_login_attempts_ip (ip, created_at),
+    KEY idx_login_attempts_company (company_id, created_at)
+);
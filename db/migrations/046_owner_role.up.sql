@@ -0,0 +1,27 @@
+-- Миграция данных: назначение владельцев существующих компаний.
+--
+-- Роль owner дает доступ к сводным отчетам по всем филиалам компании.
+-- Раньше при регистрации компании ее создатель получал роль director, поэтому
+-- у компаний, открытых до появления роли, владельца нет.
+-- Владельцем становится создатель компании - директор с наименьшим id.
+-- Компании, где владелец уже есть, не затрагиваются.
+-- Каждое назначение записывается в журнал изменений (entity user, action promote),
+-- чтобы его можно было найти и при необходимости вернуть роль director вручную.
+
+INSERT INTO audit_log (company_id, branch_id, user_id, role, entity, entity_id, action, diff)
+SELECT u.company_id, u.branch_id, NULL, '', 'user', u.id, 'promote',
+       JSON_OBJECT('role', JSON_OBJECT('old', 'director', 'new', 'owner'))
+FROM users u
+JOIN (SELECT company_id, MIN(id) AS id FROM users WHERE role = 'director' GROUP BY company_id) f ON f.id = u.id
+WHERE NOT EXISTS (SELECT 1 FROM users o WHERE o.company_id = u.company_id AND o.role = 'owner');
+
+UPDATE users u
+JOIN (SELECT company_id, MIN(id) AS id FROM users WHERE role = 'director' GROUP BY company_id) f ON f.id = u.id
+LEFT JOIN (SELECT DISTINCT company_id FROM users WHERE role = 'owner') o ON o.company_id = u.company_id
+SET u.role = 'owner'
+WHERE o.company_id IS NULL;
+
+UPDATE user_branches ub
+JOIN users u ON u.id = ub.user_id
+SET ub.role = 'owner'
+WHERE u.role = 'owner';
@@ -0,0 +1,8 @@
+-- Филиалы, открытые после 036 по шаблону, остались без станций: бар и
+-- кальян готовятся на одноименных станциях, как и в 036
+INSERT IGNORE INTO stations (company_id, branch_id, name)
+    SELECT company_id, branch_id, name FROM categories WHERE name IN ('Бар', 'Кальян') AND station_id IS NULL;
+UPDATE categories c
+    JOIN stations s ON s.company_id = c.company_id AND s.branch_id = c.branch_id AND s.name = c.name
+    SET c.is_prepared = 1, c.station_id = s.id
+    WHERE c.name IN ('Бар', 'Кальян') AND c.station_id IS NULL;
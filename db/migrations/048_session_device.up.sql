@@ -0,0 +1,5 @@
+-- Сессия входа по PIN помнит планшет, при отключении планшета его сессии
+-- удаляются
+ALTER TABLE refresh_tokens
+    ADD COLUMN device_id INT NULL,
+    ADD KEY idx_refresh_tokens_device (device_id);
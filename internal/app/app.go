@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/gin-gonic/gin"
@@ -10,6 +11,8 @@ import (
 	"psclub-crm/internal/config"
 	"psclub-crm/internal/handlers"
 	"psclub-crm/internal/middleware"
+	"psclub-crm/internal/ratelimit"
+	"psclub-crm/internal/realtime"
 	"psclub-crm/internal/repositories"
 	"psclub-crm/internal/routes"
 	"psclub-crm/internal/services"
@@ -29,9 +32,25 @@ func Run() {
 	defer db.Close()
 	// ========== Инициализация зависимостей ==========
 
-	// Клиенты
+	// Шаблоны для новых клубов
+	seedTemplates, err := config.LoadTemplates("./config/templates")
+	if err != nil {
+		log.Fatal("Failed to load club templates: ", err)
+	}
+	if len(seedTemplates) == 0 {
+		seedTemplates = append(seedTemplates, config.DefaultTemplate())
+	}
+	templateService := services.NewTemplateService(seedTemplates, "playstation")
+
+	// Журнал изменений
+	auditRepo := repositories.NewAuditRepository(db)
+	auditService := services.NewAuditService(auditRepo)
+	auditHandler := handlers.NewAuditHandler(auditService)
+
+	// Клиенты и их абонементы
 	clientRepo := repositories.NewClientRepository(db)
-	clientService := services.NewClientService(clientRepo)
+	passRepo := repositories.NewPassRepository(db)
+	clientService := services.NewClientService(clientRepo, passRepo)
 	clientHandler := handlers.NewClientHandler(clientService)
 
 	// Каналы привлечения
@@ -42,17 +61,31 @@ func Run() {
 	// Сотрудники (Users)
 	userRepo := repositories.NewUserRepository(db)
 	tokenRepo := repositories.NewTokenRepository(db)
-	userService := services.NewUserService(userRepo)
+	salaryRateRepo := repositories.NewUserSalaryRateRepository(db)
+	userBranchRepo := repositories.NewUserBranchRepository(db)
+	companyRepo := repositories.NewCompanyRepository(db)
+	deviceRepo := repositories.NewDeviceRepository(db)
+	pinRepo := repositories.NewPinRepository(db)
+	loginAttemptRepo := repositories.NewLoginAttemptRepository(db)
+	userService := services.NewUserService(userRepo, salaryRateRepo, userBranchRepo, companyRepo, tokenRepo, auditService)
 	userHandler := handlers.NewUserHandler(userService)
 	authService := services.NewAuthService(
 		userRepo,
 		tokenRepo,
+		userBranchRepo,
+		companyRepo,
+		deviceRepo,
+		pinRepo,
+		loginAttemptRepo,
+		templateService,
 		cfg.Auth.AccessSecret,
 		cfg.Auth.RefreshSecret,
 		time.Duration(cfg.Auth.AccessTTL)*time.Second,
 		time.Duration(cfg.Auth.RefreshTTL)*time.Second,
+		time.Duration(cfg.Auth.PinTTL)*time.Second,
 	)
 	authHandler := handlers.NewAuthHandler(authService)
+	deviceHandler := handlers.NewDeviceHandler(authService)
 
 	// Категории столов
 	tableCategoryRepo := repositories.NewTableCategoryRepository(db)
@@ -83,11 +116,11 @@ func Run() {
 	priceRepo := repositories.NewPriceItemRepository(db)
 	historyRepo := repositories.NewPriceItemHistoryRepository(db)
 	plHistoryRepo := repositories.NewPricelistHistoryRepository(db)
-	priceService := services.NewPriceItemService(priceRepo, historyRepo, plHistoryRepo)
+	priceService := services.NewPriceItemService(priceRepo, historyRepo, plHistoryRepo, auditService)
 
 	// Сеты товаров
 	priceSetRepo := repositories.NewPriceSetRepository(db)
-	priceSetService := services.NewPriceSetService(priceSetRepo, priceRepo, categoryRepo)
+	priceSetService := services.NewPriceSetService(priceSetRepo, priceRepo, categoryRepo, auditService)
 	priceSetHandler := handlers.NewPriceSetHandler(priceSetService)
 
 	// Бронирования (инициализируем позже, после кассы)
@@ -95,6 +128,10 @@ func Run() {
 	settingsRepo := repositories.NewSettingsRepository(db)
 	bookingRepo := repositories.NewBookingRepository(db)
 	bookingPaymentRepo := repositories.NewBookingPaymentRepository(db)
+	bookingRefundRepo := repositories.NewBookingRefundRepository(db)
+	bookingLedgerRepo := repositories.NewBookingLedgerRepository(db)
+	bookingSegmentRepo := repositories.NewBookingSegmentRepository(db)
+	bookingShareRepo := repositories.NewBookingShareRepository(db)
 
 	// Категории расходов и сами расходы
 	expCatRepo := repositories.NewExpenseCategoryRepository(db)
@@ -110,9 +147,15 @@ func Run() {
 	expenseService := services.NewExpenseService(expenseRepo)
 	expenseHandler := handlers.NewExpenseHandler(expenseService)
 
+	// Регулярные расходы, планировщик проверяет шаблоны раз в час
+	recurringExpenseRepo := repositories.NewRecurringExpenseRepository(db)
+	recurringExpenseService := services.NewRecurringExpenseService(recurringExpenseRepo, expenseRepo, expenseService)
+	recurringExpenseHandler := handlers.NewRecurringExpenseHandler(recurringExpenseService)
+	recurringExpenseService.StartScheduler(context.Background(), time.Hour)
+
 	// Инвентаризация
 	invHistRepo := repositories.NewInventoryHistoryRepository(db)
-	inventoryService := services.NewInventoryService(priceRepo, invHistRepo, expenseService, expCatService)
+	inventoryService := services.NewInventoryService(priceRepo, invHistRepo, expenseService, expCatService, auditService)
 	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
 
 	// Оборудование
@@ -134,9 +177,41 @@ func Run() {
 	// Касса
 	cashboxRepo := repositories.NewCashboxRepository(db)
 	cashboxHistRepo := repositories.NewCashboxHistoryRepository(db)
-	cashboxService := services.NewCashboxService(cashboxRepo, cashboxHistRepo, expenseService, expCatService, settingsRepo)
+	cashboxService := services.NewCashboxService(cashboxRepo, cashboxHistRepo, expenseService, expCatService, settingsRepo, auditService)
 	cashboxHandler := handlers.NewCashboxHandlerCashboxHandler(cashboxService)
 
+	// Задолженности по расходам
+	expensePaymentRepo := repositories.NewExpensePaymentRepository(db)
+	payableService := services.NewPayableService(expenseRepo, expensePaymentRepo, cashboxService, auditService)
+	payableHandler := handlers.NewPayableHandler(payableService)
+
+	// Абонементы
+	passService := services.NewPassService(passRepo, clientRepo, paymentTypeRepo, cashboxService, auditService)
+	passHandler := handlers.NewPassHandler(passService)
+
+	// Подарочные сертификаты
+	certificateRepo := repositories.NewGiftCertificateRepository(db)
+	certificateService := services.NewGiftCertificateService(certificateRepo, clientRepo, paymentTypeRepo, cashboxService, auditService)
+	certificateHandler := handlers.NewGiftCertificateHandler(certificateService)
+
+	// Акции и промокоды
+	promotionRepo := repositories.NewPromotionRepository(db)
+	promotionService := services.NewPromotionService(promotionRepo, auditService)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+
+	// Счет клиента (деньги на депозите)
+	walletRepo := repositories.NewWalletRepository(db)
+	walletService := services.NewWalletService(walletRepo, clientRepo, paymentTypeRepo, cashboxService, auditService)
+	walletHandler := handlers.NewWalletHandler(walletService)
+
+	// Станции приготовления и очередь заказов
+	stationRepo := repositories.NewStationRepository(db)
+	stationService := services.NewStationService(stationRepo)
+	ticketRepo := repositories.NewOrderTicketRepository(db)
+	ticketService := services.NewTicketService(ticketRepo, stationRepo, priceRepo, categoryRepo, realtime.NewBroker())
+	stationHandler := handlers.NewStationHandler(stationService, ticketService)
+	ticketHandler := handlers.NewTicketHandler(ticketService)
+
 	bookingService := services.NewBookingService(
 		bookingRepo,
 		bookingItemRepo,
@@ -147,33 +222,53 @@ func Run() {
 		categoryRepo,
 		bookingPaymentRepo,
 		paymentTypeRepo,
+		bookingRefundRepo,
+		bookingLedgerRepo,
+		bookingSegmentRepo,
+		bookingShareRepo,
+		passRepo,
+		certificateRepo,
+		promotionRepo,
+		walletRepo,
 		cashboxService,
+		auditService,
+		ticketService,
 	)
 	bookingHandler := handlers.NewBookingHandler(bookingService)
 
+	// Свободные слоты столов
+	availabilityService := services.NewAvailabilityService(tableRepo, bookingRepo, settingsRepo)
+	availabilityHandler := handlers.NewAvailabilityHandler(availabilityService)
+
 	// Настройки
 	settingsRepo = repositories.NewSettingsRepository(db)
-	settingsService := services.NewSettingsService(settingsRepo)
+	settingsService := services.NewSettingsService(settingsRepo, auditService)
 	settingsHandler := handlers.NewSettingsHandler(settingsService)
 
-	// Отчеты
+	// Компании
+	companyService := services.NewCompanyService(companyRepo, userBranchRepo, templateService)
+	companyHandler := handlers.NewCompanyHandler(companyService)
+
+	// Отчеты, в том числе сводные по всем филиалам компании
 	reportRepo := repositories.NewReportRepository(db)
-	reportService := services.NewReportService(reportRepo)
+	reportService := services.NewReportService(reportRepo, recurringExpenseService, companyRepo)
 	reportHandler := handlers.NewReportHandler(reportService)
 
-	// Компании
-	companyRepo := repositories.NewCompanyRepository(db)
-	companyService := services.NewCompanyService(
-		companyRepo,
-		channelRepo,
-		tableCategoryRepo,
-		tableRepo,
-		categoryRepo,
-		paymentTypeRepo,
-		settingsRepo,
-		expCatRepo,
-	)
-	companyHandler := handlers.NewCompanyHandler(companyService)
+	// Онлайн-бронирование: коды подтверждения пока пишутся в лог,
+	// лимиты запросов с одного IP и на один телефон
+	otpRepo := repositories.NewOTPRepository(db)
+	otpService := services.NewOTPService(otpRepo, services.LogOTPSender{})
+	publicLimiter := ratelimit.New(60, time.Minute)
+	phoneLimiter := ratelimit.New(5, 15*time.Minute)
+	publicBookingService := services.NewPublicBookingService(companyRepo, clientRepo, tableRepo, bookingService, otpService, phoneLimiter)
+	publicBookingHandler := handlers.NewPublicBookingHandler(publicBookingService)
+
+	// Регистрация компаний: не больше нескольких попыток в час с одного IP
+	signupLimiter := ratelimit.New(5, time.Hour)
+
+	// Смена и сброс пароля, коды сброса идут через тот же OTP-сервис
+	passwordService := services.NewPasswordService(userRepo, tokenRepo, otpService, phoneLimiter)
+	passwordHandler := handlers.NewPasswordHandler(passwordService)
 
 	// =========router := gin.New()= Роутер и middlewares ==========
 	router := gin.New()
@@ -210,7 +305,23 @@ func Run() {
 		settingsHandler,
 		reportHandler,
 		inventoryHandler,
+		payableHandler,
+		recurringExpenseHandler,
+		auditHandler,
+		availabilityHandler,
+		stationHandler,
+		ticketHandler,
+		passHandler,
+		certificateHandler,
+		promotionHandler,
+		walletHandler,
+		publicBookingHandler,
+		deviceHandler,
+		passwordHandler,
+		publicLimiter,
+		signupLimiter,
 		cfg.Auth.AccessSecret,
+		authService,
 	)
 
 	listenAddr := fmt.Sprintf(":%d", port)
@@ -5,4 +5,6 @@ type CtxKey string
 const (
 	CtxCompanyID CtxKey = "company_id"
 	CtxBranchID  CtxKey = "branch_id"
+	CtxUserID    CtxKey = "user_id"
+	CtxRole      CtxKey = "role"
 )
@@ -17,6 +17,8 @@ type Config struct {
 		RefreshSecret string `yaml:"refresh_secret"`
 		AccessTTL     int    `yaml:"access_ttl"`
 		RefreshTTL    int    `yaml:"refresh_ttl"`
+		// PinTTL - время жизни входа по PIN на общем планшете, секунды
+		PinTTL int `yaml:"pin_ttl"`
 	} `yaml:"auth"`
 }
 
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"psclub-crm/internal/models"
+)
+
+// DefaultTemplate is the seed used when no template files are found: one
+// hall with six tables and the catalog of a PlayStation club.
+func DefaultTemplate() models.SeedTemplate {
+	return models.SeedTemplate{
+		Key:               "playstation",
+		Name:              "PlayStation клуб",
+		Version:           1,
+		TableCategories:   []models.SeedTableCategory{{Name: "Основной зал", Tables: 6, TablePrefix: "Стол"}},
+		Categories:        []models.SeedCategory{{Name: "Бар"}, {Name: "Кальян"}, {Name: "Сеты"}, {Name: "Часы"}},
+		PaymentTypes:      []models.SeedPaymentType{{Name: "Наличными"}, {Name: "Картой"}, {Name: "Каспи QR"}},
+		ExpenseCategories: []string{"Бар", "Кальян", "Ремонт", "Инвентаризация", "Зарплата", "Касса"},
+		Settings: models.SeedSettings{
+			BlockTime:        120,
+			WorkTimeFrom:     "10:00",
+			WorkTimeTo:       "23:00",
+			NotificationTime: 5,
+		},
+	}
+}
+
+// LoadTemplates reads seed templates from YAML or JSON files of dir. A
+// missing dir gives no templates, a broken file is an error so that it is
+// noticed on startup. Templates are sorted by key.
+func LoadTemplates(dir string) ([]models.SeedTemplate, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []models.SeedTemplate
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		// JSON тоже читается как YAML
+		var t models.SeedTemplate
+		if err := yaml.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("template %s: %w", e.Name(), err)
+		}
+		if t.Key == "" {
+			t.Key = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		}
+		if err := checkTemplate(&t); err != nil {
+			return nil, fmt.Errorf("template %s: %w", e.Name(), err)
+		}
+		if seen[t.Key] {
+			return nil, fmt.Errorf("template %s: duplicate key %q", e.Name(), t.Key)
+		}
+		seen[t.Key] = true
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list, nil
+}
+
+// checkTemplate makes sure that everything the template refers to is
+// defined in it.
+func checkTemplate(t *models.SeedTemplate) error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if t.Version <= 0 {
+		return errors.New("version is required")
+	}
+	if len(t.TableCategories) == 0 || len(t.PaymentTypes) == 0 {
+		return errors.New("table categories and payment types are required")
+	}
+	subs := make(map[string]map[string]bool)
+	for _, c := range t.Categories {
+		subs[c.Name] = make(map[string]bool)
+		for _, sc := range c.Subcategories {
+			subs[c.Name][sc] = true
+		}
+	}
+	inCatalog := func(category, subcategory string) bool {
+		sc, ok := subs[category]
+		return ok && (subcategory == "" || sc[subcategory])
+	}
+	items := make(map[string]bool)
+	for _, it := range t.PriceItems {
+		if !inCatalog(it.Category, it.Subcategory) {
+			return fmt.Errorf("price item %q: unknown category %q", it.Name, it.Category)
+		}
+		items[it.Name] = true
+	}
+	for _, s := range t.Sets {
+		if !inCatalog(s.Category, s.Subcategory) {
+			return fmt.Errorf("set %q: unknown category %q", s.Name, s.Category)
+		}
+		if items[s.Name] {
+			return fmt.Errorf("set %q: name is taken by a price item", s.Name)
+		}
+		for _, it := range s.Items {
+			if !items[it.Item] {
+				return fmt.Errorf("set %q: unknown price item %q", s.Name, it.Item)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+type AuditHandler struct {
+	service *services.AuditService
+}
+
+func NewAuditHandler(s *services.AuditService) *AuditHandler {
+	return &AuditHandler{service: s}
+}
+
+// GET /api/audit?entity=booking&entity_id=1&user_id=2&action=update&from=2006-01-02&to=2006-01-02&limit=100&offset=0
+func (h *AuditHandler) List(c *gin.Context) {
+	layoutDate := "2006-01-02"
+	f := models.AuditFilter{
+		Entity: c.Query("entity"),
+		Action: c.Query("action"),
+	}
+	f.EntityID, _ = strconv.Atoi(c.Query("entity_id"))
+	f.UserID, _ = strconv.Atoi(c.Query("user_id"))
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+	f.Offset, _ = strconv.Atoi(c.Query("offset"))
+	if f.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if f.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+	if s := c.Query("from"); s != "" {
+		d, err := time.ParseInLocation(layoutDate, s, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		f.From = d
+	}
+	if s := c.Query("to"); s != "" {
+		d, err := time.ParseInLocation(layoutDate, s, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		// включаем весь день "to"
+		f.To = d.AddDate(0, 0, 1)
+	}
+	list, err := h.service.List(c.Request.Context(), c.GetInt("company_id"), c.GetInt("branch_id"), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
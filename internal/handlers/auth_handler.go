@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"log"
@@ -9,6 +10,9 @@ import (
 	"psclub-crm/internal/common"
 	"psclub-crm/internal/models"
 	"psclub-crm/internal/services"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type AuthHandler struct {
@@ -33,46 +37,232 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	u.BranchID = branchID
 	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
 	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
-	access, refresh, err := h.service.Register(ctx, &u)
+	access, refresh, err := h.service.Register(ctx, &u, deviceOf(c, ""))
 	if err != nil {
+		if errors.Is(err, services.ErrRoleForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"user": u, "access": access, "refresh": refresh})
 }
 
+// GET /api/auth/templates
+func (h *AuthHandler) Templates(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Templates())
+}
+
+// POST /api/auth/signup
+func (h *AuthHandler) Signup(c *gin.Context) {
+	var req struct {
+		CompanyName string `json:"company_name"`
+		BranchName  string `json:"branch_name"`
+		Name        string `json:"name"`
+		Phone       string `json:"phone"`
+		Password    string `json:"password"`
+		// Template - шаблон клуба, по умолчанию PlayStation клуб
+		Template   string `json:"template"`
+		DeviceName string `json:"device_name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	u := models.User{Name: req.Name, Phone: req.Phone, Password: req.Password}
+	access, refresh, err := h.service.Signup(c.Request.Context(), req.CompanyName, req.BranchName, req.Template, &u, deviceOf(c, req.DeviceName))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSignup), errors.Is(err, services.ErrUnknownTemplate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUserExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("signup error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "signup failed"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"access": access, "refresh": refresh, "id": u.ID, "role": u.Role, "name": u.Name, "company_id": u.CompanyID, "branch_id": u.BranchID})
+}
+
 // POST /api/auth/login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req struct {
-		Phone    string `json:"phone"`
-		Password string `json:"password"`
+		Phone      string `json:"phone"`
+		Password   string `json:"password"`
+		DeviceName string `json:"device_name"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	access, refresh, role, permission, name, id, companyID, branchID, err := h.service.Login(c.Request.Context(), req.Phone, req.Password)
+	access, refresh, role, permission, name, id, companyID, branchID, err := h.service.Login(c.Request.Context(), req.Phone, req.Password, deviceOf(c, req.DeviceName))
 	if err != nil {
-		log.Printf("login error: %v", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrLoginThrottled):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		default:
+			log.Printf("login error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	branches, err := h.service.Branches(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"access": access, "refresh": refresh, "id": id, "role": role, "permissions": permission, "name": name, "company_id": companyID, "branch_id": branchID})
+	c.JSON(http.StatusOK, gin.H{"access": access, "refresh": refresh, "id": id, "role": role, "permissions": permission, "name": name, "company_id": companyID, "branch_id": branchID, "branches": branches})
 }
 
 // POST /api/auth/refresh
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	var req struct {
-		Refresh string `json:"refresh"`
+		Refresh    string `json:"refresh"`
+		DeviceName string `json:"device_name"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	access, refresh, err := h.service.Refresh(c.Request.Context(), req.Refresh)
+	access, refresh, err := h.service.Refresh(c.Request.Context(), req.Refresh, deviceOf(c, req.DeviceName))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"access": access, "refresh": refresh})
 }
+
+// GET /api/auth/branches
+func (h *AuthHandler) Branches(c *gin.Context) {
+	branches, err := h.service.Branches(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, branches)
+}
+
+// POST /api/auth/switch-branch
+func (h *AuthHandler) SwitchBranch(c *gin.Context) {
+	var req struct {
+		BranchID int `json:"branch_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	access, refresh, ub, err := h.service.SwitchBranch(c.Request.Context(), c.GetInt("user_id"), c.GetInt("session_id"), req.BranchID, deviceOf(c, ""))
+	if err != nil {
+		if errors.Is(err, services.ErrBranchForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"access": access, "refresh": refresh, "role": ub.Role, "company_id": ub.CompanyID, "branch_id": ub.BranchID, "branch_name": ub.BranchName})
+}
+
+// GET /api/auth/sessions
+func (h *AuthHandler) Sessions(c *gin.Context) {
+	list, err := h.service.Sessions(c.Request.Context(), c.GetInt("user_id"), c.GetInt("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// DELETE /api/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.RevokeSession(c.Request.Context(), c.GetInt("user_id"), id); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	err := h.service.RevokeSession(c.Request.Context(), c.GetInt("user_id"), c.GetInt("session_id"))
+	if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/logout-all
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	if err := h.service.LogoutAll(c.Request.Context(), c.GetInt("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// deviceOf describes the device a request comes from.
+func deviceOf(c *gin.Context, name string) models.Session {
+	return models.Session{
+		DeviceName: truncate(strings.TrimSpace(name), 100),
+		IP:         c.ClientIP(),
+		UserAgent:  truncate(c.Request.UserAgent(), 255),
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// GET /api/security/login-attempts?phone=...&user_id=2&failed=1&from=2006-01-02&to=2006-01-02&limit=100&offset=0
+func (h *AuthHandler) LoginAttempts(c *gin.Context) {
+	layoutDate := "2006-01-02"
+	f := models.LoginAttemptFilter{
+		Phone:      c.Query("phone"),
+		FailedOnly: c.Query("failed") == "1" || c.Query("failed") == "true",
+	}
+	f.UserID, _ = strconv.Atoi(c.Query("user_id"))
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+	f.Offset, _ = strconv.Atoi(c.Query("offset"))
+	if s := c.Query("from"); s != "" {
+		d, err := time.ParseInLocation(layoutDate, s, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		f.From = d
+	}
+	if s := c.Query("to"); s != "" {
+		d, err := time.ParseInLocation(layoutDate, s, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		// включаем весь день "to"
+		f.To = d.AddDate(0, 0, 1)
+	}
+	list, err := h.service.LoginAttempts(c.Request.Context(), c.GetInt("company_id"), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
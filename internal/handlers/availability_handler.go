@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/services"
+)
+
+type AvailabilityHandler struct {
+	service *services.AvailabilityService
+}
+
+func NewAvailabilityHandler(s *services.AvailabilityService) *AvailabilityHandler {
+	return &AvailabilityHandler{service: s}
+}
+
+// parseDuration accepts minutes ("90") or Go duration ("1h30m").
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if m, err := strconv.Atoi(s); err == nil {
+		return time.Duration(m) * time.Minute, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// GET /api/tables/availability?date=2006-01-02&duration=90&category_id=1
+func (h *AvailabilityHandler) GetAvailability(c *gin.Context) {
+	var date time.Time
+	if s := c.Query("date"); s != "" {
+		d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			return
+		}
+		date = d
+	}
+	duration, err := parseDuration(c.Query("duration"))
+	if err != nil || duration < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid duration"})
+		return
+	}
+	categoryID := 0
+	if s := c.Query("category_id"); s != "" {
+		categoryID, err = strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category_id"})
+			return
+		}
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	data, err := h.service.Availability(c.Request.Context(), companyID, branchID, date, duration, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
@@ -38,6 +40,14 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
 	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
 	id, err := h.service.CreateBooking(ctx, &b)
+	if err == services.ErrInvalidBookingStatus || err == services.ErrInvalidPromoCode || err == services.ErrWalletNotAllowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err == services.ErrPromoCodeExhausted || err == services.ErrWalletExhausted {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("create booking service error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -117,6 +127,14 @@ func (h *BookingHandler) UpdateBooking(c *gin.Context) {
 	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
 	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
 	err = h.service.UpdateBooking(ctx, &b)
+	if err == services.ErrBookingNotActive || err == services.ErrPromoCodeExhausted || err == services.ErrWalletExhausted {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err == services.ErrInvalidPromoCode || err == services.ErrWalletNotAllowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -135,10 +153,356 @@ func (h *BookingHandler) DeleteBooking(c *gin.Context) {
 	branchID := c.GetInt("branch_id")
 	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
 	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
-	err = h.service.DeleteBooking(ctx, id)
+	err = h.service.DeleteBooking(ctx, id, c.Query("reason"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeBookingError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// POST /api/bookings/:id/cancel
+func (h *BookingHandler) CancelBooking(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req models.BookingCancellation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	b, err := h.service.CancelBooking(ctx, id, &req)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// POST /api/bookings/:id/deposit
+func (h *BookingHandler) AddDeposit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var p models.BookingPayment
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	if err := h.service.AddDeposit(ctx, id, &p); err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// POST /api/bookings/:id/confirm
+func (h *BookingHandler) ConfirmBooking(c *gin.Context) {
+	h.changeStatus(c, h.service.ConfirmBooking)
+}
+
+// POST /api/bookings/:id/seat
+func (h *BookingHandler) SeatBooking(c *gin.Context) {
+	h.changeStatus(c, h.service.SeatBooking)
+}
+
+// POST /api/bookings/:id/complete
+func (h *BookingHandler) CompleteBooking(c *gin.Context) {
+	h.changeStatus(c, h.service.CompleteBooking)
+}
+
+// POST /api/bookings/:id/no-show
+func (h *BookingHandler) MarkNoShow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	b, err := h.service.MarkNoShow(ctx, id, req.Reason)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+func (h *BookingHandler) changeStatus(c *gin.Context, fn func(context.Context, int) error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	if err := fn(ctx, id); err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	b, err := h.service.GetBookingByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// POST /api/bookings/:id/move
+func (h *BookingHandler) MoveBooking(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req models.BookingMove
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	b, err := h.service.MoveBooking(ctx, id, &req)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// POST /api/bookings/merge
+func (h *BookingHandler) MergeBookings(c *gin.Context) {
+	var req models.BookingMerge
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	b, err := h.service.MergeBookings(ctx, &req)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// POST /api/bookings/:id/split
+func (h *BookingHandler) SplitBooking(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req models.BookingSplit
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	b, err := h.service.SplitBooking(ctx, id, &req)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// DELETE /api/bookings/:id/split
+func (h *BookingHandler) RemoveSplit(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	b, err := h.service.RemoveSplit(ctx, id)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// POST /api/bookings/:id/items
+func (h *BookingHandler) AddBookingItem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var it models.BookingItem
+	if err := c.ShouldBindJSON(&it); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	line, err := h.service.AddBookingItem(ctx, id, &it)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, line)
+}
+
+// POST /api/bookings/:id/items/:line_id/void
+func (h *BookingHandler) VoidBookingItem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	lineID, err := strconv.Atoi(c.Param("line_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid line id"})
+		return
+	}
+	var req models.BookingItemVoid
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	line, err := h.service.VoidBookingItem(ctx, id, lineID, req.Reason)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, line)
+}
+
+// POST /api/bookings/:id/items/:line_id/pass
+func (h *BookingHandler) RedeemPass(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	lineID, err := strconv.Atoi(c.Param("line_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid line id"})
+		return
+	}
+	var req models.PassRedeem
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	red, err := h.service.RedeemPass(ctx, id, lineID, &req)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, red)
+}
+
+// POST /api/bookings/:id/certificate
+func (h *BookingHandler) RedeemCertificate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req models.CertificateRedeem
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	cert, err := h.service.RedeemCertificate(ctx, id, &req)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// POST /api/bookings/:id/wallet
+func (h *BookingHandler) PayFromWallet(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req models.WalletPay
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	p, err := h.service.PayFromWallet(ctx, id, &req)
+	if err != nil {
+		writeBookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func writeBookingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "booking not found"})
+	case err == services.ErrBookingNotActive, err == services.ErrNoShowTooEarly, err == services.ErrTableBusy,
+		err == services.ErrBookingSplit, err == services.ErrPassExhausted, err == services.ErrLineRedeemed,
+		err == services.ErrCertificateExhausted, err == services.ErrPromoCodeExhausted,
+		err == services.ErrWalletExhausted:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err == services.ErrCancelReasonRequired, err == services.ErrInvalidBookingStatus,
+		err == services.ErrRefundExceedsPaid, err == services.ErrInvalidAmount,
+		err == services.ErrInvalidBookingTime, err == services.ErrInvalidMoveTable,
+		err == services.ErrNotHoursItem, err == services.ErrInvalidMerge, err == services.ErrMergeBonusClient,
+		err == services.ErrInvalidSplit, err == services.ErrOverpayment,
+		err == services.ErrVoidReasonRequired, err == services.ErrItemVoided,
+		err == services.ErrPassNotAllowed, err == services.ErrInvalidPromoCode,
+		err == services.ErrWalletNotAllowed:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"psclub-crm/internal/models"
 	"psclub-crm/internal/services"
+	"strconv"
 )
 
 type CompanyHandler struct {
@@ -14,21 +18,97 @@ func NewCompanyHandler(s *services.CompanyService) *CompanyHandler {
 	return &CompanyHandler{service: s}
 }
 
-type createCompanyRequest struct {
-	Name       string `json:"name"`
-	BranchName string `json:"branch_name"`
+type branchRequest struct {
+	Name string `json:"name"`
+	// CloneFrom - филиал, каталог которого копируется в новый
+	CloneFrom int `json:"clone_from_branch_id"`
+	// Template - шаблон каталога, если филиал не копируется
+	Template string `json:"template"`
 }
 
-func (h *CompanyHandler) Create(c *gin.Context) {
-	var req createCompanyRequest
+func writeBranchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "branch not found"})
+	case errors.Is(err, services.ErrInvalidBranch), errors.Is(err, services.ErrUnknownTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBranchInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GET /api/branches
+func (h *CompanyHandler) GetBranches(c *gin.Context) {
+	list, err := h.service.GetBranches(c.Request.Context(), c.GetInt("company_id"))
+	if err != nil {
+		writeBranchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GET /api/branches/:id
+func (h *CompanyHandler) GetBranch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	b, err := h.service.GetBranch(c.Request.Context(), c.GetInt("company_id"), id)
+	if err != nil {
+		writeBranchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// POST /api/branches
+func (h *CompanyHandler) CreateBranch(c *gin.Context) {
+	var req branchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	companyID, branchID, err := h.service.CreateCompany(c.Request.Context(), req.Name, req.BranchName)
+	b, err := h.service.CreateBranch(c.Request.Context(), c.GetInt("company_id"), req.Name, req.CloneFrom, req.Template)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeBranchError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+// PUT /api/branches/:id
+func (h *CompanyHandler) UpdateBranch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req branchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	b := models.Branch{ID: id, CompanyID: c.GetInt("company_id"), Name: req.Name}
+	if err := h.service.UpdateBranch(c.Request.Context(), &b); err != nil {
+		writeBranchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// DELETE /api/branches/:id
+func (h *CompanyHandler) DeleteBranch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.DeleteBranch(c.Request.Context(), c.GetInt("company_id"), id); err != nil {
+		writeBranchError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"company_id": companyID, "branch_id": branchID})
+	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"psclub-crm/internal/services"
+	"strconv"
+)
+
+// DeviceHandler serves shared admin tablets: enrollment by a director and
+// PIN login of staff on an enrolled tablet.
+type DeviceHandler struct {
+	service *services.AuthService
+}
+
+func NewDeviceHandler(s *services.AuthService) *DeviceHandler {
+	return &DeviceHandler{service: s}
+}
+
+// deviceTokenHeader carries the token of an enrolled device.
+const deviceTokenHeader = "X-Device-Token"
+
+func writeDeviceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownDevice), errors.Is(err, services.ErrWrongPin), errors.Is(err, services.ErrWrongPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPinLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDevice), errors.Is(err, services.ErrInvalidPinCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GET /api/devices
+func (h *DeviceHandler) GetDevices(c *gin.Context) {
+	list, err := h.service.Devices(c.Request.Context(), c.GetInt("company_id"), c.GetInt("branch_id"))
+	if err != nil {
+		writeDeviceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// POST /api/devices
+func (h *DeviceHandler) EnrollDevice(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, token, err := h.service.EnrollDevice(c.Request.Context(), c.GetInt("company_id"), c.GetInt("branch_id"), c.GetInt("user_id"), req.Name)
+	if err != nil {
+		writeDeviceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"device": d, "device_token": token})
+}
+
+// DELETE /api/devices/:id
+func (h *DeviceHandler) RevokeDevice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.RevokeDevice(c.Request.Context(), c.GetInt("company_id"), c.GetInt("branch_id"), id); err != nil {
+		writeDeviceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /api/auth/device/users
+func (h *DeviceHandler) GetUsers(c *gin.Context) {
+	list, err := h.service.DeviceUsers(c.Request.Context(), c.GetHeader(deviceTokenHeader))
+	if err != nil {
+		writeDeviceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// POST /api/auth/pin-login
+func (h *DeviceHandler) PinLogin(c *gin.Context) {
+	var req struct {
+		UserID int    `json:"user_id"`
+		Pin    string `json:"pin"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	access, u, err := h.service.PinLogin(c.Request.Context(), c.GetHeader(deviceTokenHeader), req.UserID, req.Pin, deviceOf(c, ""))
+	if err != nil {
+		writeDeviceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"access": access, "id": u.ID, "role": u.Role, "permissions": u.Permissions, "name": u.Name, "company_id": u.CompanyID, "branch_id": u.BranchID})
+}
+
+// PUT /api/auth/pin
+func (h *DeviceHandler) SetPin(c *gin.Context) {
+	var req struct {
+		Password string `json:"password"`
+		Pin      string `json:"pin"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.SetPin(c.Request.Context(), c.GetInt("user_id"), req.Password, req.Pin); err != nil {
+		writeDeviceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DELETE /api/auth/pin
+func (h *DeviceHandler) RemovePin(c *gin.Context) {
+	if err := h.service.RemovePin(c.Request.Context(), c.GetInt("user_id")); err != nil {
+		writeDeviceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+type GiftCertificateHandler struct {
+	service *services.GiftCertificateService
+}
+
+func NewGiftCertificateHandler(s *services.GiftCertificateService) *GiftCertificateHandler {
+	return &GiftCertificateHandler{service: s}
+}
+
+// POST /api/gift-certificates
+func (h *GiftCertificateHandler) SellCertificate(c *gin.Context) {
+	var req models.GiftCertificateSale
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	g, err := h.service.SellCertificate(ctx, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err == services.ErrCertificateCodeExists:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err == services.ErrInvalidAmount, err == services.ErrInvalidCertificate:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, g)
+}
+
+// GET /api/gift-certificates
+func (h *GiftCertificateHandler) GetAllCertificates(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	list, err := h.service.GetAllCertificates(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GET /api/gift-certificates/lookup?code=ABCD2345EF
+func (h *GiftCertificateHandler) LookupCertificate(c *gin.Context) {
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	g, err := h.service.LookupCertificate(ctx, code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "certificate not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, g)
+}
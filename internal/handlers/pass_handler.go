@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+type PassHandler struct {
+	service *services.PassService
+}
+
+func NewPassHandler(s *services.PassService) *PassHandler {
+	return &PassHandler{service: s}
+}
+
+func passCtx(c *gin.Context) context.Context {
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, c.GetInt("company_id"))
+	return context.WithValue(ctx, common.CtxBranchID, c.GetInt("branch_id"))
+}
+
+func writePassError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err == services.ErrInvalidPassType:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// POST /api/pass-types
+func (h *PassHandler) CreatePassType(c *gin.Context) {
+	var t models.PassType
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := h.service.CreatePassType(passCtx(c), &t)
+	if err != nil {
+		writePassError(c, err)
+		return
+	}
+	t.ID = id
+	c.JSON(http.StatusCreated, t)
+}
+
+// GET /api/pass-types
+func (h *PassHandler) GetPassTypes(c *gin.Context) {
+	types, err := h.service.GetPassTypes(passCtx(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// PUT /api/pass-types/:id
+func (h *PassHandler) UpdatePassType(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var t models.PassType
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t.ID = id
+	if err := h.service.UpdatePassType(passCtx(c), &t); err != nil {
+		writePassError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// DELETE /api/pass-types/:id
+func (h *PassHandler) DeletePassType(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.DeletePassType(passCtx(c), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// POST /api/clients/:id/passes
+func (h *PassHandler) SellPass(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req models.PassSale
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.service.SellPass(passCtx(c), clientID, &req)
+	if err != nil {
+		writePassError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// GET /api/clients/:id/passes
+func (h *PassHandler) GetClientPasses(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	passes, err := h.service.ClientPasses(passCtx(c), clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, passes)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"psclub-crm/internal/services"
+	"strconv"
+)
+
+type PasswordHandler struct {
+	service *services.PasswordService
+}
+
+func NewPasswordHandler(s *services.PasswordService) *PasswordHandler {
+	return &PasswordHandler{service: s}
+}
+
+func writePasswordError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, services.ErrRoleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWrongPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTooManyRequests):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWeakPassword), errors.Is(err, services.ErrInvalidOTP), errors.Is(err, services.ErrInvalidPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// POST /api/auth/change-password
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	var req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.ChangePassword(c.Request.Context(), c.GetInt("user_id"), req.OldPassword, req.NewPassword); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/forgot-password
+func (h *PasswordHandler) RequestReset(c *gin.Context) {
+	var req struct {
+		Phone string `json:"phone"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.RequestReset(c.Request.Context(), req.Phone); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/auth/reset-password
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Phone       string `json:"phone"`
+		Code        string `json:"code"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.ResetPassword(c.Request.Context(), req.Phone, req.Code, req.NewPassword); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/users/:id/reset-password
+func (h *PasswordHandler) IssueResetCode(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	code, expiresAt, err := h.service.IssueResetCode(c.Request.Context(), id, c.GetInt("company_id"), c.GetInt("branch_id"))
+	if err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": code, "expires_at": expiresAt})
+}
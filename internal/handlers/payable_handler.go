@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+type PayableHandler struct {
+	service *services.PayableService
+}
+
+func NewPayableHandler(s *services.PayableService) *PayableHandler {
+	return &PayableHandler{service: s}
+}
+
+// GET /api/payables
+func (h *PayableHandler) GetPayables(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	list, err := h.service.GetPayables(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GET /api/expenses/:id/payments
+func (h *PayableHandler) GetPayments(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	list, err := h.service.GetPayments(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// POST /api/expenses/:id/payments
+func (h *PayableHandler) AddPayment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var p models.ExpensePayment
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	p.ExpenseID = id
+	p.UserID = c.GetInt("user_id")
+	p.CompanyID = companyID
+	p.BranchID = branchID
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, companyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branchID)
+	paymentID, err := h.service.AddPayment(ctx, &p)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		case err == services.ErrInvalidAmount, err == services.ErrOverpayment, err == services.ErrInsufficientFunds:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	p.ID = paymentID
+	c.JSON(http.StatusCreated, p)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+type PromotionHandler struct {
+	service *services.PromotionService
+}
+
+func NewPromotionHandler(s *services.PromotionService) *PromotionHandler {
+	return &PromotionHandler{service: s}
+}
+
+func promotionCtx(c *gin.Context) context.Context {
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, c.GetInt("company_id"))
+	return context.WithValue(ctx, common.CtxBranchID, c.GetInt("branch_id"))
+}
+
+func writePromotionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err == services.ErrInvalidPromotion:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err == services.ErrPromoCodeExists:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// POST /api/promotions
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	var p models.Promotion
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := h.service.CreatePromotion(promotionCtx(c), &p)
+	if err != nil {
+		writePromotionError(c, err)
+		return
+	}
+	p.ID = id
+	c.JSON(http.StatusCreated, p)
+}
+
+// GET /api/promotions
+func (h *PromotionHandler) GetPromotions(c *gin.Context) {
+	list, err := h.service.GetPromotions(promotionCtx(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GET /api/promotions/:id
+func (h *PromotionHandler) GetPromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	p, err := h.service.GetPromotionByID(promotionCtx(c), id)
+	if err != nil {
+		writePromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// PUT /api/promotions/:id
+func (h *PromotionHandler) UpdatePromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var p models.Promotion
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.ID = id
+	if err := h.service.UpdatePromotion(promotionCtx(c), &p); err != nil {
+		writePromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// DELETE /api/promotions/:id
+func (h *PromotionHandler) DeletePromotion(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.DeletePromotion(promotionCtx(c), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+// PublicBookingHandler serves unauthenticated booking requests of guests.
+type PublicBookingHandler struct {
+	service *services.PublicBookingService
+}
+
+func NewPublicBookingHandler(s *services.PublicBookingService) *PublicBookingHandler {
+	return &PublicBookingHandler{service: s}
+}
+
+// ResolveBranch takes tenant from the :branch_id path parameter instead of
+// an employee token.
+func (h *PublicBookingHandler) ResolveBranch(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid branch id"})
+		return
+	}
+	branch, err := h.service.Branch(c.Request.Context(), id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "branch not found"})
+		return
+	}
+	c.Set("company_id", branch.CompanyID)
+	c.Set("branch_id", branch.ID)
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, branch.CompanyID)
+	ctx = context.WithValue(ctx, common.CtxBranchID, branch.ID)
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// POST /api/public/branches/:branch_id/estimate
+func (h *PublicBookingHandler) Estimate(c *gin.Context) {
+	var req models.PublicReservation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	b, err := h.service.Estimate(c.Request.Context(), &req)
+	if err != nil {
+		writePublicError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": b.Items, "total_amount": b.TotalAmount})
+}
+
+// POST /api/public/branches/:branch_id/otp
+func (h *PublicBookingHandler) RequestCode(c *gin.Context) {
+	var req struct {
+		Phone string `json:"phone"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.RequestCode(c.Request.Context(), req.Phone); err != nil {
+		writePublicError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/public/branches/:branch_id/reservations
+func (h *PublicBookingHandler) Reserve(c *gin.Context) {
+	var req models.PublicReservation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	b, err := h.service.Reserve(c.Request.Context(), &req)
+	if err != nil {
+		writePublicError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":           b.ID,
+		"status":       b.Status,
+		"table_id":     b.TableID,
+		"start_time":   b.StartTime,
+		"end_time":     b.EndTime,
+		"total_amount": b.TotalAmount,
+	})
+}
+
+func writePublicError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case err == services.ErrTooManyRequests:
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case err == services.ErrTableBusy:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err == services.ErrInvalidPhone, err == services.ErrInvalidOTP,
+		err == services.ErrInvalidBookingTime, err == services.ErrInvalidAmount:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+type RecurringExpenseHandler struct {
+	service *services.RecurringExpenseService
+}
+
+func NewRecurringExpenseHandler(s *services.RecurringExpenseService) *RecurringExpenseHandler {
+	return &RecurringExpenseHandler{service: s}
+}
+
+func tenantContext(c *gin.Context) context.Context {
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, c.GetInt("company_id"))
+	return context.WithValue(ctx, common.CtxBranchID, c.GetInt("branch_id"))
+}
+
+func recurringError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrInvalidSchedule, services.ErrInvalidAmount:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// POST /api/recurring-expenses
+func (h *RecurringExpenseHandler) Create(c *gin.Context) {
+	var e models.RecurringExpense
+	if err := c.ShouldBindJSON(&e); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	e.CompanyID = c.GetInt("company_id")
+	e.BranchID = c.GetInt("branch_id")
+	id, err := h.service.Create(tenantContext(c), &e)
+	if err != nil {
+		recurringError(c, err)
+		return
+	}
+	e.ID = id
+	c.JSON(http.StatusCreated, e)
+}
+
+// GET /api/recurring-expenses
+func (h *RecurringExpenseHandler) GetAll(c *gin.Context) {
+	list, err := h.service.GetAll(tenantContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GET /api/recurring-expenses/:id
+func (h *RecurringExpenseHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	e, err := h.service.GetByID(tenantContext(c), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// PUT /api/recurring-expenses/:id
+func (h *RecurringExpenseHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var e models.RecurringExpense
+	if err := c.ShouldBindJSON(&e); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	e.ID = id
+	e.CompanyID = c.GetInt("company_id")
+	e.BranchID = c.GetInt("branch_id")
+	if err := h.service.Update(tenantContext(c), &e); err != nil {
+		recurringError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// DELETE /api/recurring-expenses/:id
+func (h *RecurringExpenseHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.Delete(tenantContext(c), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /api/recurring-expenses/:id/pause
+func (h *RecurringExpenseHandler) Pause(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.Pause(tenantContext(c), id); err != nil {
+		recurringError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "paused"})
+}
+
+// POST /api/recurring-expenses/:id/resume
+func (h *RecurringExpenseHandler) Resume(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.service.Resume(tenantContext(c), id); err != nil {
+		recurringError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "active"})
+}
+
+// GET /api/recurring-expenses/upcoming?from=2006-01-02&to=2006-01-02
+func (h *RecurringExpenseHandler) Upcoming(c *gin.Context) {
+	layoutDate := "2006-01-02"
+	from := time.Now()
+	to := from.AddDate(0, 1, 0)
+	if s := c.Query("from"); s != "" {
+		d, err := time.Parse(layoutDate, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		from = d
+	}
+	if s := c.Query("to"); s != "" {
+		d, err := time.Parse(layoutDate, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		to = d
+	}
+	list, err := h.service.Upcoming(tenantContext(c), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
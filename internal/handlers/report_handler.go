@@ -94,6 +94,106 @@ func (h *ReportHandler) GetTablesReport(c *gin.Context) {
 	c.JSON(http.StatusOK, data)
 }
 
+// GET /api/reports/payables?date=2006-01-02
+func (h *ReportHandler) GetPayablesReport(c *gin.Context) {
+	asOf := time.Now()
+	if dateStr := c.Query("date"); dateStr != "" {
+		d, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			return
+		}
+		asOf = d
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	data, err := h.service.PayablesReport(c.Request.Context(), asOf, companyID, branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// GET /api/reports/certificates
+func (h *ReportHandler) GetCertificatesReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	data, err := h.service.CertificatesReport(c.Request.Context(), companyID, branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// GET /api/reports/wallets
+func (h *ReportHandler) GetWalletsReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	data, err := h.service.WalletsReport(c.Request.Context(), companyID, branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// GET /api/reports/company/summary
+func (h *ReportHandler) GetCompanySummaryReport(c *gin.Context) {
+	from, to, tFrom, tTo := getPeriod(c)
+	data, err := h.service.CompanySummaryReport(c.Request.Context(), from, to, tFrom, tTo, getUserID(c), c.GetInt("company_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// GET /api/reports/company/sales
+func (h *ReportHandler) GetCompanySalesReport(c *gin.Context) {
+	from, to, tFrom, tTo := getPeriod(c)
+	data, err := h.service.CompanySalesReport(c.Request.Context(), from, to, tFrom, tTo, getUserID(c), c.GetInt("company_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// GET /api/reports/company/admins
+func (h *ReportHandler) GetCompanyAdminsReport(c *gin.Context) {
+	from, to, tFrom, tTo := getPeriod(c)
+	data, err := h.service.CompanyAdminsReport(c.Request.Context(), from, to, tFrom, tTo, getUserID(c), c.GetInt("company_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// GET /api/reports/company/tables
+func (h *ReportHandler) GetCompanyTablesReport(c *gin.Context) {
+	from, to, tFrom, tTo := getPeriod(c)
+	data, err := h.service.CompanyTablesReport(c.Request.Context(), from, to, tFrom, tTo, getUserID(c), c.GetInt("company_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// GET /api/reports/company/branches
+func (h *ReportHandler) GetBranchComparison(c *gin.Context) {
+	from, to, tFrom, tTo := getPeriod(c)
+	data, err := h.service.BranchComparison(c.Request.Context(), from, to, tFrom, tTo, getUserID(c), c.GetInt("company_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
 func getPeriod(c *gin.Context) (from, to time.Time, tFrom, tTo string) {
 	layoutDate := "2006-01-02"
 	fromStr := c.DefaultQuery("from", time.Now().AddDate(0, 0, -7).Format(layoutDate))
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"psclub-crm/internal/models"
@@ -30,15 +32,26 @@ func (h *SettingsHandler) GetSettings(c *gin.Context) {
 
 // PUT /api/settings/:id
 func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
-	var set models.Settings
-	if err := c.ShouldBindJSON(&set); err != nil {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var upd models.SettingsUpdate
+	if err := c.ShouldBindJSON(&upd); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	set.CompanyID = c.GetInt("company_id")
-	set.BranchID = c.GetInt("branch_id")
-	err := h.service.UpdateSettings(c.Request.Context(), &set)
+	set, err := h.service.UpdateSettings(c.Request.Context(), id, c.GetInt("company_id"), c.GetInt("branch_id"), &upd)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "settings not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -56,6 +69,10 @@ func (h *SettingsHandler) CreateSettings(c *gin.Context) {
 	set.BranchID = c.GetInt("branch_id")
 	id, err := h.service.CreateSettings(c.Request.Context(), &set)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
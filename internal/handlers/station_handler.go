@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+// streamPing keeps idle ticket streams alive behind proxies.
+const streamPing = 30 * time.Second
+
+type StationHandler struct {
+	service *services.StationService
+	tickets *services.TicketService
+}
+
+func NewStationHandler(s *services.StationService, t *services.TicketService) *StationHandler {
+	return &StationHandler{service: s, tickets: t}
+}
+
+// POST /api/stations
+func (h *StationHandler) CreateStation(c *gin.Context) {
+	var st models.Station
+	if err := c.ShouldBindJSON(&st); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st.CompanyID = c.GetInt("company_id")
+	st.BranchID = c.GetInt("branch_id")
+	id, err := h.service.CreateStation(c.Request.Context(), &st)
+	if err != nil {
+		if err == services.ErrNameExists {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	st.ID = id
+	c.JSON(http.StatusCreated, st)
+}
+
+// GET /api/stations
+func (h *StationHandler) GetAllStations(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	stations, err := h.service.GetAllStations(c.Request.Context(), companyID, branchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stations)
+}
+
+// PUT /api/stations/:id
+func (h *StationHandler) UpdateStation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var st models.Station
+	if err := c.ShouldBindJSON(&st); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st.ID = id
+	st.CompanyID = c.GetInt("company_id")
+	st.BranchID = c.GetInt("branch_id")
+	if err := h.service.UpdateStation(c.Request.Context(), &st); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+}
+
+// DELETE /api/stations/:id
+func (h *StationHandler) DeleteStation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	if err := h.service.DeleteStation(c.Request.Context(), id, companyID, branchID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "delete failed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// GET /api/stations/:id/tickets?status=new,in_progress
+func (h *StationHandler) GetTickets(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var statuses []string
+	if s := c.Query("status"); s != "" {
+		statuses = strings.Split(s, ",")
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	tickets, err := h.tickets.StationTickets(c.Request.Context(), companyID, branchID, id, statuses)
+	if err != nil {
+		writeTicketError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tickets)
+}
+
+// GET /api/stations/:id/tickets/stream
+// Server-sent events: "ticket" with a created or changed ticket, "ping" to
+// keep the connection open.
+func (h *StationHandler) StreamTickets(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	if _, err := h.service.GetStationByID(c.Request.Context(), id, companyID, branchID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "station not found"})
+		return
+	}
+	events, unsubscribe := h.tickets.Subscribe(id)
+	defer unsubscribe()
+	ping := time.NewTicker(streamPing)
+	defer ping.Stop()
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent("ticket", ev)
+		case <-ping.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/services"
+)
+
+type TicketHandler struct {
+	service *services.TicketService
+}
+
+func NewTicketHandler(s *services.TicketService) *TicketHandler {
+	return &TicketHandler{service: s}
+}
+
+func writeTicketError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case err == services.ErrInvalidTicketStatus:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// POST /api/tickets/:id/start
+func (h *TicketHandler) StartTicket(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	t, err := h.service.Start(c.Request.Context(), c.GetInt("company_id"), c.GetInt("branch_id"), id)
+	if err != nil {
+		writeTicketError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// POST /api/tickets/:id/serve
+func (h *TicketHandler) ServeTicket(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	t, err := h.service.Serve(c.Request.Context(), c.GetInt("company_id"), c.GetInt("branch_id"), id)
+	if err != nil {
+		writeTicketError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
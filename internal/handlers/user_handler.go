@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -17,6 +19,17 @@ func NewUserHandler(service *services.UserService) *UserHandler {
 	return &UserHandler{service: service}
 }
 
+func writeUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrInvalidUserBranch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoleForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // POST /api/users
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var user models.User
@@ -29,7 +42,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 	id, err := h.service.CreateUser(c.Request.Context(), &user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeUserError(c, err)
 		return
 	}
 	user.ID = id
@@ -87,7 +100,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	user.BranchID = c.GetInt("branch_id")
 	err = h.service.UpdateUser(c.Request.Context(), &user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeUserError(c, err)
 		return
 	}
 	user.Password = ""
@@ -105,8 +118,64 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	branchID := c.GetInt("branch_id")
 	err = h.service.DeleteUser(c.Request.Context(), id, companyID, branchID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeUserError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// GET /api/users/:id/salary-history
+func (h *UserHandler) GetSalaryHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	companyID := c.GetInt("company_id")
+	branchID := c.GetInt("branch_id")
+	history, err := h.service.GetSalaryHistory(c.Request.Context(), id, companyID, branchID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// GET /api/users/:id/branches
+func (h *UserHandler) GetUserBranches(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	list, err := h.service.GetUserBranches(c.Request.Context(), id, c.GetInt("company_id"), c.GetInt("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// PUT /api/users/:id/branches
+func (h *UserHandler) SetUserBranches(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req []models.UserBranch
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, err := h.service.SetUserBranches(c.Request.Context(), id, c.GetInt("company_id"), c.GetInt("branch_id"), req)
+	if err != nil {
+		writeUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
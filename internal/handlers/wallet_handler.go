@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/services"
+)
+
+type WalletHandler struct {
+	service *services.WalletService
+}
+
+func NewWalletHandler(s *services.WalletService) *WalletHandler {
+	return &WalletHandler{service: s}
+}
+
+func walletCtx(c *gin.Context) context.Context {
+	ctx := context.WithValue(c.Request.Context(), common.CtxCompanyID, c.GetInt("company_id"))
+	return context.WithValue(ctx, common.CtxBranchID, c.GetInt("branch_id"))
+}
+
+// POST /api/clients/:id/wallet/top-up
+func (h *WalletHandler) TopUp(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req models.WalletTopUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	e, err := h.service.TopUp(walletCtx(c), clientID, &req)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, e)
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err == services.ErrInvalidAmount:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GET /api/clients/:id/wallet
+func (h *WalletHandler) GetEntries(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	entries, err := h.service.Entries(walletCtx(c), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"psclub-crm/internal/common"
 )
 
 type jwtClaims struct {
@@ -17,10 +19,17 @@ type jwtClaims struct {
 	CompanyID int    `json:"company_id"`
 	BranchID  int    `json:"branch_id"`
 	Role      string `json:"role"`
+	SessionID int    `json:"sid"`
 	Exp       int64  `json:"exp"`
 }
 
-func Auth(secret string) gin.HandlerFunc {
+// SessionChecker tells whether the session of an access token is still
+// active, so that a revoked session stops working before its token expires.
+type SessionChecker interface {
+	SessionActive(ctx context.Context, userID, sessionID int) bool
+}
+
+func Auth(secret string, sessions SessionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -55,10 +64,35 @@ func Auth(secret string) gin.HandlerFunc {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		if sessions != nil && !sessions.SessionActive(c.Request.Context(), claims.UserID, claims.SessionID) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
 		c.Set("company_id", claims.CompanyID)
 		c.Set("branch_id", claims.BranchID)
 		c.Set("role", claims.Role)
+		// те же значения в контексте запроса, чтобы сервисы знали, кто выполняет действие
+		ctx := context.WithValue(c.Request.Context(), common.CtxUserID, claims.UserID)
+		ctx = context.WithValue(ctx, common.CtxRole, claims.Role)
+		ctx = context.WithValue(ctx, common.CtxCompanyID, claims.CompanyID)
+		ctx = context.WithValue(ctx, common.CtxBranchID, claims.BranchID)
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
+
+// RequireRole allows the request only for users with one of the given roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog is a record of a change made by a user. Diff contains changed
+// top-level fields as {"field": {"old": ..., "new": ...}}.
+type AuditLog struct {
+	ID        int64           `json:"id"`
+	CompanyID int             `json:"company_id"`
+	BranchID  int             `json:"branch_id"`
+	UserID    int             `json:"user_id,omitempty"`
+	UserName  string          `json:"user_name,omitempty"`
+	Role      string          `json:"role"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entity_id"`
+	Action    string          `json:"action"`
+	Diff      json.RawMessage `json:"diff,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows audit log search. Zero values are ignored.
+type AuditFilter struct {
+	Entity   string
+	EntityID int
+	UserID   int
+	Action   string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// TimeSlot is a free interval of a table.
+type TimeSlot struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// TableAvailability lists free slots of a table within a business day.
+type TableAvailability struct {
+	TableID    int        `json:"table_id"`
+	TableName  string     `json:"table_name"`
+	Number     int        `json:"number"`
+	CategoryID int        `json:"category_id"`
+	FreeSlots  []TimeSlot `json:"free_slots"`
+}
+
+// Availability is the free-slot calendar of a business day.
+type Availability struct {
+	DayStart time.Time           `json:"day_start"`
+	DayEnd   time.Time           `json:"day_end"`
+	Tables   []TableAvailability `json:"tables"`
+}
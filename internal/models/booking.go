@@ -3,29 +3,40 @@ package models
 import "time"
 
 type Booking struct {
-	ID             int              `json:"id"`
-	CompanyID      int              `json:"company_id"`
-	BranchID       int              `json:"branch_id"`
-	ClientID       int              `json:"client_id"`
-	TableID        int              `json:"table_id"`
-	UserID         int              `json:"user_id"`
-	StartTime      time.Time        `json:"start_time"`
-	EndTime        time.Time        `json:"end_time"`
-	Note           string           `json:"note"`
-	Discount       int              `json:"discount"`
-	DiscountReason string           `json:"discount_reason"`
-	TotalAmount    int              `json:"total_amount"`
-	BonusUsed      int              `json:"bonus_used"`
-	PaymentStatus  string           `json:"payment_status"`
-	PaymentTypeID  int              `json:"payment_type_id"`
-	CreatedAt      time.Time        `json:"created_at"`
-	UpdatedAt      time.Time        `json:"updated_at"`
-	ClientName     string           `json:"client_name,omitempty"`
-	ClientPhone    string           `json:"client_phone,omitempty"`
-	PaymentType    *string          `json:"payment_type,omitempty"`
-	ChannelName    string           `json:"channel_name,omitempty"`
-	Items          []BookingItem    `json:"items,omitempty"`
-	Payments       []BookingPayment `json:"payments,omitempty"`
+	ID             int                   `json:"id"`
+	CompanyID      int                   `json:"company_id"`
+	BranchID       int                   `json:"branch_id"`
+	ClientID       int                   `json:"client_id"`
+	TableID        int                   `json:"table_id"`
+	UserID         int                   `json:"user_id"`
+	StartTime      time.Time             `json:"start_time"`
+	EndTime        time.Time             `json:"end_time"`
+	Note           string                `json:"note"`
+	Discount       int                   `json:"discount"`
+	DiscountReason string                `json:"discount_reason"`
+	PromoCode      string                `json:"promo_code,omitempty"`
+	PromotionID    int                   `json:"promotion_id,omitempty"`
+	Promotions     []BookingPromotion    `json:"promotions,omitempty"`
+	TotalAmount    int                   `json:"total_amount"`
+	BonusUsed      int                   `json:"bonus_used"`
+	PaymentStatus  string                `json:"payment_status"`
+	PaymentTypeID  int                   `json:"payment_type_id"`
+	Status         string                `json:"status"`
+	CancelReason   string                `json:"cancel_reason,omitempty"`
+	CancelledAt    *time.Time            `json:"cancelled_at,omitempty"`
+	CancelledBy    int                   `json:"cancelled_by,omitempty"`
+	Refunds        []BookingRefund       `json:"refunds,omitempty"`
+	MergedInto     int                   `json:"merged_into,omitempty"`
+	Segments       []BookingTableSegment `json:"segments,omitempty"`
+	Shares         []BookingShare        `json:"shares,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	ClientName     string                `json:"client_name,omitempty"`
+	ClientPhone    string                `json:"client_phone,omitempty"`
+	PaymentType    *string               `json:"payment_type,omitempty"`
+	ChannelName    string                `json:"channel_name,omitempty"`
+	Items          []BookingItem         `json:"items,omitempty"`
+	Payments       []BookingPayment      `json:"payments,omitempty"`
 }
 
 type BookingItem struct {
@@ -39,4 +50,25 @@ type BookingItem struct {
 	Discount  int     `json:"discount"`
 	ItemPrice float64 `json:"item_price,omitempty"`
 	ItemName  string  `json:"item_name,omitempty"`
+	// UserID - сотрудник, добавивший позицию
+	UserID     int        `json:"user_id,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	VoidReason string     `json:"void_reason,omitempty"`
+	VoidedAt   *time.Time `json:"voided_at,omitempty"`
+	VoidedBy   int        `json:"voided_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// PromotionID - скидка строки рассчитана акцией, а не назначена вручную
+	PromotionID int `json:"promotion_id,omitempty"`
+}
+
+// Booking line statuses. A voided line stays in the bill history but is not
+// charged and does not count in reports.
+const (
+	BookingItemActive = "active"
+	BookingItemVoided = "voided"
+)
+
+// BookingItemVoid is a request to void a booking line.
+type BookingItemVoid struct {
+	Reason string `json:"reason"`
 }
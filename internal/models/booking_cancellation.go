@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Booking statuses. A walk-in booking is created active, an advance
+// reservation goes reserved -> seated -> completed. Online requests start
+// pending until an admin confirms them. Cancellation moves it to
+// one of the final statuses depending on the refunded amount. A booking
+// whose bill was combined into another one becomes merged.
+const (
+	BookingStatusActive            = "active"
+	BookingStatusPending           = "pending"
+	BookingStatusReserved          = "reserved"
+	BookingStatusSeated            = "seated"
+	BookingStatusCompleted         = "completed"
+	BookingStatusCancelled         = "cancelled"
+	BookingStatusNoShow            = "no_show"
+	BookingStatusRefunded          = "refunded"
+	BookingStatusPartiallyRefunded = "partially_refunded"
+	BookingStatusMerged            = "merged"
+)
+
+// IsOpenBookingStatus reports whether booking with the status can still be
+// changed or cancelled.
+func IsOpenBookingStatus(status string) bool {
+	switch status {
+	case BookingStatusActive, BookingStatusPending, BookingStatusReserved, BookingStatusSeated:
+		return true
+	}
+	return false
+}
+
+// BookingCancellation is a request to cancel a booking.
+type BookingCancellation struct {
+	// Status - cancelled или no_show; при возврате денег итоговый статус
+	// становится refunded или partially_refunded
+	Status       string          `json:"status"`
+	Reason       string          `json:"reason"`
+	Refunds      []BookingRefund `json:"refunds"`
+	RestoreStock *bool           `json:"restore_stock,omitempty"`
+}
+
+// BookingRefund is money returned to the client for one payment type.
+type BookingRefund struct {
+	ID            int       `json:"id"`
+	BookingID     int       `json:"booking_id"`
+	PaymentTypeID int       `json:"payment_type_id"`
+	PaymentType   string    `json:"payment_type,omitempty"`
+	Amount        int       `json:"amount"`
+	UserID        int       `json:"user_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Ledger entry types.
+const (
+	LedgerStock = "stock"
+	LedgerBonus = "bonus"
+)
+
+// BookingLedgerEntry records stock or bonus movement made on cancellation.
+type BookingLedgerEntry struct {
+	ID        int       `json:"id"`
+	BookingID int       `json:"booking_id"`
+	EntryType string    `json:"entry_type"`
+	ItemID    int       `json:"item_id,omitempty"`
+	ClientID  int       `json:"client_id,omitempty"`
+	Quantity  float64   `json:"quantity"`
+	Amount    int       `json:"amount"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // BookingPayment represents a part of a booking payment with specific method.
 type BookingPayment struct {
 	ID            int     `json:"id"`
@@ -9,4 +11,14 @@ type BookingPayment struct {
 	PaymentTypeID int     `json:"payment_type_id"`
 	Amount        int     `json:"amount"`
 	PaymentType   *string `json:"payment_type,omitempty"`
+	// IsDeposit - предоплата по предварительной брони, засчитывается в итоговый счёт
+	IsDeposit bool `json:"is_deposit"`
+	// ShareID - доля разделенного счета, к которой относится оплата
+	ShareID int `json:"share_id,omitempty"`
+	// Method - особый способ оплаты без типа оплаты кассы: "pass", "certificate", "wallet"
+	Method            string    `json:"method,omitempty"`
+	ClientPassID      int       `json:"client_pass_id,omitempty"`
+	GiftCertificateID int       `json:"gift_certificate_id,omitempty"`
+	WalletClientID    int       `json:"wallet_client_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
 }
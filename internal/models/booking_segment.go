@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// BookingTableSegment is a period of a booking spent at one table.
+type BookingTableSegment struct {
+	ID        int       `json:"id"`
+	BookingID int       `json:"booking_id"`
+	TableID   int       `json:"table_id"`
+	TableName string    `json:"table_name,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// BookingMove is a request to continue a booking at another table.
+type BookingMove struct {
+	TableID int `json:"table_id"`
+	// HoursItemID - тариф нового стола, по умолчанию остается прежний
+	HoursItemID int        `json:"hours_item_id"`
+	At          *time.Time `json:"at,omitempty"`
+}
+
+// BookingMerge is a request to combine source booking bill into target.
+type BookingMerge struct {
+	TargetID int `json:"target_id"`
+	SourceID int `json:"source_id"`
+}
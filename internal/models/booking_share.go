@@ -0,0 +1,37 @@
+package models
+
+// Bill split modes.
+const (
+	SplitByItems   = "items"
+	SplitEqual     = "equal"
+	SplitByAmounts = "amounts"
+)
+
+// BookingShare is a part of a booking bill paid by one participant.
+type BookingShare struct {
+	ID         int                `json:"id"`
+	BookingID  int                `json:"booking_id"`
+	ClientID   int                `json:"client_id"`
+	ClientName string             `json:"client_name,omitempty"`
+	Amount     int                `json:"amount"`
+	BonusUsed  int                `json:"bonus_used"`
+	Items      []BookingShareItem `json:"items,omitempty"`
+	Payments   []BookingPayment   `json:"payments,omitempty"`
+}
+
+// BookingShareItem is a quantity of a booking line taken by a share.
+type BookingShareItem struct {
+	ID            int     `json:"id"`
+	ShareID       int     `json:"share_id"`
+	BookingItemID int     `json:"booking_item_id"`
+	Quantity      float64 `json:"quantity"`
+	Amount        int     `json:"amount"`
+}
+
+// BookingSplit is a request to split a booking bill.
+type BookingSplit struct {
+	// Mode - items, equal или amounts. Для items участники перечисляют
+	// позиции, для amounts указывают сумму, equal делит счет поровну
+	Mode   string         `json:"mode"`
+	Shares []BookingShare `json:"shares"`
+}
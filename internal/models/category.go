@@ -5,6 +5,9 @@ type Category struct {
 	Name      string `json:"name"`
 	CompanyID int    `json:"company_id"`
 	BranchID  int    `json:"branch_id"`
+	// IsPrepared - позиции категории готовятся на станции StationID
+	IsPrepared bool `json:"is_prepared"`
+	StationID  int  `json:"station_id,omitempty"`
 }
 type Subcategory struct {
 	ID         int    `json:"id"`
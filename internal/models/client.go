@@ -17,4 +17,8 @@ type Client struct {
 	Status      string     `json:"status"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+	// WalletBalance - деньги клиента на счету, меняются только через журнал
+	WalletBalance int `json:"wallet_balance"`
+	// Passes - абонементы клиента с остатками, заполняется в карточке клиента
+	Passes []ClientPass `json:"passes,omitempty"`
 }
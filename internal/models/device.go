@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Device is a shared tablet enrolled in a branch by a director. Staff log in
+// on it with a PIN instead of the phone and password.
+type Device struct {
+	ID         int        `json:"id"`
+	CompanyID  int        `json:"company_id"`
+	BranchID   int        `json:"branch_id"`
+	Name       string     `json:"name"`
+	CreatedBy  int        `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// DeviceUser is a staff member who can log in on the device of the branch.
+type DeviceUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// UserPin is the PIN of a user with its lockout state.
+type UserPin struct {
+	UserID         int
+	Hash           string
+	FailedAttempts int
+	// Locked - PIN заблокирован после неудачных попыток
+	Locked bool
+}
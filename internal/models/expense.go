@@ -3,17 +3,30 @@ package models
 import "time"
 
 type Expense struct {
-	ID               int       `json:"id"`
-	Date             time.Time `json:"date"`
-	Title            string    `json:"title"`
-	CategoryID       int       `json:"category_id,omitempty"`
-	Category         string    `json:"category"`
-	RepairCategoryID int       `json:"repair_category_id,omitempty"`
-	RepairCategory   string    `json:"repair_category"`
-	Total            float64   `json:"total"`
-	Description      string    `json:"description"`
-	Paid             bool      `json:"paid"`
-	CreatedAt        time.Time `json:"created_at"`
-	CompanyID        int       `json:"company_id"`
-	BranchID         int       `json:"branch_id"`
+	ID               int        `json:"id"`
+	Date             time.Time  `json:"date"`
+	Title            string     `json:"title"`
+	CategoryID       int        `json:"category_id,omitempty"`
+	Category         string     `json:"category"`
+	RepairCategoryID int        `json:"repair_category_id,omitempty"`
+	RepairCategory   string     `json:"repair_category"`
+	Total            float64    `json:"total"`
+	Description      string     `json:"description"`
+	Paid             bool       `json:"paid"`
+	PaidAmount       float64    `json:"paid_amount"`
+	DueDate          *time.Time `json:"due_date,omitempty"`
+	Counterparty     string     `json:"counterparty"`
+	// RecurringExpenseID ссылается на шаблон, из которого создан расход
+	RecurringExpenseID int       `json:"recurring_expense_id,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	CompanyID          int       `json:"company_id"`
+	BranchID           int       `json:"branch_id"`
+}
+
+// Remaining returns the part of the expense that is still owed.
+func (e *Expense) Remaining() float64 {
+	if e.PaidAmount >= e.Total {
+		return 0
+	}
+	return e.Total - e.PaidAmount
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ExpensePayment is a (partial) settlement of an unpaid expense.
+type ExpensePayment struct {
+	ID            int       `json:"id"`
+	ExpenseID     int       `json:"expense_id"`
+	Amount        float64   `json:"amount"`
+	PaymentTypeID int       `json:"payment_type_id,omitempty"`
+	PaymentType   string    `json:"payment_type,omitempty"`
+	FromCashbox   bool      `json:"from_cashbox"`
+	Note          string    `json:"note"`
+	UserID        int       `json:"user_id,omitempty"`
+	PaidAt        time.Time `json:"paid_at"`
+	CompanyID     int       `json:"company_id"`
+	BranchID      int       `json:"branch_id"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// PaymentMethodCertificate marks a booking payment taken from a gift
+// certificate balance.
+const PaymentMethodCertificate = "certificate"
+
+// GiftCertificate is a prepaid certificate with a unique code. Its balance
+// can be spent in several bookings until it expires.
+type GiftCertificate struct {
+	ID                int       `json:"id"`
+	CompanyID         int       `json:"company_id"`
+	BranchID          int       `json:"branch_id"`
+	Code              string    `json:"code"`
+	Nominal           int       `json:"nominal"`
+	Balance           int       `json:"balance"`
+	PurchaserClientID int       `json:"purchaser_client_id,omitempty"`
+	PurchaserName     string    `json:"purchaser_name,omitempty"`
+	PaymentTypeID     int       `json:"payment_type_id"`
+	UserID            int       `json:"user_id,omitempty"`
+	SoldAt            time.Time `json:"sold_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	// Active - сертификат не истек и на нем остались деньги
+	Active bool `json:"active"`
+}
+
+// GiftCertificateSale is a request to sell a certificate. An empty code is
+// generated, a zero expiry means a year from the sale.
+type GiftCertificateSale struct {
+	Code              string     `json:"code"`
+	Nominal           int        `json:"nominal" binding:"required"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	PurchaserClientID int        `json:"purchaser_client_id"`
+	PurchaserName     string     `json:"purchaser_name"`
+	PaymentTypeID     int        `json:"payment_type_id" binding:"required"`
+}
+
+// CertificateRedeem is a request to pay part of a booking bill from a
+// certificate.
+type CertificateRedeem struct {
+	Code   string `json:"code" binding:"required"`
+	Amount int    `json:"amount" binding:"required"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Причины в журнале попыток входа
+const (
+	LoginSuccess       = "success"
+	LoginWrongPassword = "wrong_password"
+	LoginUnknownPhone  = "unknown_phone"
+	LoginThrottled     = "throttled"
+)
+
+// LoginAttempt is a record of the security log: one attempt to log in with
+// a phone and password.
+type LoginAttempt struct {
+	ID        int64     `json:"id"`
+	Phone     string    `json:"phone"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	UserID    int       `json:"user_id,omitempty"`
+	UserName  string    `json:"user_name,omitempty"`
+	CompanyID int       `json:"company_id,omitempty"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginAttemptFilter narrows security log search. Zero values are ignored.
+type LoginAttemptFilter struct {
+	Phone      string
+	UserID     int
+	FailedOnly bool
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// LoginFailures counts recent failed logins of a phone or an IP.
+type LoginFailures struct {
+	Count int
+	// SinceLast - время с последней неудачной попытки
+	SinceLast time.Duration
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Station is a place where ordered items are prepared: bar, hookah room.
+type Station struct {
+	ID        int    `json:"id"`
+	CompanyID int    `json:"company_id"`
+	BranchID  int    `json:"branch_id"`
+	Name      string `json:"name"`
+}
+
+// Order ticket statuses: new -> in_progress -> served. Tickets of voided
+// lines and cancelled bookings become cancelled.
+const (
+	TicketNew        = "new"
+	TicketInProgress = "in_progress"
+	TicketServed     = "served"
+	TicketCancelled  = "cancelled"
+)
+
+// OrderTicket is a booking line to be prepared at a station.
+type OrderTicket struct {
+	ID            int        `json:"id"`
+	StationID     int        `json:"station_id"`
+	BookingID     int        `json:"booking_id"`
+	BookingItemID int        `json:"booking_item_id,omitempty"`
+	ItemID        int        `json:"item_id"`
+	ItemName      string     `json:"item_name,omitempty"`
+	Quantity      float64    `json:"quantity"`
+	TableID       int        `json:"table_id,omitempty"`
+	TableName     string     `json:"table_name,omitempty"`
+	Status        string     `json:"status"`
+	UserID        int        `json:"user_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	ServedAt      *time.Time `json:"served_at,omitempty"`
+}
+
+// PrepStat shows how fast a station serves tickets. Wait is the time from
+// order to start, prep is the time from start to serving, in minutes.
+type PrepStat struct {
+	Station        string  `json:"station"`
+	Tickets        int     `json:"tickets"`
+	AvgWaitMinutes float64 `json:"avg_wait_minutes"`
+	AvgPrepMinutes float64 `json:"avg_prep_minutes"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// OTPCode is a one-time confirmation code sent to a phone. Only hash of the
+// code is stored.
+type OTPCode struct {
+	ID        int       `json:"id"`
+	CompanyID int       `json:"company_id"`
+	BranchID  int       `json:"branch_id"`
+	Phone     string    `json:"phone"`
+	Purpose   string    `json:"purpose"`
+	CodeHash  string    `json:"-"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Pass kinds: a package of table hours or a number of visits.
+const (
+	PassKindHours  = "hours"
+	PassKindVisits = "visits"
+)
+
+// PaymentMethodPass marks a booking payment covered by a client pass
+// instead of a cashbox payment type.
+const PaymentMethodPass = "pass"
+
+// PassType is a membership pass (абонемент) on sale.
+type PassType struct {
+	ID        int    `json:"id"`
+	CompanyID int    `json:"company_id"`
+	BranchID  int    `json:"branch_id"`
+	Name      string `json:"name" binding:"required"`
+	Kind      string `json:"kind"`
+	// Amount - количество часов или посещений в абонементе
+	Amount       float64 `json:"amount"`
+	Price        int     `json:"price"`
+	ValidityDays int     `json:"validity_days"`
+	// TableCategoryIDs - категории столов, пустой список - любые столы
+	TableCategoryIDs []int `json:"table_category_ids"`
+	IsActive         bool  `json:"is_active"`
+}
+
+// ClientPass is a pass sold to a client with its remaining balance.
+type ClientPass struct {
+	ID            int       `json:"id"`
+	CompanyID     int       `json:"company_id"`
+	BranchID      int       `json:"branch_id"`
+	ClientID      int       `json:"client_id"`
+	PassTypeID    int       `json:"pass_type_id"`
+	PassTypeName  string    `json:"pass_type_name,omitempty"`
+	Kind          string    `json:"kind"`
+	Total         float64   `json:"total"`
+	Balance       float64   `json:"balance"`
+	Price         int       `json:"price"`
+	PaymentTypeID int       `json:"payment_type_id"`
+	UserID        int       `json:"user_id,omitempty"`
+	SoldAt        time.Time `json:"sold_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	// Active - абонемент не истек и на нем остались часы или посещения
+	Active bool `json:"active"`
+}
+
+// PassSale is a request to sell a pass to a client.
+type PassSale struct {
+	PassTypeID    int `json:"pass_type_id" binding:"required"`
+	PaymentTypeID int `json:"payment_type_id" binding:"required"`
+}
+
+// PassRedeem is a request to pay a booking hours line from a client pass.
+type PassRedeem struct {
+	ClientPassID int `json:"client_pass_id" binding:"required"`
+}
+
+// PassRedemption records hours or a visit taken from a pass for a booking
+// line. Returned redemptions give the units back to the pass.
+type PassRedemption struct {
+	ID            int        `json:"id"`
+	ClientPassID  int        `json:"client_pass_id"`
+	BookingID     int        `json:"booking_id"`
+	BookingItemID int        `json:"booking_item_id"`
+	PaymentID     int        `json:"payment_id"`
+	Units         float64    `json:"units"`
+	Amount        int        `json:"amount"`
+	UserID        int        `json:"user_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReturnedAt    *time.Time `json:"returned_at,omitempty"`
+}
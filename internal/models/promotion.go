@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Promotion kinds: a percent off matching bill lines or a fixed amount off
+// the whole booking.
+const (
+	PromotionPercent = "percent"
+	PromotionAmount  = "amount"
+)
+
+// Promotion is a discount rule applied to bookings automatically. Empty
+// conditions do not restrict anything.
+type Promotion struct {
+	ID        int    `json:"id"`
+	CompanyID int    `json:"company_id"`
+	BranchID  int    `json:"branch_id"`
+	Name      string `json:"name" binding:"required"`
+	Kind      string `json:"kind"`
+	// Value - процент скидки или сумма скидки в зависимости от Kind
+	Value int `json:"value"`
+	// CategoryID и ItemID ограничивают процентную скидку строками счета
+	CategoryID int `json:"category_id,omitempty"`
+	ItemID     int `json:"item_id,omitempty"`
+	// DaysOfWeek - дни недели начала брони, "12345" - будни (1 - понедельник)
+	DaysOfWeek string `json:"days_of_week,omitempty"`
+	// TimeFrom и TimeTo - окно времени начала брони в формате 15:04
+	TimeFrom        string     `json:"time_from,omitempty"`
+	TimeTo          string     `json:"time_to,omitempty"`
+	ValidFrom       *time.Time `json:"valid_from,omitempty"`
+	ValidTo         *time.Time `json:"valid_to,omitempty"`
+	MinSpend        int        `json:"min_spend,omitempty"`
+	MinClientVisits int        `json:"min_client_visits,omitempty"`
+	// Code - промокод, акция с кодом действует только при его вводе
+	Code       string `json:"code,omitempty"`
+	UsageLimit int    `json:"usage_limit,omitempty"`
+	UsedCount  int    `json:"used_count"`
+	// Stackable - скидка суммируется с другими суммируемыми акциями,
+	// иначе действует только одна лучшая акция
+	Stackable bool `json:"stackable"`
+	Priority  int  `json:"priority"`
+	IsActive  bool `json:"is_active"`
+}
+
+// BookingPromotion records a discount produced by a promotion for a bill
+// line or, without BookingItemID, for the whole booking.
+type BookingPromotion struct {
+	ID            int    `json:"id"`
+	BookingID     int    `json:"booking_id"`
+	BookingItemID int    `json:"booking_item_id,omitempty"`
+	PromotionID   int    `json:"promotion_id"`
+	PromotionName string `json:"promotion_name,omitempty"`
+	Amount        int    `json:"amount"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PublicReservation is a booking request made by a guest without an
+// employee account. Phone is confirmed with a one-time code.
+type PublicReservation struct {
+	Name      string        `json:"name"`
+	Phone     string        `json:"phone"`
+	Code      string        `json:"code"`
+	TableID   int           `json:"table_id"`
+	StartTime time.Time     `json:"start_time"`
+	EndTime   time.Time     `json:"end_time"`
+	Note      string        `json:"note"`
+	Items     []BookingItem `json:"items"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// RecurringExpense is a template the scheduler uses to generate expenses
+// (rent, utilities, subscriptions).
+//
+// Schedule is one of:
+//   - "monthly": on DayOfMonth (clamped to the last day of short months)
+//   - "weekly":  on DayOfWeek (0 - Sunday ... 6 - Saturday)
+//   - "custom":  Cron expression "day-of-month month day-of-week"
+type RecurringExpense struct {
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	CategoryID   int        `json:"category_id,omitempty"`
+	Category     string     `json:"category"`
+	Total        float64    `json:"total"`
+	Description  string     `json:"description"`
+	Counterparty string     `json:"counterparty"`
+	Schedule     string     `json:"schedule"`
+	DayOfMonth   int        `json:"day_of_month"`
+	DayOfWeek    int        `json:"day_of_week"`
+	Cron         string     `json:"cron"`
+	MarkPaid     bool       `json:"mark_paid"`
+	DueDays      int        `json:"due_days"`
+	Paused       bool       `json:"paused"`
+	NextRun      time.Time  `json:"next_run"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompanyID    int        `json:"company_id"`
+	BranchID     int        `json:"branch_id"`
+}
+
+// UpcomingExpense is a planned charge of a recurring expense.
+type UpcomingExpense struct {
+	RecurringExpenseID int       `json:"recurring_expense_id"`
+	Title              string    `json:"title"`
+	Category           string    `json:"category"`
+	Date               time.Time `json:"date"`
+	Total              float64   `json:"total"`
+}
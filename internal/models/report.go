@@ -18,6 +18,7 @@ type SummaryReport struct {
 	ClientsChange  float64        `json:"clients_change_percent"`
 	AvgCheckChange float64        `json:"avg_check_change_percent"`
 	LoadChange     float64        `json:"load_change_percent"`
+	Cancellations  Cancellations  `json:"cancellations"`
 }
 
 type AdminsReport struct {
@@ -66,13 +67,33 @@ type SalesReport struct {
 	IncomeByPayment  []CategoryIncome `json:"income_by_payment_type,omitempty"`
 	TotalIncome      float64          `json:"total_income,omitempty"`
 	TotalExpenses    float64          `json:"total_expenses,omitempty"`
+	PaidExpenses     float64          `json:"paid_expenses,omitempty"`
+	UnpaidExpenses   float64          `json:"unpaid_expenses,omitempty"`
 	NetProfit        float64          `json:"net_profit,omitempty"`
+	// Forecast - ещё не созданные регулярные расходы в пределах периода
+	Forecast      []UpcomingExpense `json:"forecast,omitempty"`
+	ForecastTotal float64           `json:"forecast_total,omitempty"`
+	// DepositIncome - депозиты, внесенные за период. ForfeitedDeposits -
+	// депозиты, удержанные при неявке и поздней отмене
+	DepositIncome     float64 `json:"deposit_income,omitempty"`
+	ForfeitedDeposits float64 `json:"forfeited_deposits,omitempty"`
+	// PassSales - продажи абонементов за период. PassRedemptions - стоимость
+	// часов, оплаченных абонементами, она уже входит в доход по категориям
+	PassSales       float64 `json:"pass_sales,omitempty"`
+	PassRedemptions float64 `json:"pass_redemptions,omitempty"`
+	// CertificateSales и CertificateRedemptions - то же для подарочных сертификатов
+	CertificateSales       float64 `json:"certificate_sales,omitempty"`
+	CertificateRedemptions float64 `json:"certificate_redemptions,omitempty"`
+	// WalletTopUps и WalletPayments - то же для денег на счету клиентов
+	WalletTopUps   float64 `json:"wallet_top_ups,omitempty"`
+	WalletPayments float64 `json:"wallet_payments,omitempty"`
 }
 
 type AnalyticsReport struct {
 	DailyRevenue  []DataPoint    `json:"daily_revenue"`
 	HourlyLoad    []DataPoint    `json:"hourly_load"`
 	CategoryStats []CategoryStat `json:"category_stats"`
+	PrepTimes     []PrepStat     `json:"prep_times"`
 }
 
 type DataPoint struct {
@@ -88,12 +109,32 @@ type CategoryStat struct {
 }
 
 type DiscountsReport struct {
-	TotalDiscount     int         `json:"total_discount"`
-	DiscountCount     int         `json:"discount_count"`
-	AvgDiscount       int         `json:"avg_discount"`
-	TopReasons        []ReasonRow `json:"top_reasons"`
-	DistributionBySum []DataPoint `json:"distribution_by_sum"`
-	Orders            []Booking   `json:"orders"`
+	TotalDiscount     int           `json:"total_discount"`
+	DiscountCount     int           `json:"discount_count"`
+	AvgDiscount       int           `json:"avg_discount"`
+	TopReasons        []ReasonRow   `json:"top_reasons"`
+	DistributionBySum []DataPoint   `json:"distribution_by_sum"`
+	Orders            []Booking     `json:"orders"`
+	Cancellations     Cancellations `json:"cancellations"`
+	// ByPromotion - скидки, которые дали акции и промокоды
+	ByPromotion []PromotionRow `json:"by_promotion"`
+}
+
+// PromotionRow sums discounts given by a promotion over the period.
+type PromotionRow struct {
+	PromotionID int    `json:"promotion_id"`
+	Name        string `json:"name"`
+	Count       int    `json:"count"`
+	Amount      int    `json:"amount"`
+}
+
+// Cancellations summarizes cancelled bookings of the period.
+type Cancellations struct {
+	Count    int         `json:"count"`
+	Amount   int         `json:"amount"`
+	Refunded int         `json:"refunded"`
+	ByStatus []DataPoint `json:"by_status"`
+	ByReason []ReasonRow `json:"by_reason"`
 }
 
 type ReasonRow struct {
@@ -128,7 +169,126 @@ type TableReport struct {
 	TotalRevenue      float64          `json:"total_revenue"`
 	AvgCheck          float64          `json:"avg_check"`
 	LoadPercent       float64          `json:"load_percent"`
+	OccupiedHours     float64          `json:"occupied_hours"`
 	Visits            float64          `json:"visits"`
 	PaymentTypeIncome []CategoryIncome `json:"income_by_payment_type"`
 	TotalCost         float64          `json:"total_cost"`
 }
+
+// PayablesReport shows outstanding supplier debts grouped by how long they are
+// overdue.
+type PayablesReport struct {
+	TotalOutstanding float64            `json:"total_outstanding"`
+	TotalOverdue     float64            `json:"total_overdue"`
+	Buckets          []AgingBucket      `json:"buckets"`
+	Counterparties   []CounterpartyDebt `json:"counterparties"`
+}
+
+type AgingBucket struct {
+	Label  string  `json:"label"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+type CounterpartyDebt struct {
+	Counterparty string  `json:"counterparty"`
+	Amount       float64 `json:"amount"`
+	Overdue      float64 `json:"overdue"`
+}
+
+// CertificatesReport shows the liability of sold gift certificates: money
+// received for which the club still owes services.
+type CertificatesReport struct {
+	TotalOutstanding float64 `json:"total_outstanding"`
+	Count            int     `json:"count"`
+	// ExpiredBalance - остатки истекших сертификатов, обязательство по ним снято
+	ExpiredBalance float64           `json:"expired_balance"`
+	Certificates   []GiftCertificate `json:"certificates"`
+}
+
+// WalletsReport shows money clients keep on account, the club owes it as
+// services.
+type WalletsReport struct {
+	TotalOutstanding int                `json:"total_outstanding"`
+	Count            int                `json:"count"`
+	Clients          []ClientWalletLine `json:"clients"`
+}
+
+type ClientWalletLine struct {
+	ClientID int    `json:"client_id"`
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	Balance  int    `json:"balance"`
+}
+
+// CompanySummaryReport sums up summary reports of all branches of a company.
+type CompanySummaryReport struct {
+	Total        float64 `json:"total"`
+	TotalRevenue float64 `json:"total_revenue"`
+	TotalClients float64 `json:"total_clients"`
+	AvgCheck     float64 `json:"avg_check"`
+	TotalCost    float64 `json:"total_cost"`
+	// LoadPercent - средняя загрузка филиалов, взвешенная по числу столов
+	LoadPercent float64         `json:"load_percent"`
+	Branches    []BranchSummary `json:"branches"`
+}
+
+type BranchSummary struct {
+	BranchID   int    `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	SummaryReport
+}
+
+// CompanySalesReport sums up sales reports of all branches of a company.
+type CompanySalesReport struct {
+	IncomeByCategory []CategoryIncome `json:"income_by_category"`
+	IncomeByPayment  []CategoryIncome `json:"income_by_payment_type"`
+	TotalIncome      float64          `json:"total_income"`
+	TotalExpenses    float64          `json:"total_expenses"`
+	PaidExpenses     float64          `json:"paid_expenses"`
+	UnpaidExpenses   float64          `json:"unpaid_expenses"`
+	NetProfit        float64          `json:"net_profit"`
+	ForecastTotal    float64          `json:"forecast_total"`
+	Branches         []BranchSales    `json:"branches"`
+}
+
+type BranchSales struct {
+	BranchID   int    `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	SalesReport
+}
+
+type CompanyAdminsReport struct {
+	Admins []BranchAdminRow `json:"admins"`
+}
+
+type BranchAdminRow struct {
+	BranchID   int    `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	AdminReportRow
+}
+
+type CompanyTablesReport struct {
+	Tables []BranchTableRow `json:"tables"`
+}
+
+type BranchTableRow struct {
+	BranchID   int    `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	TableReport
+}
+
+// BranchComparison puts the key figures of branches side by side.
+// RevenueShare - доля филиала в выручке компании, %
+type BranchComparison struct {
+	BranchID     int     `json:"branch_id"`
+	BranchName   string  `json:"branch_name"`
+	TotalRevenue float64 `json:"total_revenue"`
+	TotalClients float64 `json:"total_clients"`
+	AvgCheck     float64 `json:"avg_check"`
+	LoadPercent  float64 `json:"load_percent"`
+	TotalIncome  float64 `json:"total_income"`
+	Expenses     float64 `json:"total_expenses"`
+	NetProfit    float64 `json:"net_profit"`
+	RevenueShare float64 `json:"revenue_share_percent"`
+}
@@ -0,0 +1,67 @@
+package models
+
+// SeedTemplate describes what a new club gets on signup: halls with tables,
+// catalog, payment types, expense categories, settings and, optionally,
+// sample price items and sets. Templates are kept in config/templates.
+type SeedTemplate struct {
+	Key               string              `yaml:"key" json:"key"`
+	Name              string              `yaml:"name" json:"name"`
+	Version           int                 `yaml:"version" json:"version"`
+	Description       string              `yaml:"description" json:"description,omitempty"`
+	TableCategories   []SeedTableCategory `yaml:"table_categories" json:"table_categories"`
+	Categories        []SeedCategory      `yaml:"categories" json:"categories"`
+	PaymentTypes      []SeedPaymentType   `yaml:"payment_types" json:"payment_types"`
+	ExpenseCategories []string            `yaml:"expense_categories" json:"expense_categories"`
+	Settings          SeedSettings        `yaml:"settings" json:"settings"`
+	PriceItems        []SeedPriceItem     `yaml:"price_items" json:"price_items,omitempty"`
+	Sets              []SeedSet           `yaml:"sets" json:"sets,omitempty"`
+}
+
+type SeedTableCategory struct {
+	Name string `yaml:"name" json:"name"`
+	// Tables - количество столов, названия "<TablePrefix> N"
+	Tables      int    `yaml:"tables" json:"tables"`
+	TablePrefix string `yaml:"table_prefix" json:"table_prefix"`
+}
+
+type SeedCategory struct {
+	Name          string   `yaml:"name" json:"name"`
+	Subcategories []string `yaml:"subcategories" json:"subcategories,omitempty"`
+	// Station - станция, где готовятся позиции категории (бар, кальянная),
+	// пусто - позиции выдаются сразу
+	Station string `yaml:"station" json:"station,omitempty"`
+}
+
+type SeedPaymentType struct {
+	Name        string  `yaml:"name" json:"name"`
+	HoldPercent float64 `yaml:"hold_percent" json:"hold_percent"`
+}
+
+type SeedSettings struct {
+	BlockTime        int    `yaml:"block_time" json:"block_time"`
+	BonusPercent     int    `yaml:"bonus_percent" json:"bonus_percent"`
+	WorkTimeFrom     string `yaml:"work_time_from" json:"work_time_from"`
+	WorkTimeTo       string `yaml:"work_time_to" json:"work_time_to"`
+	NotificationTime int    `yaml:"notification_time" json:"notification_time"`
+}
+
+type SeedPriceItem struct {
+	Name        string  `yaml:"name" json:"name"`
+	Category    string  `yaml:"category" json:"category"`
+	Subcategory string  `yaml:"subcategory" json:"subcategory,omitempty"`
+	SalePrice   float64 `yaml:"sale_price" json:"sale_price"`
+	BuyPrice    float64 `yaml:"buy_price" json:"buy_price"`
+}
+
+type SeedSet struct {
+	Name        string        `yaml:"name" json:"name"`
+	Category    string        `yaml:"category" json:"category"`
+	Subcategory string        `yaml:"subcategory" json:"subcategory,omitempty"`
+	Price       int           `yaml:"price" json:"price"`
+	Items       []SeedSetItem `yaml:"items" json:"items"`
+}
+
+type SeedSetItem struct {
+	Item     string  `yaml:"item" json:"item"`
+	Quantity float64 `yaml:"quantity" json:"quantity"`
+}
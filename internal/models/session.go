@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session is a device the user is logged in on, one per refresh token.
+type Session struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	BranchID   int        `json:"branch_id"`
+	DeviceName string     `json:"device_name"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	// DeviceID - подключенный планшет, на котором начата сессия входа по PIN
+	DeviceID int `json:"device_id,omitempty"`
+	// Current - сессия, из которой сделан запрос
+	Current bool `json:"current"`
+}
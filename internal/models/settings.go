@@ -1,16 +1,60 @@
 package models
 
 type Settings struct {
-	ID               int           `json:"id"`
-	PaymentType      int           `json:"payment_type"`
-	BlockTime        int           `json:"block_time"`
-	BonusPercent     int           `json:"bonus_percent"`
-	WorkTimeFrom     string        `json:"work_time_from"`
-	WorkTimeTo       string        `json:"work_time_to"`
-	TablesCount      int           `json:"tables_count"`
-	NotificationTime int           `json:"notification_time"`
-	CompanyID        int           `json:"company_id"`
-	BranchID         int           `json:"branch_id"`
-	PaymentTypes     []PaymentType `json:"payment_types"` // список всех типов
-	Channels         []Channel     `json:"channels"`      // список всех каналов
+	ID                      int           `json:"id"`
+	PaymentType             int           `json:"payment_type"`
+	BlockTime               int           `json:"block_time"`
+	BonusPercent            int           `json:"bonus_percent"`
+	WorkTimeFrom            string        `json:"work_time_from"`
+	WorkTimeTo              string        `json:"work_time_to"`
+	TablesCount             int           `json:"tables_count"`
+	NotificationTime        int           `json:"notification_time"`
+	DepositForfeitPercent   int           `json:"deposit_forfeit_percent"` // удержание депозита при неявке, %
+	ReservationGraceMinutes int           `json:"reservation_grace_minutes"`
+	DepositRefundHours      int           `json:"deposit_refund_hours"`   // полный возврат депозита при отмене раньше, ч
+	CleanupBufferMinutes    int           `json:"cleanup_buffer_minutes"` // перерыв между сеансами за столом
+	CompanyID               int           `json:"company_id"`
+	BranchID                int           `json:"branch_id"`
+	PaymentTypes            []PaymentType `json:"payment_types"` // список всех типов
+	Channels                []Channel     `json:"channels"`      // список всех каналов
+}
+
+// SettingsUpdate is a partial change of settings: only fields present in
+// the request are changed, the rest keep their stored values.
+type SettingsUpdate struct {
+	PaymentType             *int    `json:"payment_type"`
+	BlockTime               *int    `json:"block_time"`
+	BonusPercent            *int    `json:"bonus_percent"`
+	WorkTimeFrom            *string `json:"work_time_from"`
+	WorkTimeTo              *string `json:"work_time_to"`
+	TablesCount             *int    `json:"tables_count"`
+	NotificationTime        *int    `json:"notification_time"`
+	DepositForfeitPercent   *int    `json:"deposit_forfeit_percent"`
+	ReservationGraceMinutes *int    `json:"reservation_grace_minutes"`
+	DepositRefundHours      *int    `json:"deposit_refund_hours"`
+	CleanupBufferMinutes    *int    `json:"cleanup_buffer_minutes"`
+}
+
+// Apply copies the fields present in the update to s.
+func (u *SettingsUpdate) Apply(s *Settings) {
+	setInt := func(dst *int, v *int) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	setInt(&s.PaymentType, u.PaymentType)
+	setInt(&s.BlockTime, u.BlockTime)
+	setInt(&s.BonusPercent, u.BonusPercent)
+	setInt(&s.TablesCount, u.TablesCount)
+	setInt(&s.NotificationTime, u.NotificationTime)
+	setInt(&s.DepositForfeitPercent, u.DepositForfeitPercent)
+	setInt(&s.ReservationGraceMinutes, u.ReservationGraceMinutes)
+	setInt(&s.DepositRefundHours, u.DepositRefundHours)
+	setInt(&s.CleanupBufferMinutes, u.CleanupBufferMinutes)
+	if u.WorkTimeFrom != nil {
+		s.WorkTimeFrom = *u.WorkTimeFrom
+	}
+	if u.WorkTimeTo != nil {
+		s.WorkTimeTo = *u.WorkTimeTo
+	}
 }
@@ -1,20 +1,67 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Роли сотрудников. Владелец видит всю компанию и все её филиалы, директор
+// управляет филиалом, администратор работает на смене.
+const (
+	RoleOwner    = "owner"
+	RoleDirector = "director"
+	RoleAdmin    = "admin"
+)
 
 type User struct {
-	ID               int       `json:"id"`
-	Name             string    `json:"name"`
-	Phone            string    `json:"phone"`
-	Password         string    `json:"password"`
-	CompanyID        int       `json:"company_id"`
-	BranchID         int       `json:"branch_id"`
-	Role             string    `json:"role"`
-	Permissions      []string  `json:"permissions"`
-	SalaryHookah     float64   `json:"salary_hookah"`
-	HookahSalaryType string    `json:"hookah_salary_type"`
-	SalaryBar        float64   `json:"salary_bar"`
-	SalaryShift      int       `json:"salary_shift"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID               int      `json:"id"`
+	Name             string   `json:"name"`
+	Phone            string   `json:"phone"`
+	Password         string   `json:"password"`
+	CompanyID        int      `json:"company_id"`
+	BranchID         int      `json:"branch_id"`
+	Role             string   `json:"role"`
+	Permissions      []string `json:"permissions"`
+	SalaryHookah     float64  `json:"salary_hookah"`
+	HookahSalaryType string   `json:"hookah_salary_type"`
+	SalaryBar        float64  `json:"salary_bar"`
+	SalaryShift      int      `json:"salary_shift"`
+	// SalaryEffectiveFrom задаёт дату, с которой действуют новые ставки (по умолчанию — сегодня)
+	SalaryEffectiveFrom *time.Time `json:"salary_effective_from,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// UnmarshalJSON accepts salary_effective_from both as a date "2006-01-02"
+// and as a full RFC 3339 time.
+func (u *User) UnmarshalJSON(data []byte) error {
+	type user User
+	aux := struct {
+		*user
+		SalaryEffectiveFrom *string `json:"salary_effective_from,omitempty"`
+	}{user: (*user)(u)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	u.SalaryEffectiveFrom = nil
+	if aux.SalaryEffectiveFrom == nil || *aux.SalaryEffectiveFrom == "" {
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, *aux.SalaryEffectiveFrom); err == nil {
+			u.SalaryEffectiveFrom = &t
+			return nil
+		}
+	}
+	return &time.ParseError{Layout: "2006-01-02", Value: *aux.SalaryEffectiveFrom, Message: ": invalid salary_effective_from"}
+}
+
+// UserBranch gives a user access to a branch of the company with the role
+// the user has there.
+type UserBranch struct {
+	UserID     int    `json:"user_id"`
+	CompanyID  int    `json:"company_id"`
+	BranchID   int    `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	Role       string `json:"role"`
 }
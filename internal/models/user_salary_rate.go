@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserSalaryRate is a snapshot of user's salary settings valid from EffectiveFrom
+// until the next rate for the same user.
+type UserSalaryRate struct {
+	ID               int       `json:"id"`
+	UserID           int       `json:"user_id"`
+	CompanyID        int       `json:"company_id"`
+	BranchID         int       `json:"branch_id"`
+	SalaryShift      int       `json:"salary_shift"`
+	SalaryHookah     float64   `json:"salary_hookah"`
+	HookahSalaryType string    `json:"hookah_salary_type"`
+	SalaryBar        float64   `json:"salary_bar"`
+	EffectiveFrom    time.Time `json:"effective_from"`
+	CreatedAt        time.Time `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// PaymentMethodWallet marks a booking payment taken from the money a client
+// keeps on account.
+const PaymentMethodWallet = "wallet"
+
+// Kinds of client wallet entries.
+const (
+	WalletTopUp   = "topup"
+	WalletPayment = "payment"
+	WalletReturn  = "return"
+)
+
+// WalletEntry records a change of a client wallet balance. Amount is
+// positive for money put on the account and negative for money spent.
+type WalletEntry struct {
+	ID            int       `json:"id"`
+	CompanyID     int       `json:"company_id"`
+	BranchID      int       `json:"branch_id"`
+	ClientID      int       `json:"client_id"`
+	Kind          string    `json:"kind"`
+	Amount        int       `json:"amount"`
+	BalanceAfter  int       `json:"balance_after"`
+	PaymentTypeID int       `json:"payment_type_id,omitempty"`
+	PaymentType   string    `json:"payment_type,omitempty"`
+	BookingID     int       `json:"booking_id,omitempty"`
+	UserID        int       `json:"user_id,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WalletTopUpRequest is a request to put money on a client wallet.
+type WalletTopUpRequest struct {
+	Amount        int    `json:"amount" binding:"required"`
+	PaymentTypeID int    `json:"payment_type_id" binding:"required"`
+	Note          string `json:"note"`
+}
+
+// WalletPay is a request to pay part of a booking bill from the wallet of
+// the booking client.
+type WalletPay struct {
+	Amount int `json:"amount" binding:"required"`
+}
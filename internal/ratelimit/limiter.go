@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is an in-memory sliding window limiter. It allows at most limit
+// events per key within window. State is kept per process only.
+type Limiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+	sweep  time.Time
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, hits: make(map[string][]time.Time)}
+}
+
+// Allow registers an event for key and reports whether it fits the limit.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.sweep) > l.window {
+		// периодически убираем ключи без событий в окне
+		for k, v := range l.hits {
+			if len(v) == 0 || now.Sub(v[len(v)-1]) > l.window {
+				delete(l.hits, k)
+			}
+		}
+		l.sweep = now
+	}
+	recent := l.hits[key][:0]
+	for _, t := range l.hits[key] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.hits[key] = recent
+		return false
+	}
+	l.hits[key] = append(recent, now)
+	return true
+}
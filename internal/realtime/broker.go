@@ -0,0 +1,52 @@
+package realtime
+
+import "sync"
+
+// Broker fans out events published to a topic to its subscribers. State is
+// kept per process only. A subscriber that does not keep up loses events
+// instead of blocking the publisher.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[string]map[chan interface{}]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[string]map[chan interface{}]struct{})}
+}
+
+// Subscribe returns a channel with events of the topic and a function that
+// must be called to unsubscribe.
+func (b *Broker) Subscribe(topic string) (<-chan interface{}, func()) {
+	ch := make(chan interface{}, 16)
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan interface{}]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[topic], ch)
+			if len(b.subs[topic]) == 0 {
+				delete(b.subs, topic)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the event to every subscriber of the topic.
+func (b *Broker) Publish(topic string, event interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"psclub-crm/internal/models"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, a *models.AuditLog) error {
+	var diff interface{}
+	if len(a.Diff) > 0 {
+		diff = string(a.Diff)
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO audit_log (company_id, branch_id, user_id, role, entity, entity_id, action, diff, created_at)
+                VALUES (?, ?, NULLIF(?,0), ?, ?, ?, ?, ?, NOW())`,
+		a.CompanyID, a.BranchID, a.UserID, a.Role, a.Entity, a.EntityID, a.Action, diff)
+	return err
+}
+
+// List returns audit records of the branch, newest first.
+func (r *AuditRepository) List(ctx context.Context, companyID, branchID int, f models.AuditFilter) ([]models.AuditLog, error) {
+	query := `SELECT a.id, a.company_id, a.branch_id, IFNULL(a.user_id, 0), IFNULL(u.name, ''), a.role, a.entity, a.entity_id, a.action, IFNULL(a.diff, 'null'), a.created_at
+                FROM audit_log a
+                LEFT JOIN users u ON a.user_id = u.id
+                WHERE a.company_id=? AND a.branch_id=?`
+	args := []interface{}{companyID, branchID}
+	if f.Entity != "" {
+		query += " AND a.entity=?"
+		args = append(args, f.Entity)
+	}
+	if f.EntityID > 0 {
+		query += " AND a.entity_id=?"
+		args = append(args, f.EntityID)
+	}
+	if f.UserID > 0 {
+		query += " AND a.user_id=?"
+		args = append(args, f.UserID)
+	}
+	if f.Action != "" {
+		query += " AND a.action=?"
+		args = append(args, f.Action)
+	}
+	if !f.From.IsZero() {
+		query += " AND a.created_at >= ?"
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		query += " AND a.created_at < ?"
+		args = append(args, f.To)
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	query += " ORDER BY a.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, f.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.AuditLog
+	for rows.Next() {
+		var a models.AuditLog
+		var diff string
+		if err := rows.Scan(&a.ID, &a.CompanyID, &a.BranchID, &a.UserID, &a.UserName, &a.Role, &a.Entity, &a.EntityID, &a.Action, &diff, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if diff != "null" {
+			a.Diff = []byte(diff)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
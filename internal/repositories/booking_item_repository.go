@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"log"
 
 	"psclub-crm/internal/models"
 )
@@ -15,12 +16,34 @@ func NewBookingItemRepository(db *sql.DB) *BookingItemRepository {
 	return &BookingItemRepository{db: db}
 }
 
+// notVoided returns condition excluding voided booking lines. alias is the
+// booking_items table alias.
+func notVoided(alias string) string {
+	return " AND " + alias + ".status <> '" + models.BookingItemVoided + "'"
+}
+
+const bookingItemColumns = `bi.id, bi.booking_id, bi.company_id, bi.branch_id, bi.item_id, bi.quantity, bi.price, bi.discount, IFNULL(bi.promotion_id, 0), pi.name,
+               IFNULL(bi.user_id, 0), bi.status, bi.void_reason, bi.voided_at, IFNULL(bi.voided_by, 0), IFNULL(bi.created_at, NOW())`
+
+func scanBookingItem(sc interface{ Scan(...interface{}) error }) (models.BookingItem, error) {
+	var it models.BookingItem
+	var voidedAt sql.NullTime
+	err := sc.Scan(&it.ID, &it.BookingID, &it.CompanyID, &it.BranchID, &it.ItemID, &it.Quantity, &it.Price, &it.Discount, &it.PromotionID, &it.ItemName,
+		&it.UserID, &it.Status, &it.VoidReason, &voidedAt, &it.VoidedBy, &it.CreatedAt)
+	if voidedAt.Valid {
+		it.VoidedAt = &voidedAt.Time
+	}
+	return it, err
+}
+
 // GetByBookingID returns booking items for the specified booking filtered by company and branch.
+// Voided lines are included, callers changing stock or totals skip them.
 func (r *BookingItemRepository) GetByBookingID(ctx context.Context, companyID, branchID, bookingID int) ([]models.BookingItem, error) {
-	query := `SELECT bi.id, bi.booking_id, bi.company_id, bi.branch_id, bi.item_id, bi.quantity, bi.price, bi.discount, pi.name
+	query := `SELECT ` + bookingItemColumns + `
                FROM booking_items bi
                JOIN price_items pi ON bi.item_id = pi.id
-               WHERE bi.booking_id = ? AND bi.company_id = ? AND bi.branch_id = ?`
+               WHERE bi.booking_id = ? AND bi.company_id = ? AND bi.branch_id = ?
+               ORDER BY bi.created_at, bi.id`
 	rows, err := r.db.QueryContext(ctx, query, bookingID, companyID, branchID)
 	if err != nil {
 		return nil, err
@@ -29,11 +52,141 @@ func (r *BookingItemRepository) GetByBookingID(ctx context.Context, companyID, b
 
 	var items []models.BookingItem
 	for rows.Next() {
-		var it models.BookingItem
-		if err := rows.Scan(&it.ID, &it.BookingID, &it.CompanyID, &it.BranchID, &it.ItemID, &it.Quantity, &it.Price, &it.Discount, &it.ItemName); err != nil {
+		it, err := scanBookingItem(rows)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, it)
 	}
 	return items, nil
 }
+
+// GetByID returns a single line of a booking.
+func (r *BookingItemRepository) GetByID(ctx context.Context, companyID, branchID, bookingID, id int) (*models.BookingItem, error) {
+	query := `SELECT ` + bookingItemColumns + `
+               FROM booking_items bi
+               JOIN price_items pi ON bi.item_id = pi.id
+               WHERE bi.id = ? AND bi.booking_id = ? AND bi.company_id = ? AND bi.branch_id = ?`
+	it, err := scanBookingItem(r.db.QueryRowContext(ctx, query, id, bookingID, companyID, branchID))
+	if err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// Add inserts a line into an open booking and adds its cost to the booking
+// total within a single transaction.
+func (r *BookingItemRepository) Add(ctx context.Context, companyID, branchID int, it *models.BookingItem, cost int) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE bookings SET total_amount=total_amount+?, updated_at=NOW()
+        WHERE id=? AND company_id=? AND branch_id=? AND status IN (`+openStatuses+`)`, cost, it.BookingID, companyID, branchID)
+	if err != nil {
+		log.Printf("update booking total error: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = sql.ErrNoRows
+		return err
+	}
+	res, err = tx.ExecContext(ctx, `INSERT INTO booking_items (booking_id, company_id, branch_id, item_id, quantity, price, discount, promotion_id, user_id, status, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?,0), NULLIF(?,0), ?, NOW())`,
+		it.BookingID, companyID, branchID, it.ItemID, it.Quantity, it.Price, it.Discount, it.PromotionID, it.UserID, models.BookingItemActive)
+	if err != nil {
+		log.Printf("insert booking item error: %v", err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	it.ID = int(id)
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit booking item error: %v", err)
+	}
+	return err
+}
+
+// Void marks an active line voided and subtracts its cost from the booking
+// total. False is returned if the line was already voided.
+func (r *BookingItemRepository) Void(ctx context.Context, companyID, branchID, bookingID, id int, reason string, userID, cost int) (ok bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE booking_items SET status=?, void_reason=?, voided_at=NOW(), voided_by=NULLIF(?,0)
+        WHERE id=? AND booking_id=? AND company_id=? AND branch_id=? AND status=?`,
+		models.BookingItemVoided, reason, userID, id, bookingID, companyID, branchID, models.BookingItemActive)
+	if err != nil {
+		log.Printf("void booking item error: %v", err)
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE bookings SET total_amount=total_amount-?, updated_at=NOW() WHERE id=? AND company_id=? AND branch_id=?`,
+		cost, bookingID, companyID, branchID); err != nil {
+		log.Printf("update booking total error: %v", err)
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit booking item void error: %v", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// MoveLineRefs points pass redemptions not returned yet and open station
+// tickets of a booking line to another line of the same booking.
+func (r *BookingItemRepository) MoveLineRefs(ctx context.Context, companyID, branchID, bookingID, fromID, toID int) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `UPDATE pass_redemptions SET booking_item_id=?
+        WHERE booking_id=? AND booking_item_id=? AND company_id=? AND branch_id=? AND returned_at IS NULL`,
+		toID, bookingID, fromID, companyID, branchID); err != nil {
+		log.Printf("move pass redemptions error: %v", err)
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE order_tickets SET booking_item_id=?
+        WHERE booking_id=? AND booking_item_id=? AND company_id=? AND branch_id=? AND status IN (?, ?)`,
+		toID, bookingID, fromID, companyID, branchID, models.TicketNew, models.TicketInProgress); err != nil {
+		log.Printf("move order tickets error: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit line refs move error: %v", err)
+	}
+	return err
+}
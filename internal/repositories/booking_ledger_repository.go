@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"psclub-crm/internal/models"
+)
+
+// BookingLedgerRepository keeps stock and bonus movements made for bookings
+// outside of the regular create/update flow (e.g. on cancellation).
+type BookingLedgerRepository struct {
+	db *sql.DB
+}
+
+func NewBookingLedgerRepository(db *sql.DB) *BookingLedgerRepository {
+	return &BookingLedgerRepository{db: db}
+}
+
+func (r *BookingLedgerRepository) Create(ctx context.Context, companyID, branchID int, e *models.BookingLedgerEntry) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO booking_ledger (booking_id, company_id, branch_id, entry_type, item_id, client_id, quantity, amount, note, created_at)
+             VALUES (?, ?, ?, ?, NULLIF(?,0), NULLIF(?,0), ?, ?, ?, NOW())`,
+		e.BookingID, companyID, branchID, e.EntryType, e.ItemID, e.ClientID, e.Quantity, e.Amount, e.Note)
+	return err
+}
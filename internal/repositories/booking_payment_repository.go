@@ -20,27 +20,29 @@ func (r *BookingPaymentRepository) Create(ctx context.Context, companyID, branch
 	if len(payments) == 0 {
 		return nil
 	}
-	query := `INSERT INTO booking_payments (booking_id, company_id, branch_id, payment_type_id, amount) VALUES (?, ?, ?, ?, ?)`
+	query := `INSERT INTO booking_payments (booking_id, company_id, branch_id, payment_type_id, amount, is_deposit, share_id, method, client_pass_id, created_at) VALUES (?, ?, ?, NULLIF(?,0), ?, ?, NULLIF(?,0), ?, NULLIF(?,0), NOW())`
 	for _, p := range payments {
-		if _, err := r.db.ExecContext(ctx, query, bookingID, companyID, branchID, p.PaymentTypeID, p.Amount); err != nil {
+		if _, err := r.db.ExecContext(ctx, query, bookingID, companyID, branchID, p.PaymentTypeID, p.Amount, p.IsDeposit, p.ShareID, p.Method, p.ClientPassID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// DeleteByBookingID removes all payments for a booking.
-func (r *BookingPaymentRepository) DeleteByBookingID(ctx context.Context, companyID, branchID, bookingID int) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM booking_payments WHERE booking_id = ? AND company_id = ? AND branch_id = ?`, bookingID, companyID, branchID)
+// DeleteRegularByBookingID removes payments for a booking except deposits
+// and pass payments, which stay attached to the booking until it is closed.
+func (r *BookingPaymentRepository) DeleteRegularByBookingID(ctx context.Context, companyID, branchID, bookingID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM booking_payments WHERE booking_id = ? AND company_id = ? AND branch_id = ? AND is_deposit = 0 AND method = ''`, bookingID, companyID, branchID)
 	return err
 }
 
 // GetByBookingID returns all payments for a specific booking.
 func (r *BookingPaymentRepository) GetByBookingID(ctx context.Context, companyID, branchID, bookingID int) ([]models.BookingPayment, error) {
-	query := `SELECT bp.id, bp.booking_id, bp.company_id, bp.branch_id, bp.payment_type_id, bp.amount, pt.name
+	query := `SELECT bp.id, bp.booking_id, bp.company_id, bp.branch_id, IFNULL(bp.payment_type_id, 0), bp.amount, pt.name, bp.is_deposit, IFNULL(bp.share_id, 0), bp.method, IFNULL(bp.client_pass_id, 0), IFNULL(bp.gift_certificate_id, 0), IFNULL(bp.wallet_client_id, 0), IFNULL(bp.created_at, NOW())
              FROM booking_payments bp
              LEFT JOIN payment_types pt ON bp.payment_type_id = pt.id
-             WHERE bp.booking_id = ? AND bp.company_id = ? AND bp.branch_id = ?`
+             WHERE bp.booking_id = ? AND bp.company_id = ? AND bp.branch_id = ?
+             ORDER BY bp.id`
 	rows, err := r.db.QueryContext(ctx, query, bookingID, companyID, branchID)
 	if err != nil {
 		return nil, err
@@ -50,7 +52,7 @@ func (r *BookingPaymentRepository) GetByBookingID(ctx context.Context, companyID
 	var payments []models.BookingPayment
 	for rows.Next() {
 		var p models.BookingPayment
-		if err := rows.Scan(&p.ID, &p.BookingID, &p.CompanyID, &p.BranchID, &p.PaymentTypeID, &p.Amount, &p.PaymentType); err != nil {
+		if err := rows.Scan(&p.ID, &p.BookingID, &p.CompanyID, &p.BranchID, &p.PaymentTypeID, &p.Amount, &p.PaymentType, &p.IsDeposit, &p.ShareID, &p.Method, &p.ClientPassID, &p.GiftCertificateID, &p.WalletClientID, &p.CreatedAt); err != nil {
 			return nil, err
 		}
 		payments = append(payments, p)
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"psclub-crm/internal/models"
+)
+
+// BookingRefundRepository stores money returned for cancelled bookings.
+type BookingRefundRepository struct {
+	db *sql.DB
+}
+
+func NewBookingRefundRepository(db *sql.DB) *BookingRefundRepository {
+	return &BookingRefundRepository{db: db}
+}
+
+// Create inserts refund lines for a booking.
+func (r *BookingRefundRepository) Create(ctx context.Context, companyID, branchID, bookingID int, refunds []models.BookingRefund) error {
+	query := `INSERT INTO booking_refunds (booking_id, company_id, branch_id, payment_type_id, amount, user_id, created_at) VALUES (?, ?, ?, NULLIF(?,0), ?, NULLIF(?,0), NOW())`
+	for _, rf := range refunds {
+		if _, err := r.db.ExecContext(ctx, query, bookingID, companyID, branchID, rf.PaymentTypeID, rf.Amount, rf.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByBookingID returns refunds of a booking.
+func (r *BookingRefundRepository) GetByBookingID(ctx context.Context, companyID, branchID, bookingID int) ([]models.BookingRefund, error) {
+	query := `SELECT br.id, br.booking_id, IFNULL(br.payment_type_id, 0), IFNULL(pt.name, ''), br.amount, IFNULL(br.user_id, 0), br.created_at
+             FROM booking_refunds br
+             LEFT JOIN payment_types pt ON br.payment_type_id = pt.id
+             WHERE br.booking_id = ? AND br.company_id = ? AND br.branch_id = ?
+             ORDER BY br.id`
+	rows, err := r.db.QueryContext(ctx, query, bookingID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var refunds []models.BookingRefund
+	for rows.Next() {
+		var rf models.BookingRefund
+		if err := rows.Scan(&rf.ID, &rf.BookingID, &rf.PaymentTypeID, &rf.PaymentType, &rf.Amount, &rf.UserID, &rf.CreatedAt); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, rf)
+	}
+	return refunds, rows.Err()
+}
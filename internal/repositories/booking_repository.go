@@ -17,6 +17,20 @@ func NewBookingRepository(db *sql.DB) *BookingRepository {
 	return &BookingRepository{db: db}
 }
 
+// cancelledStatuses are booking statuses that neither occupy a table nor
+// count as revenue.
+const cancelledStatuses = "'cancelled','no_show','refunded','partially_refunded'"
+
+// notCancelled returns condition excluding cancelled bookings. alias is the
+// bookings table alias, empty when columns are used without prefix.
+func notCancelled(alias string) string {
+	col := "status"
+	if alias != "" {
+		col = alias + ".status"
+	}
+	return " AND " + col + " NOT IN (" + cancelledStatuses + ")"
+}
+
 func (r *BookingRepository) CreateWithItems(ctx context.Context, companyID, branchID int, b *models.Booking) (int, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -29,8 +43,8 @@ func (r *BookingRepository) CreateWithItems(ctx context.Context, companyID, bran
 		}
 	}()
 
-	query := `INSERT INTO bookings (company_id, branch_id, client_id, table_id, user_id, start_time, end_time, note, discount, discount_reason, total_amount, bonus_used, payment_status, payment_type_id, created_at, updated_at)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`
+	query := `INSERT INTO bookings (company_id, branch_id, client_id, table_id, user_id, start_time, end_time, note, discount, discount_reason, promo_code, promotion_id, total_amount, bonus_used, payment_status, payment_type_id, status, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?,''), NULLIF(?,0), ?, ?, ?, ?, ?, NOW(), NOW())`
 
 	var clientID interface{}
 	if b.ClientID > 0 {
@@ -53,7 +67,7 @@ func (r *BookingRepository) CreateWithItems(ctx context.Context, companyID, bran
 		userID = nil
 	}
 
-	res, err := tx.ExecContext(ctx, query, companyID, branchID, clientID, tableID, userID, b.StartTime, b.EndTime, b.Note, b.Discount, b.DiscountReason, b.TotalAmount, b.BonusUsed, b.PaymentStatus, b.PaymentTypeID)
+	res, err := tx.ExecContext(ctx, query, companyID, branchID, clientID, tableID, userID, b.StartTime, b.EndTime, b.Note, b.Discount, b.DiscountReason, b.PromoCode, b.PromotionID, b.TotalAmount, b.BonusUsed, b.PaymentStatus, b.PaymentTypeID, b.Status)
 	if err != nil {
 		log.Printf("insert booking error: %v", err)
 		return 0, err
@@ -65,13 +79,16 @@ func (r *BookingRepository) CreateWithItems(ctx context.Context, companyID, bran
 	}
 
 	if len(b.Items) > 0 {
-		itemQuery := `INSERT INTO booking_items (booking_id, company_id, branch_id, item_id, quantity, price, discount) VALUES (?, ?, ?, ?, ?, ?, ?)`
-		for _, item := range b.Items {
-			_, err := tx.ExecContext(ctx, itemQuery, bookingID, companyID, branchID, item.ItemID, item.Quantity, item.Price, item.Discount)
+		itemQuery := `INSERT INTO booking_items (booking_id, company_id, branch_id, item_id, quantity, price, discount, promotion_id, user_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?,0), NULLIF(?,0), NOW())`
+		for i, item := range b.Items {
+			res, err := tx.ExecContext(ctx, itemQuery, bookingID, companyID, branchID, item.ItemID, item.Quantity, item.Price, item.Discount, item.PromotionID, item.UserID)
 			if err != nil {
 				log.Printf("insert booking item error: %v", err)
 				return 0, err
 			}
+			if lineID, err := res.LastInsertId(); err == nil {
+				b.Items[i].ID = int(lineID)
+			}
 		}
 	}
 
@@ -84,7 +101,7 @@ func (r *BookingRepository) CreateWithItems(ctx context.Context, companyID, bran
 }
 
 func (r *BookingRepository) GetAll(ctx context.Context, companyID, branchID int) ([]models.Booking, error) {
-	query := `SELECT b.id, b.company_id, b.branch_id, b.client_id, table_id, b.user_id, start_time, end_time, note, discount, discount_reason, total_amount, bonus_used, payment_status, payment_type_id, b.created_at, b.updated_at,
+	query := `SELECT b.id, b.company_id, b.branch_id, b.client_id, table_id, b.user_id, start_time, end_time, note, discount, discount_reason, total_amount, bonus_used, payment_status, payment_type_id, b.status, b.cancel_reason, b.cancelled_at, IFNULL(b.cancelled_by, 0), b.created_at, b.updated_at,
                               IFNULL(c.name, ''), IFNULL(c.phone, ''), payment_types.name AS payment_type, IFNULL(channels.name, '') AS channel_name
                               FROM bookings b
                               LEFT JOIN clients c ON b.client_id = c.id
@@ -105,7 +122,8 @@ func (r *BookingRepository) GetAll(ctx context.Context, companyID, branchID int)
 		var tableID sql.NullInt64
 		var userID sql.NullInt64
 		var channelName sql.NullString
-		err := rows.Scan(&b.ID, &b.CompanyID, &b.BranchID, &clientID, &tableID, &userID, &b.StartTime, &b.EndTime, &b.Note, &b.Discount, &b.DiscountReason, &b.TotalAmount, &b.BonusUsed, &b.PaymentStatus, &b.PaymentTypeID, &b.CreatedAt, &b.UpdatedAt, &b.ClientName, &b.ClientPhone, &b.PaymentType, &channelName)
+		var cancelledAt sql.NullTime
+		err := rows.Scan(&b.ID, &b.CompanyID, &b.BranchID, &clientID, &tableID, &userID, &b.StartTime, &b.EndTime, &b.Note, &b.Discount, &b.DiscountReason, &b.TotalAmount, &b.BonusUsed, &b.PaymentStatus, &b.PaymentTypeID, &b.Status, &b.CancelReason, &cancelledAt, &b.CancelledBy, &b.CreatedAt, &b.UpdatedAt, &b.ClientName, &b.ClientPhone, &b.PaymentType, &channelName)
 		if err != nil {
 			log.Printf("scan booking error: %v", err)
 			return nil, err
@@ -122,6 +140,9 @@ func (r *BookingRepository) GetAll(ctx context.Context, companyID, branchID int)
 		if channelName.Valid {
 			b.ChannelName = channelName.String
 		}
+		if cancelledAt.Valid {
+			b.CancelledAt = &cancelledAt.Time
+		}
 		result = append(result, b)
 	}
 	return result, nil
@@ -129,7 +150,7 @@ func (r *BookingRepository) GetAll(ctx context.Context, companyID, branchID int)
 
 // GetByClientID returns all bookings for a specific client ordered by id DESC.
 func (r *BookingRepository) GetByClientID(ctx context.Context, companyID, branchID, clientID int) ([]models.Booking, error) {
-	query := `SELECT b.id, b.company_id, b.branch_id, b.client_id, table_id, b.user_id, start_time, end_time, note, discount, discount_reason, total_amount, bonus_used, payment_status, payment_type_id, b.created_at, b.updated_at,
+	query := `SELECT b.id, b.company_id, b.branch_id, b.client_id, table_id, b.user_id, start_time, end_time, note, discount, discount_reason, total_amount, bonus_used, payment_status, payment_type_id, b.status, b.cancel_reason, b.cancelled_at, IFNULL(b.cancelled_by, 0), b.created_at, b.updated_at,
                               IFNULL(c.name, ''), IFNULL(c.phone, ''), payment_types.name AS payment_type, IFNULL(channels.name, '') AS channel_name
                               FROM bookings b
                               LEFT JOIN clients c ON b.client_id = c.id
@@ -150,7 +171,8 @@ func (r *BookingRepository) GetByClientID(ctx context.Context, companyID, branch
 		var tableID sql.NullInt64
 		var userID sql.NullInt64
 		var channelName sql.NullString
-		if err := rows.Scan(&b.ID, &b.CompanyID, &b.BranchID, &cID, &tableID, &userID, &b.StartTime, &b.EndTime, &b.Note, &b.Discount, &b.DiscountReason, &b.TotalAmount, &b.BonusUsed, &b.PaymentStatus, &b.PaymentTypeID, &b.CreatedAt, &b.UpdatedAt, &b.ClientName, &b.ClientPhone, &b.PaymentType, &channelName); err != nil {
+		var cancelledAt sql.NullTime
+		if err := rows.Scan(&b.ID, &b.CompanyID, &b.BranchID, &cID, &tableID, &userID, &b.StartTime, &b.EndTime, &b.Note, &b.Discount, &b.DiscountReason, &b.TotalAmount, &b.BonusUsed, &b.PaymentStatus, &b.PaymentTypeID, &b.Status, &b.CancelReason, &cancelledAt, &b.CancelledBy, &b.CreatedAt, &b.UpdatedAt, &b.ClientName, &b.ClientPhone, &b.PaymentType, &channelName); err != nil {
 			log.Printf("scan booking by client error: %v", err)
 			return nil, err
 		}
@@ -166,13 +188,16 @@ func (r *BookingRepository) GetByClientID(ctx context.Context, companyID, branch
 		if channelName.Valid {
 			b.ChannelName = channelName.String
 		}
+		if cancelledAt.Valid {
+			b.CancelledAt = &cancelledAt.Time
+		}
 		result = append(result, b)
 	}
 	return result, nil
 }
 
 func (r *BookingRepository) GetByID(ctx context.Context, companyID, branchID, id int) (*models.Booking, error) {
-	query := `SELECT bookings.id, bookings.company_id, bookings.branch_id, bookings.client_id, table_id, user_id, start_time, end_time, note, discount, discount_reason, total_amount, bonus_used, payment_status, payment_type_id, bookings.created_at, bookings.updated_at,
+	query := `SELECT bookings.id, bookings.company_id, bookings.branch_id, bookings.client_id, table_id, user_id, start_time, end_time, note, discount, discount_reason, IFNULL(promo_code, ''), IFNULL(promotion_id, 0), total_amount, bonus_used, payment_status, payment_type_id, bookings.status, bookings.cancel_reason, bookings.cancelled_at, IFNULL(bookings.cancelled_by, 0), IFNULL(bookings.merged_into, 0), bookings.created_at, bookings.updated_at,
                               payment_types.name AS payment_type, IFNULL(channels.name, '') AS channel_name, IFNULL(c.name, ''), IFNULL(c.phone, '')
                               FROM bookings
                               LEFT JOIN payment_types ON bookings.payment_type_id = payment_types.id
@@ -184,9 +209,10 @@ func (r *BookingRepository) GetByID(ctx context.Context, companyID, branchID, id
 	var tableID sql.NullInt64
 	var channelName sql.NullString
 	var userID sql.NullInt64
+	var cancelledAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, id, companyID, branchID).Scan(
-		&b.ID, &b.CompanyID, &b.BranchID, &clientID, &tableID, &userID, &b.StartTime, &b.EndTime, &b.Note, &b.Discount, &b.DiscountReason,
-		&b.TotalAmount, &b.BonusUsed, &b.PaymentStatus, &b.PaymentTypeID, &b.CreatedAt, &b.UpdatedAt,
+		&b.ID, &b.CompanyID, &b.BranchID, &clientID, &tableID, &userID, &b.StartTime, &b.EndTime, &b.Note, &b.Discount, &b.DiscountReason, &b.PromoCode, &b.PromotionID,
+		&b.TotalAmount, &b.BonusUsed, &b.PaymentStatus, &b.PaymentTypeID, &b.Status, &b.CancelReason, &cancelledAt, &b.CancelledBy, &b.MergedInto, &b.CreatedAt, &b.UpdatedAt,
 		&b.PaymentType, &channelName, &b.ClientName, &b.ClientPhone,
 	)
 	if err != nil {
@@ -205,6 +231,9 @@ func (r *BookingRepository) GetByID(ctx context.Context, companyID, branchID, id
 	if channelName.Valid {
 		b.ChannelName = channelName.String
 	}
+	if cancelledAt.Valid {
+		b.CancelledAt = &cancelledAt.Time
+	}
 	return &b, nil
 }
 
@@ -239,12 +268,15 @@ func (r *BookingRepository) Update(ctx context.Context, companyID, branchID int,
 	return err
 }
 
-// UpdateWithItems updates booking data and replaces its items within a single transaction.
-func (r *BookingRepository) UpdateWithItems(ctx context.Context, companyID, branchID int, b *models.Booking) (err error) {
+// UpdateWithItems updates booking data and its lines within a single
+// transaction. Lines with an id are updated in place, their author and order
+// time stay as stored. Lines without an id are added. Active lines missing
+// from the booking are voided with voidReason, their ids are returned.
+func (r *BookingRepository) UpdateWithItems(ctx context.Context, companyID, branchID int, b *models.Booking, voidedBy int, voidReason string) (removed []int, err error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("begin tx error: %v", err)
-		return err
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -252,7 +284,7 @@ func (r *BookingRepository) UpdateWithItems(ctx context.Context, companyID, bran
 		}
 	}()
 
-	query := `UPDATE bookings SET company_id=?, branch_id=?, client_id=?, table_id=?, user_id=?, start_time=?, end_time=?, note=?, discount=?, discount_reason=?, total_amount=?, bonus_used=?, payment_status=?, payment_type_id=?, updated_at=NOW() WHERE id=? AND company_id=? AND branch_id=?`
+	query := `UPDATE bookings SET company_id=?, branch_id=?, client_id=?, table_id=?, user_id=?, start_time=?, end_time=?, note=?, discount=?, discount_reason=?, promo_code=NULLIF(?,''), promotion_id=NULLIF(?,0), total_amount=?, bonus_used=?, payment_status=?, payment_type_id=?, updated_at=NOW() WHERE id=? AND company_id=? AND branch_id=?`
 
 	var clientID interface{}
 	if b.ClientID > 0 {
@@ -275,32 +307,70 @@ func (r *BookingRepository) UpdateWithItems(ctx context.Context, companyID, bran
 		userID = nil
 	}
 
-	_, err = tx.ExecContext(ctx, query, companyID, branchID, clientID, tableID, userID, b.StartTime, b.EndTime, b.Note, b.Discount, b.DiscountReason, b.TotalAmount, b.BonusUsed, b.PaymentStatus, b.PaymentTypeID, b.ID, companyID, branchID)
+	_, err = tx.ExecContext(ctx, query, companyID, branchID, clientID, tableID, userID, b.StartTime, b.EndTime, b.Note, b.Discount, b.DiscountReason, b.PromoCode, b.PromotionID, b.TotalAmount, b.BonusUsed, b.PaymentStatus, b.PaymentTypeID, b.ID, companyID, branchID)
 	if err != nil {
 		log.Printf("update booking error: %v", err)
-		return err
+		return nil, err
 	}
 
-	if _, err = tx.ExecContext(ctx, `DELETE FROM booking_items WHERE booking_id=? AND company_id=? AND branch_id=?`, b.ID, companyID, branchID); err != nil {
-		log.Printf("delete booking items error: %v", err)
-		return err
+	// отмененные строки остаются в истории счета и не меняются
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM booking_items WHERE booking_id=? AND company_id=? AND branch_id=? AND status=? FOR UPDATE`,
+		b.ID, companyID, branchID, models.BookingItemActive)
+	if err != nil {
+		log.Printf("lock booking items error: %v", err)
+		return nil, err
+	}
+	active := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		active[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
-	if len(b.Items) > 0 {
-		itemQuery := `INSERT INTO booking_items (booking_id, company_id, branch_id, item_id, quantity, price, discount) VALUES (?, ?, ?, ?, ?, ?, ?)`
-		for _, it := range b.Items {
-			if _, err = tx.ExecContext(ctx, itemQuery, b.ID, companyID, branchID, it.ItemID, it.Quantity, it.Price, it.Discount); err != nil {
-				log.Printf("insert booking item error: %v", err)
-				return err
+	updateQuery := `UPDATE booking_items SET item_id=?, quantity=?, price=?, discount=?, promotion_id=NULLIF(?,0) WHERE id=?`
+	insertQuery := `INSERT INTO booking_items (booking_id, company_id, branch_id, item_id, quantity, price, discount, promotion_id, user_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?,0), NULLIF(?,0), NOW())`
+	for i, it := range b.Items {
+		if it.ID > 0 && active[it.ID] {
+			if _, err = tx.ExecContext(ctx, updateQuery, it.ItemID, it.Quantity, it.Price, it.Discount, it.PromotionID, it.ID); err != nil {
+				log.Printf("update booking item error: %v", err)
+				return nil, err
 			}
+			delete(active, it.ID)
+			continue
 		}
+		var res sql.Result
+		if res, err = tx.ExecContext(ctx, insertQuery, b.ID, companyID, branchID, it.ItemID, it.Quantity, it.Price, it.Discount, it.PromotionID, it.UserID); err != nil {
+			log.Printf("insert booking item error: %v", err)
+			return nil, err
+		}
+		lineID, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		b.Items[i].ID = int(lineID)
+	}
+	for id := range active {
+		if _, err = tx.ExecContext(ctx, `UPDATE booking_items SET status=?, void_reason=?, voided_at=NOW(), voided_by=NULLIF(?,0) WHERE id=?`,
+			models.BookingItemVoided, voidReason, voidedBy, id); err != nil {
+			log.Printf("void booking item error: %v", err)
+			return nil, err
+		}
+		removed = append(removed, id)
 	}
 
 	err = tx.Commit()
 	if err != nil {
 		log.Printf("commit booking update error: %v", err)
+		return nil, err
 	}
-	return err
+	return removed, nil
 }
 
 func (r *BookingRepository) Delete(ctx context.Context, companyID, branchID, id int) error {
@@ -311,11 +381,81 @@ func (r *BookingRepository) Delete(ctx context.Context, companyID, branchID, id
 	return err
 }
 
+// notCounted excludes bookings that bring no revenue to reports: cancelled
+// ones and bookings merged into another bill.
+func notCounted(alias string) string {
+	col := "status"
+	if alias != "" {
+		col = alias + ".status"
+	}
+	return " AND " + col + " NOT IN (" + cancelledStatuses + ",'merged')"
+}
+
+// openStatuses are statuses of bookings that can still be changed.
+const openStatuses = "'active','pending','reserved','seated'"
+
+// Cancel marks booking as cancelled with the given final status. Only open
+// bookings can be cancelled, false is returned if nothing was changed.
+func (r *BookingRepository) Cancel(ctx context.Context, companyID, branchID, id int, status, reason string, userID int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE bookings SET status=?, cancel_reason=?, cancelled_at=NOW(), cancelled_by=NULLIF(?,0), updated_at=NOW()
+        WHERE id=? AND company_id=? AND branch_id=? AND status IN (`+openStatuses+`)`, status, reason, userID, id, companyID, branchID)
+	if err != nil {
+		log.Printf("cancel booking error: %v", err)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SetStatus moves booking from one status to another. False is returned if
+// the booking is not in the expected status anymore.
+func (r *BookingRepository) SetStatus(ctx context.Context, companyID, branchID, id int, from, to string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE bookings SET status=?, updated_at=NOW() WHERE id=? AND company_id=? AND branch_id=? AND status=?`,
+		to, id, companyID, branchID, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetByPeriod returns bookings occupying tables within the period. Only
+// table, time and status fields are loaded.
+func (r *BookingRepository) GetByPeriod(ctx context.Context, companyID, branchID int, from, to time.Time) ([]models.Booking, error) {
+	query := `SELECT id, table_id, start_time, end_time, status FROM bookings
+        WHERE company_id=? AND branch_id=? AND table_id IS NOT NULL AND ? < end_time AND ? > start_time` + notCancelled("") + `
+        ORDER BY table_id, start_time`
+	rows, err := r.db.QueryContext(ctx, query, companyID, branchID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.Booking
+	for rows.Next() {
+		var b models.Booking
+		if err := rows.Scan(&b.ID, &b.TableID, &b.StartTime, &b.EndTime, &b.Status); err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+// IsTableAvailable reports whether the table is free from start to end.
+// Bookings are extended by the cleanup buffer of the branch on both sides.
 func (r *BookingRepository) IsTableAvailable(ctx context.Context, companyID, branchID, tableID, excludeID int, start, end time.Time) (bool, error) {
-	query := `SELECT COUNT(1) FROM bookings WHERE company_id=? AND branch_id=? AND table_id=? AND ? < end_time AND ? > start_time`
-	args := []interface{}{companyID, branchID, tableID, start, end}
+	// бронь с историей столов занимает столы по своим отрезкам
+	query := `SELECT COUNT(1) FROM bookings b
+              CROSS JOIN (SELECT IFNULL(MAX(cleanup_buffer_minutes), 0) AS m FROM settings WHERE company_id=? AND branch_id=?) buf
+              WHERE b.company_id=? AND b.branch_id=?
+                AND ((NOT EXISTS (SELECT 1 FROM booking_table_segments s WHERE s.booking_id=b.id)
+                      AND b.table_id=? AND ? < b.end_time + INTERVAL buf.m MINUTE AND ? > b.start_time - INTERVAL buf.m MINUTE)
+                  OR EXISTS (SELECT 1 FROM booking_table_segments s
+                             WHERE s.booking_id=b.id AND s.table_id=?
+                               AND ? < s.end_time + INTERVAL buf.m MINUTE AND ? > s.start_time - INTERVAL buf.m MINUTE))` + notCancelled("b")
+	args := []interface{}{companyID, branchID, companyID, branchID, tableID, start, end, tableID, start, end}
 	if excludeID > 0 {
-		query += " AND id <> ?"
+		query += " AND b.id <> ?"
 		args = append(args, excludeID)
 	}
 	var cnt int
@@ -326,3 +466,54 @@ func (r *BookingRepository) IsTableAvailable(ctx context.Context, companyID, bra
 	}
 	return cnt == 0, nil
 }
+
+// Merge moves items, payments and table history of source booking to target
+// within a single transaction. Source is closed with status merged and keeps
+// its table occupied until its end time.
+func (r *BookingRepository) Merge(ctx context.Context, companyID, branchID, targetID, sourceID int) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var total, bonus int
+	err = tx.QueryRowContext(ctx, `SELECT total_amount, bonus_used FROM bookings WHERE id=? AND company_id=? AND branch_id=? AND status IN (`+openStatuses+`) FOR UPDATE`,
+		sourceID, companyID, branchID).Scan(&total, &bonus)
+	if err != nil {
+		log.Printf("lock merged booking error: %v", err)
+		return err
+	}
+	for _, table := range []string{"booking_items", "booking_payments", "booking_table_segments", "pass_redemptions", "booking_promotions", "order_tickets"} {
+		if _, err = tx.ExecContext(ctx, `UPDATE `+table+` SET booking_id=? WHERE booking_id=? AND company_id=? AND branch_id=?`, targetID, sourceID, companyID, branchID); err != nil {
+			log.Printf("merge %s error: %v", table, err)
+			return err
+		}
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE bookings SET total_amount=total_amount+?, bonus_used=bonus_used+?, updated_at=NOW()
+        WHERE id=? AND company_id=? AND branch_id=? AND status IN (`+openStatuses+`)`, total, bonus, targetID, companyID, branchID)
+	if err != nil {
+		log.Printf("merge target booking error: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = sql.ErrNoRows
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE bookings SET status=?, merged_into=?, total_amount=0, bonus_used=0, updated_at=NOW()
+        WHERE id=? AND company_id=? AND branch_id=?`, models.BookingStatusMerged, targetID, sourceID, companyID, branchID); err != nil {
+		log.Printf("close merged booking error: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit booking merge error: %v", err)
+	}
+	return err
+}
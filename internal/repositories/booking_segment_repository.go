@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+// BookingSegmentRepository stores which table a booking used and when.
+type BookingSegmentRepository struct {
+	db *sql.DB
+}
+
+func NewBookingSegmentRepository(db *sql.DB) *BookingSegmentRepository {
+	return &BookingSegmentRepository{db: db}
+}
+
+// Create adds a table period to a booking.
+func (r *BookingSegmentRepository) Create(ctx context.Context, companyID, branchID int, s *models.BookingTableSegment) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO booking_table_segments (booking_id, company_id, branch_id, table_id, start_time, end_time, created_at) VALUES (?, ?, ?, ?, ?, ?, NOW())`,
+		s.BookingID, companyID, branchID, s.TableID, s.StartTime, s.EndTime)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// GetByBookingID returns table history of a booking in time order.
+func (r *BookingSegmentRepository) GetByBookingID(ctx context.Context, companyID, branchID, bookingID int) ([]models.BookingTableSegment, error) {
+	query := `SELECT s.id, s.booking_id, s.table_id, IFNULL(t.name, ''), s.start_time, s.end_time
+             FROM booking_table_segments s
+             LEFT JOIN tables t ON s.table_id = t.id
+             WHERE s.booking_id = ? AND s.company_id = ? AND s.branch_id = ?
+             ORDER BY s.start_time, s.id`
+	rows, err := r.db.QueryContext(ctx, query, bookingID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var segments []models.BookingTableSegment
+	for rows.Next() {
+		var s models.BookingTableSegment
+		if err := rows.Scan(&s.ID, &s.BookingID, &s.TableID, &s.TableName, &s.StartTime, &s.EndTime); err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, rows.Err()
+}
+
+// CloseLast ends the current table period of a booking at the given time.
+// A period left without duration is removed.
+func (r *BookingSegmentRepository) CloseLast(ctx context.Context, companyID, branchID, bookingID int, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE booking_table_segments SET end_time=?
+        WHERE booking_id=? AND company_id=? AND branch_id=? ORDER BY start_time DESC, id DESC LIMIT 1`, at, bookingID, companyID, branchID)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `DELETE FROM booking_table_segments WHERE booking_id=? AND company_id=? AND branch_id=? AND end_time <= start_time`,
+		bookingID, companyID, branchID)
+	return err
+}
+
+// SyncBounds follows booking changes: the first period starts with the
+// booking, the last one ends with it and is spent at the booking table.
+func (r *BookingSegmentRepository) SyncBounds(ctx context.Context, companyID, branchID int, b *models.Booking) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE booking_table_segments SET start_time=?
+        WHERE booking_id=? AND company_id=? AND branch_id=? ORDER BY start_time, id LIMIT 1`, b.StartTime, b.ID, companyID, branchID)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `UPDATE booking_table_segments SET end_time=?, table_id=?
+        WHERE booking_id=? AND company_id=? AND branch_id=? ORDER BY start_time DESC, id DESC LIMIT 1`, b.EndTime, b.TableID, b.ID, companyID, branchID)
+	return err
+}
@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"psclub-crm/internal/models"
+)
+
+// BookingShareRepository stores parts of split booking bills.
+type BookingShareRepository struct {
+	db *sql.DB
+}
+
+func NewBookingShareRepository(db *sql.DB) *BookingShareRepository {
+	return &BookingShareRepository{db: db}
+}
+
+// Replace stores a new split of the booking bill within a single
+// transaction. Previous shares and all regular payments are removed, the
+// payments of every share are inserted with a link to it. Booking bonus and
+// payment type follow the shares.
+func (r *BookingShareRepository) Replace(ctx context.Context, companyID, branchID int, b *models.Booking) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM booking_shares WHERE booking_id=? AND company_id=? AND branch_id=?`, b.ID, companyID, branchID); err != nil {
+		log.Printf("delete booking shares error: %v", err)
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM booking_payments WHERE booking_id=? AND company_id=? AND branch_id=? AND is_deposit = 0 AND method = ''`, b.ID, companyID, branchID); err != nil {
+		log.Printf("delete booking payments error: %v", err)
+		return err
+	}
+
+	payQuery := `INSERT INTO booking_payments (booking_id, company_id, branch_id, payment_type_id, amount, is_deposit, share_id, created_at) VALUES (?, ?, ?, ?, ?, 0, ?, NOW())`
+	itemQuery := `INSERT INTO booking_share_items (share_id, booking_item_id, quantity, amount) VALUES (?, ?, ?, ?)`
+	for i := range b.Shares {
+		sh := &b.Shares[i]
+		res, err := tx.ExecContext(ctx, `INSERT INTO booking_shares (booking_id, company_id, branch_id, client_id, amount, bonus_used, created_at) VALUES (?, ?, ?, NULLIF(?,0), ?, ?, NOW())`,
+			b.ID, companyID, branchID, sh.ClientID, sh.Amount, sh.BonusUsed)
+		if err != nil {
+			log.Printf("insert booking share error: %v", err)
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		sh.ID = int(id)
+		sh.BookingID = b.ID
+		for j := range sh.Items {
+			sh.Items[j].ShareID = sh.ID
+			if _, err := tx.ExecContext(ctx, itemQuery, sh.ID, sh.Items[j].BookingItemID, sh.Items[j].Quantity, sh.Items[j].Amount); err != nil {
+				log.Printf("insert booking share item error: %v", err)
+				return err
+			}
+		}
+		for j := range sh.Payments {
+			p := &sh.Payments[j]
+			p.BookingID = b.ID
+			p.ShareID = sh.ID
+			if _, err := tx.ExecContext(ctx, payQuery, b.ID, companyID, branchID, p.PaymentTypeID, p.Amount, sh.ID); err != nil {
+				log.Printf("insert share payment error: %v", err)
+				return err
+			}
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE bookings SET bonus_used=?, payment_type_id=?, updated_at=NOW() WHERE id=? AND company_id=? AND branch_id=?`,
+		b.BonusUsed, b.PaymentTypeID, b.ID, companyID, branchID); err != nil {
+		log.Printf("update split booking error: %v", err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit booking split error: %v", err)
+	}
+	return err
+}
+
+// Remove drops the split of a booking. Payments of the shares stay with the
+// booking as regular payments, bonus redemptions are reset.
+func (r *BookingShareRepository) Remove(ctx context.Context, companyID, branchID, bookingID int) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.ExecContext(ctx, `UPDATE booking_payments SET share_id=NULL WHERE booking_id=? AND company_id=? AND branch_id=?`, bookingID, companyID, branchID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM booking_shares WHERE booking_id=? AND company_id=? AND branch_id=?`, bookingID, companyID, branchID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE bookings SET bonus_used=0, updated_at=NOW() WHERE id=? AND company_id=? AND branch_id=?`, bookingID, companyID, branchID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByBookingID returns shares of a booking with their items. Payments are
+// attached by the caller.
+func (r *BookingShareRepository) GetByBookingID(ctx context.Context, companyID, branchID, bookingID int) ([]models.BookingShare, error) {
+	query := `SELECT s.id, s.booking_id, IFNULL(s.client_id, 0), IFNULL(c.name, ''), s.amount, s.bonus_used
+             FROM booking_shares s
+             LEFT JOIN clients c ON s.client_id = c.id
+             WHERE s.booking_id = ? AND s.company_id = ? AND s.branch_id = ?
+             ORDER BY s.id`
+	rows, err := r.db.QueryContext(ctx, query, bookingID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var shares []models.BookingShare
+	index := make(map[int]int)
+	for rows.Next() {
+		var sh models.BookingShare
+		if err := rows.Scan(&sh.ID, &sh.BookingID, &sh.ClientID, &sh.ClientName, &sh.Amount, &sh.BonusUsed); err != nil {
+			return nil, err
+		}
+		index[sh.ID] = len(shares)
+		shares = append(shares, sh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, nil
+	}
+
+	itemRows, err := r.db.QueryContext(ctx, `SELECT si.id, si.share_id, si.booking_item_id, si.quantity, si.amount
+             FROM booking_share_items si
+             JOIN booking_shares s ON si.share_id = s.id
+             WHERE s.booking_id = ? AND s.company_id = ? AND s.branch_id = ?
+             ORDER BY si.id`, bookingID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+	for itemRows.Next() {
+		var it models.BookingShareItem
+		if err := itemRows.Scan(&it.ID, &it.ShareID, &it.BookingItemID, &it.Quantity, &it.Amount); err != nil {
+			return nil, err
+		}
+		if i, ok := index[it.ShareID]; ok {
+			shares[i].Items = append(shares[i].Items, it)
+		}
+	}
+	return shares, itemRows.Err()
+}
@@ -15,9 +15,9 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 }
 
 func (r *CategoryRepository) GetByName(ctx context.Context, name string, companyID, branchID int) (*models.Category, error) {
-	query := `SELECT id, name, company_id, branch_id FROM categories WHERE name = ? AND company_id = ? AND branch_id = ?`
+	query := `SELECT id, name, company_id, branch_id, is_prepared, IFNULL(station_id, 0) FROM categories WHERE name = ? AND company_id = ? AND branch_id = ?`
 	var c models.Category
-	err := r.db.QueryRowContext(ctx, query, name, companyID, branchID).Scan(&c.ID, &c.Name, &c.CompanyID, &c.BranchID)
+	err := r.db.QueryRowContext(ctx, query, name, companyID, branchID).Scan(&c.ID, &c.Name, &c.CompanyID, &c.BranchID, &c.IsPrepared, &c.StationID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -28,8 +28,8 @@ func (r *CategoryRepository) GetByName(ctx context.Context, name string, company
 }
 
 func (r *CategoryRepository) Create(ctx context.Context, c *models.Category) (int, error) {
-	query := `INSERT INTO categories (name, company_id, branch_id) VALUES (?, ?, ?)`
-	res, err := r.db.ExecContext(ctx, query, c.Name, c.CompanyID, c.BranchID)
+	query := `INSERT INTO categories (name, company_id, branch_id, is_prepared, station_id) VALUES (?, ?, ?, ?, NULLIF(?,0))`
+	res, err := r.db.ExecContext(ctx, query, c.Name, c.CompanyID, c.BranchID, c.IsPrepared, c.StationID)
 	if err != nil {
 		return 0, err
 	}
@@ -38,7 +38,7 @@ func (r *CategoryRepository) Create(ctx context.Context, c *models.Category) (in
 }
 
 func (r *CategoryRepository) GetAll(ctx context.Context, companyID, branchID int) ([]models.Category, error) {
-	query := `SELECT id, name, company_id, branch_id FROM categories WHERE company_id=? AND branch_id=? ORDER BY id`
+	query := `SELECT id, name, company_id, branch_id, is_prepared, IFNULL(station_id, 0) FROM categories WHERE company_id=? AND branch_id=? ORDER BY id`
 	rows, err := r.db.QueryContext(ctx, query, companyID, branchID)
 	if err != nil {
 		return nil, err
@@ -47,7 +47,7 @@ func (r *CategoryRepository) GetAll(ctx context.Context, companyID, branchID int
 	var result []models.Category
 	for rows.Next() {
 		var c models.Category
-		err := rows.Scan(&c.ID, &c.Name, &c.CompanyID, &c.BranchID)
+		err := rows.Scan(&c.ID, &c.Name, &c.CompanyID, &c.BranchID, &c.IsPrepared, &c.StationID)
 		if err != nil {
 			return nil, err
 		}
@@ -57,9 +57,9 @@ func (r *CategoryRepository) GetAll(ctx context.Context, companyID, branchID int
 }
 
 func (r *CategoryRepository) GetByID(ctx context.Context, id int) (*models.Category, error) {
-	query := `SELECT id, name FROM categories WHERE id=?`
+	query := `SELECT id, name, company_id, branch_id, is_prepared, IFNULL(station_id, 0) FROM categories WHERE id=?`
 	var c models.Category
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Name)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Name, &c.CompanyID, &c.BranchID, &c.IsPrepared, &c.StationID)
 	if err != nil {
 		return nil, err
 	}
@@ -67,9 +67,9 @@ func (r *CategoryRepository) GetByID(ctx context.Context, id int) (*models.Categ
 }
 
 func (r *CategoryRepository) GetByIDTenant(ctx context.Context, id, companyID, branchID int) (*models.Category, error) {
-	query := `SELECT id, name, company_id, branch_id FROM categories WHERE id=? AND company_id=? AND branch_id=?`
+	query := `SELECT id, name, company_id, branch_id, is_prepared, IFNULL(station_id, 0) FROM categories WHERE id=? AND company_id=? AND branch_id=?`
 	var c models.Category
-	err := r.db.QueryRowContext(ctx, query, id, companyID, branchID).Scan(&c.ID, &c.Name, &c.CompanyID, &c.BranchID)
+	err := r.db.QueryRowContext(ctx, query, id, companyID, branchID).Scan(&c.ID, &c.Name, &c.CompanyID, &c.BranchID, &c.IsPrepared, &c.StationID)
 	if err != nil {
 		return nil, err
 	}
@@ -77,8 +77,8 @@ func (r *CategoryRepository) GetByIDTenant(ctx context.Context, id, companyID, b
 }
 
 func (r *CategoryRepository) Update(ctx context.Context, c *models.Category) error {
-	query := `UPDATE categories SET name=? WHERE id=? AND company_id=? AND branch_id=?`
-	_, err := r.db.ExecContext(ctx, query, c.Name, c.ID, c.CompanyID, c.BranchID)
+	query := `UPDATE categories SET name=?, is_prepared=?, station_id=NULLIF(?,0) WHERE id=? AND company_id=? AND branch_id=?`
+	_, err := r.db.ExecContext(ctx, query, c.Name, c.IsPrepared, c.StationID, c.ID, c.CompanyID, c.BranchID)
 	return err
 }
 
@@ -20,7 +20,7 @@ func (r *ClientRepository) Create(ctx context.Context, c *models.Client) (int, e
 	branchID := ctx.Value(common.CtxBranchID).(int)
 	query := `
         INSERT INTO clients (company_id, branch_id, name, phone, date_of_birth, channel_id, bonus, visits, income, status, created_at, updated_at)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`
+        VALUES (?, ?, ?, ?, ?, NULLIF(?, 0), ?, ?, ?, ?, NOW(), NOW())`
 	res, err := r.db.ExecContext(ctx, query, companyID, branchID, c.Name, c.Phone, c.DateOfBirth, c.ChannelID, c.Bonus, c.Visits, c.Income, c.Status)
 	if err != nil {
 		return 0, err
@@ -32,7 +32,7 @@ func (r *ClientRepository) Create(ctx context.Context, c *models.Client) (int, e
 func (r *ClientRepository) GetAll(ctx context.Context) ([]models.Client, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
-	query := `SELECT c.id, c.company_id, c.branch_id, c.name, c.phone, c.date_of_birth, c.channel_id, IFNULL(ch.name, ''), c.bonus, c.visits, c.income, c.status, c.created_at, c.updated_at
+	query := `SELECT c.id, c.company_id, c.branch_id, c.name, c.phone, c.date_of_birth, c.channel_id, IFNULL(ch.name, ''), c.bonus, c.visits, c.income, c.wallet_balance, c.status, c.created_at, c.updated_at
                 FROM clients c
                 LEFT JOIN channels ch ON c.channel_id = ch.id
                 WHERE c.status <> 'deleted' AND c.company_id = ? AND c.branch_id = ?
@@ -46,7 +46,7 @@ func (r *ClientRepository) GetAll(ctx context.Context) ([]models.Client, error)
 	for rows.Next() {
 		var c models.Client
 		var dob sql.NullTime
-		err := rows.Scan(&c.ID, &c.CompanyID, &c.BranchID, &c.Name, &c.Phone, &dob, &c.ChannelID, &c.Channel, &c.Bonus, &c.Visits, &c.Income, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+		err := rows.Scan(&c.ID, &c.CompanyID, &c.BranchID, &c.Name, &c.Phone, &dob, &c.ChannelID, &c.Channel, &c.Bonus, &c.Visits, &c.Income, &c.WalletBalance, &c.Status, &c.CreatedAt, &c.UpdatedAt)
 		if dob.Valid {
 			c.DateOfBirth = &dob.Time
 		}
@@ -61,13 +61,13 @@ func (r *ClientRepository) GetAll(ctx context.Context) ([]models.Client, error)
 func (r *ClientRepository) GetByID(ctx context.Context, id int) (*models.Client, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
-	query := `SELECT c.id, c.company_id, c.branch_id, c.name, c.phone, c.date_of_birth, c.channel_id, IFNULL(ch.name, ''), c.bonus, c.visits, c.income, c.status, c.created_at, c.updated_at
+	query := `SELECT c.id, c.company_id, c.branch_id, c.name, c.phone, c.date_of_birth, c.channel_id, IFNULL(ch.name, ''), c.bonus, c.visits, c.income, c.wallet_balance, c.status, c.created_at, c.updated_at
                 FROM clients c
                 LEFT JOIN channels ch ON c.channel_id = ch.id
                 WHERE c.id=? AND c.company_id = ? AND c.branch_id = ?`
 	var c models.Client
 	var dob sql.NullTime
-	err := r.db.QueryRowContext(ctx, query, id, companyID, branchID).Scan(&c.ID, &c.CompanyID, &c.BranchID, &c.Name, &c.Phone, &dob, &c.ChannelID, &c.Channel, &c.Bonus, &c.Visits, &c.Income, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, id, companyID, branchID).Scan(&c.ID, &c.CompanyID, &c.BranchID, &c.Name, &c.Phone, &dob, &c.ChannelID, &c.Channel, &c.Bonus, &c.Visits, &c.Income, &c.WalletBalance, &c.Status, &c.CreatedAt, &c.UpdatedAt)
 	if dob.Valid {
 		c.DateOfBirth = &dob.Time
 	}
@@ -123,16 +123,28 @@ func (r *ClientRepository) AddBonus(ctx context.Context, clientID int, bonus int
 	return err
 }
 
+// phoneDigits strips the usual phone formatting (spaces, dashes, dots and
+// parentheses) from a SQL expression, so that "+7 (701) 123-45-67" and
+// "+77011234567" are the same client.
+func phoneDigits(expr string) string {
+	for _, ch := range []string{" ", "-", "(", ")", "."} {
+		expr = "REPLACE(" + expr + ", '" + ch + "', '')"
+	}
+	return expr
+}
+
+// GetByPhone finds a client of the branch by phone ignoring its formatting.
 func (r *ClientRepository) GetByPhone(ctx context.Context, phone string) (*models.Client, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
-	query := `SELECT c.id, c.company_id, c.branch_id, c.name, c.phone, c.date_of_birth, c.channel_id, IFNULL(ch.name, ''), c.bonus, c.visits, c.income, c.status, c.created_at, c.updated_at
+	query := `SELECT c.id, c.company_id, c.branch_id, c.name, c.phone, c.date_of_birth, c.channel_id, IFNULL(ch.name, ''), c.bonus, c.visits, c.income, c.wallet_balance, c.status, c.created_at, c.updated_at
                 FROM clients c
                 LEFT JOIN channels ch ON c.channel_id = ch.id
-                WHERE c.phone = ? AND c.company_id = ? AND c.branch_id = ?`
+                WHERE ` + phoneDigits("c.phone") + ` = ` + phoneDigits("?") + ` AND c.company_id = ? AND c.branch_id = ?
+                ORDER BY c.id LIMIT 1`
 	var c models.Client
 	var dob sql.NullTime
-	err := r.db.QueryRowContext(ctx, query, phone, companyID, branchID).Scan(&c.ID, &c.CompanyID, &c.BranchID, &c.Name, &c.Phone, &dob, &c.ChannelID, &c.Channel, &c.Bonus, &c.Visits, &c.Income, &c.Status, &c.CreatedAt, &c.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, phone, companyID, branchID).Scan(&c.ID, &c.CompanyID, &c.BranchID, &c.Name, &c.Phone, &dob, &c.ChannelID, &c.Channel, &c.Bonus, &c.Visits, &c.Income, &c.WalletBalance, &c.Status, &c.CreatedAt, &c.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -3,6 +3,11 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"psclub-crm/internal/models"
 )
 
 type CompanyRepository struct {
@@ -13,20 +18,460 @@ func NewCompanyRepository(db *sql.DB) *CompanyRepository {
 	return &CompanyRepository{db: db}
 }
 
-func (r *CompanyRepository) CreateCompany(ctx context.Context, name string) (int, error) {
-	res, err := r.db.ExecContext(ctx, `INSERT INTO companies (name) VALUES (?)`, name)
+// CreateCompany creates a company with its first branch set up from the
+// template. When director is given, the first user of the company is
+// created too. Everything is done in one transaction.
+func (r *CompanyRepository) CreateCompany(ctx context.Context, name, branchName string, t *models.SeedTemplate, director *models.User) (int, int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `INSERT INTO companies (name) VALUES (?)`, name)
+	if err != nil {
+		return 0, 0, err
 	}
 	id, err := res.LastInsertId()
-	return int(id), err
+	if err != nil {
+		return 0, 0, err
+	}
+	companyID := int(id)
+	branchID, err := createBranch(ctx, tx, companyID, branchName)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := seedBranch(ctx, tx, companyID, branchID, t); err != nil {
+		return 0, 0, err
+	}
+	if director != nil {
+		director.CompanyID = companyID
+		director.BranchID = branchID
+		if director.ID, err = insertUser(ctx, tx, director); err != nil {
+			return 0, 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_branches (user_id, company_id, branch_id, role) VALUES (?, ?, ?, ?)`,
+			director.ID, companyID, branchID, director.Role); err != nil {
+			return 0, 0, err
+		}
+	}
+	return companyID, branchID, tx.Commit()
 }
 
-func (r *CompanyRepository) CreateBranch(ctx context.Context, companyID int, name string) (int, error) {
-	res, err := r.db.ExecContext(ctx, `INSERT INTO branches (company_id, name) VALUES (?, ?)`, companyID, name)
+// CreateBranch creates a branch with its cashbox, set up from the template.
+func (r *CompanyRepository) CreateBranch(ctx context.Context, companyID int, name string, t *models.SeedTemplate) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	id, err := createBranch(ctx, tx, companyID, name)
+	if err != nil {
+		return 0, err
+	}
+	if err := seedBranch(ctx, tx, companyID, id, t); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// createBranch adds a branch with an empty cashbox and the default channel.
+func createBranch(ctx context.Context, tx *sql.Tx, companyID int, name string) (int, error) {
+	res, err := tx.ExecContext(ctx, `INSERT INTO branches (company_id, name) VALUES (?, ?)`, companyID, name)
 	if err != nil {
 		return 0, err
 	}
 	id, err := res.LastInsertId()
-	return int(id), err
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO cashbox (amount, company_id, branch_id) VALUES (0, ?, ?)`, companyID, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO channels (name, company_id, branch_id) VALUES (?, ?, ?)`, "- не указано", companyID, id); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// seedBranch fills a new branch from the template: halls with tables,
+// categories with their preparation stations, payment types, expense
+// categories, settings and sample price items and sets.
+func seedBranch(ctx context.Context, tx *sql.Tx, companyID, branchID int, t *models.SeedTemplate) error {
+	insert := func(query string, args ...interface{}) (int64, error) {
+		res, err := tx.ExecContext(ctx, query, append(args, companyID, branchID)...)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+
+	tablesCount := 0
+	for _, tc := range t.TableCategories {
+		tableCatID, err := insert(`INSERT INTO table_categories (name, company_id, branch_id) VALUES (?, ?, ?)`, tc.Name)
+		if err != nil {
+			return err
+		}
+		prefix := tc.TablePrefix
+		if prefix == "" {
+			prefix = "Стол"
+		}
+		for i := 1; i <= tc.Tables; i++ {
+			tablesCount++
+			if _, err := insert(`INSERT INTO tables (category_id, name, number, company_id, branch_id) VALUES (?, ?, 0, ?, ?)`,
+				tableCatID, fmt.Sprintf("%s %d", prefix, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	stations := make(map[string]int64)
+	cats := make(map[string]int64)
+	subs := make(map[string]int64)
+	for _, c := range t.Categories {
+		var stationID interface{}
+		if c.Station != "" {
+			if _, ok := stations[c.Station]; !ok {
+				id, err := insert(`INSERT INTO stations (name, company_id, branch_id) VALUES (?, ?, ?)`, c.Station)
+				if err != nil {
+					return err
+				}
+				stations[c.Station] = id
+			}
+			stationID = stations[c.Station]
+		}
+		id, err := insert(`INSERT INTO categories (name, is_prepared, station_id, company_id, branch_id) VALUES (?, ?, ?, ?, ?)`,
+			c.Name, c.Station != "", stationID)
+		if err != nil {
+			return err
+		}
+		cats[c.Name] = id
+		for _, sc := range c.Subcategories {
+			subID, err := insert(`INSERT INTO subcategories (category_id, name, company_id, branch_id) VALUES (?, ?, ?, ?)`, id, sc)
+			if err != nil {
+				return err
+			}
+			subs[c.Name+"/"+sc] = subID
+		}
+	}
+	subcategory := func(category, name string) interface{} {
+		if id, ok := subs[category+"/"+name]; ok {
+			return id
+		}
+		return nil
+	}
+
+	items := make(map[string]int64)
+	for _, it := range t.PriceItems {
+		id, err := insert(`INSERT INTO price_items (name, category_id, subcategory_id, sale_price, buy_price, is_set, company_id, branch_id)
+            VALUES (?, ?, ?, ?, ?, 0, ?, ?)`, it.Name, cats[it.Category], subcategory(it.Category, it.Subcategory), it.SalePrice, it.BuyPrice)
+		if err != nil {
+			return err
+		}
+		items[it.Name] = id
+	}
+	// набор хранится под тем же id, что и его позиция в прайсе
+	for _, set := range t.Sets {
+		id, err := insert(`INSERT INTO price_items (name, category_id, subcategory_id, sale_price, is_set, company_id, branch_id)
+            VALUES (?, ?, ?, ?, 1, ?, ?)`, set.Name, cats[set.Category], subcategory(set.Category, set.Subcategory), set.Price)
+		if err != nil {
+			return err
+		}
+		if _, err := insert(`INSERT INTO price_sets (id, name, category_id, subcategory_id, price, company_id, branch_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, set.Name, cats[set.Category], subcategory(set.Category, set.Subcategory), set.Price); err != nil {
+			return err
+		}
+		for _, si := range set.Items {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO set_items (price_set_id, item_id, quantity) VALUES (?, ?, ?)`,
+				id, items[si.Item], si.Quantity); err != nil {
+				return err
+			}
+		}
+	}
+
+	var firstPTID int64
+	for i, p := range t.PaymentTypes {
+		id, err := insert(`INSERT INTO payment_types (name, hold_percent, company_id, branch_id) VALUES (?, ?, ?, ?)`, p.Name, p.HoldPercent)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			firstPTID = id
+		}
+	}
+
+	for _, e := range t.ExpenseCategories {
+		if _, err := insert(`INSERT INTO expense_categories (name, company_id, branch_id) VALUES (?, ?, ?)`, e); err != nil {
+			return err
+		}
+	}
+
+	// правила депозитов, ожидания брони и уборки берут значения по умолчанию из схемы
+	st := t.Settings
+	_, err := insert(`
+        INSERT INTO settings (payment_type, block_time, bonus_percent, work_time_from, work_time_to, tables_count, notification_time, company_id, branch_id)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		firstPTID, st.BlockTime, st.BonusPercent, st.WorkTimeFrom, st.WorkTimeTo, tablesCount, st.NotificationTime)
+	return err
+}
+
+func (r *CompanyRepository) GetBranch(ctx context.Context, id int) (*models.Branch, error) {
+	var b models.Branch
+	err := r.db.QueryRowContext(ctx, `SELECT id, company_id, name FROM branches WHERE id=?`, id).Scan(&b.ID, &b.CompanyID, &b.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *CompanyRepository) GetBranches(ctx context.Context, companyID int) ([]models.Branch, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, company_id, name FROM branches WHERE company_id=? ORDER BY id`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.Branch
+	for rows.Next() {
+		var b models.Branch
+		if err := rows.Scan(&b.ID, &b.CompanyID, &b.Name); err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
+
+func (r *CompanyRepository) UpdateBranch(ctx context.Context, b *models.Branch) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE branches SET name=? WHERE id=? AND company_id=?`, b.Name, b.ID, b.CompanyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists int
+		if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM branches WHERE id=? AND company_id=?`, b.ID, b.CompanyID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return sql.ErrNoRows
+		}
+	}
+	return nil
+}
+
+// BranchInUse tells whether the branch already has bookings, expenses or
+// staff.
+func (r *CompanyRepository) BranchInUse(ctx context.Context, companyID, branchID int) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+        SELECT (SELECT COUNT(*) FROM bookings WHERE company_id=? AND branch_id=?) +
+               (SELECT COUNT(*) FROM expenses WHERE company_id=? AND branch_id=?) +
+               (SELECT COUNT(*) FROM users WHERE company_id=? AND branch_id=?)`,
+		companyID, branchID, companyID, branchID, companyID, branchID).Scan(&n)
+	return n > 0, err
+}
+
+// DeleteBranch removes the branch and the catalog set up for it.
+func (r *CompanyRepository) DeleteBranch(ctx context.Context, companyID, branchID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `DELETE FROM branches WHERE id=? AND company_id=?`, branchID, companyID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	for _, t := range []string{"price_sets", "price_items", "subcategories", "categories", "stations", "tables", "table_categories",
+		"payment_types", "expense_categories", "channels", "settings", "cashbox", "user_branches"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+t+" WHERE company_id=? AND branch_id=?", companyID, branchID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateBranchFrom creates a branch with a copy of the catalog of another
+// branch: categories with their stations, subcategories, price list without
+// stock, sets, table categories, tables, payment types, expense categories
+// and settings.
+func (r *CompanyRepository) CreateBranchFrom(ctx context.Context, companyID int, name string, fromID int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	toID, err := createBranch(ctx, tx, companyID, name)
+	if err != nil {
+		return 0, err
+	}
+	c := branchCopy{ctx: ctx, tx: tx, companyID: companyID, fromID: fromID, toID: toID}
+
+	stations, err := c.copy("stations", []string{"name"}, nil)
+	if err != nil {
+		return 0, err
+	}
+	cats, err := c.copy("categories", []string{"name", "is_prepared", "station_id"}, map[string]map[int]int{"station_id": stations})
+	if err != nil {
+		return 0, err
+	}
+	subs, err := c.copy("subcategories", []string{"category_id", "name"}, map[string]map[int]int{"category_id": cats})
+	if err != nil {
+		return 0, err
+	}
+	items, err := c.copy("price_items", []string{"name", "category_id", "subcategory_id", "sale_price", "buy_price", "is_set"},
+		map[string]map[int]int{"category_id": cats, "subcategory_id": subs})
+	if err != nil {
+		return 0, err
+	}
+	// набор хранится под тем же id, что и его позиция в прайсе
+	if _, err := c.copy("price_sets", []string{"id", "name", "category_id", "subcategory_id", "price"},
+		map[string]map[int]int{"id": items, "category_id": cats, "subcategory_id": subs}); err != nil {
+		return 0, err
+	}
+	if err := c.copySetItems(items); err != nil {
+		return 0, err
+	}
+	tableCats, err := c.copy("table_categories", []string{"name"}, nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.copy("tables", []string{"category_id", "name", "number"}, map[string]map[int]int{"category_id": tableCats}); err != nil {
+		return 0, err
+	}
+	paymentTypes, err := c.copy("payment_types", []string{"name", "hold_percent"}, nil)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.copy("expense_categories", []string{"name"}, nil); err != nil {
+		return 0, err
+	}
+	if _, err := c.copy("settings", []string{"payment_type", "block_time", "bonus_percent", "work_time_from", "work_time_to", "tables_count",
+		"notification_time", "deposit_forfeit_percent", "reservation_grace_minutes", "deposit_refund_hours", "cleanup_buffer_minutes"},
+		map[string]map[int]int{"payment_type": paymentTypes}); err != nil {
+		return 0, err
+	}
+	return toID, tx.Commit()
+}
+
+// branchCopy copies rows of branch tables from one branch to another.
+type branchCopy struct {
+	ctx       context.Context
+	tx        *sql.Tx
+	companyID int
+	fromID    int
+	toID      int
+}
+
+// copy copies the given columns of the rows of a table. Columns listed in
+// refs hold ids of copied rows and are translated to the new ids. Returns
+// the new id of every copied row.
+func (c *branchCopy) copy(table string, cols []string, refs map[string]map[int]int) (map[int]int, error) {
+	rows, err := c.tx.QueryContext(c.ctx, fmt.Sprintf("SELECT id, %s FROM %s WHERE company_id=? AND branch_id=? ORDER BY id",
+		strings.Join(cols, ", "), table), c.companyID, c.fromID)
+	if err != nil {
+		return nil, err
+	}
+	var list [][]interface{}
+	for rows.Next() {
+		vals := make([]interface{}, len(cols)+1)
+		ptrs := make([]interface{}, len(vals))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, vals)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s, company_id, branch_id) VALUES (%s?, ?)",
+		table, strings.Join(cols, ", "), strings.Repeat("?, ", len(cols)))
+	ids := make(map[int]int)
+	for _, vals := range list {
+		oldID := dbInt(vals[0])
+		args := vals[1:]
+		for i, col := range cols {
+			m, ok := refs[col]
+			if !ok || args[i] == nil {
+				continue
+			}
+			newID, ok := m[dbInt(args[i])]
+			if !ok {
+				args[i] = nil
+				continue
+			}
+			args[i] = newID
+		}
+		args = append(args, c.companyID, c.toID)
+		res, err := c.tx.ExecContext(c.ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids[oldID] = int(id)
+	}
+	return ids, nil
+}
+
+// copySetItems copies the content of sets, sets and items are given new ids
+// by the same map.
+func (c *branchCopy) copySetItems(items map[int]int) error {
+	rows, err := c.tx.QueryContext(c.ctx, `
+        SELECT si.price_set_id, si.item_id, si.quantity
+        FROM set_items si
+        JOIN price_sets ps ON ps.id = si.price_set_id
+        WHERE ps.company_id=? AND ps.branch_id=?`, c.companyID, c.fromID)
+	if err != nil {
+		return err
+	}
+	var list []models.SetItem
+	for rows.Next() {
+		var it models.SetItem
+		if err := rows.Scan(&it.PriceSetID, &it.ItemID, &it.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		list = append(list, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, it := range list {
+		setID, ok1 := items[it.PriceSetID]
+		itemID, ok2 := items[it.ItemID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		if _, err := c.tx.ExecContext(c.ctx, `INSERT INTO set_items (price_set_id, item_id, quantity) VALUES (?, ?, ?)`, setID, itemID, it.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbInt reads an integer scanned into interface{}, the driver gives either
+// int64 or its text.
+func dbInt(v interface{}) int {
+	switch x := v.(type) {
+	case int64:
+		return int(x)
+	case []byte:
+		n, _ := strconv.Atoi(string(x))
+		return n
+	case string:
+		n, _ := strconv.Atoi(x)
+		return n
+	}
+	return 0
 }
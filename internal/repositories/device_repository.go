@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"psclub-crm/internal/models"
+)
+
+// DeviceRepository keeps shared devices enrolled in branches.
+type DeviceRepository struct {
+	db *sql.DB
+}
+
+func NewDeviceRepository(db *sql.DB) *DeviceRepository {
+	return &DeviceRepository{db: db}
+}
+
+func (r *DeviceRepository) Create(ctx context.Context, d *models.Device, hash string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO branch_devices (company_id, branch_id, name, token_hash, created_by, created_at)
+        VALUES (?, ?, ?, ?, ?, NOW())`, d.CompanyID, d.BranchID, d.Name, hash, d.CreatedBy)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+const deviceColumns = `id, company_id, branch_id, name, created_by, created_at, last_used_at`
+
+func scanDevice(row rowScanner) (*models.Device, error) {
+	var d models.Device
+	var lastUsed sql.NullTime
+	if err := row.Scan(&d.ID, &d.CompanyID, &d.BranchID, &d.Name, &d.CreatedBy, &d.CreatedAt, &lastUsed); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		d.LastUsedAt = &lastUsed.Time
+	}
+	return &d, nil
+}
+
+// GetByHash returns the device of a device token.
+func (r *DeviceRepository) GetByHash(ctx context.Context, hash string) (*models.Device, error) {
+	return scanDevice(r.db.QueryRowContext(ctx, `SELECT `+deviceColumns+` FROM branch_devices WHERE token_hash=?`, hash))
+}
+
+func (r *DeviceRepository) GetAll(ctx context.Context, companyID, branchID int) ([]models.Device, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+deviceColumns+` FROM branch_devices
+        WHERE company_id=? AND branch_id=? ORDER BY id`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.Device
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *d)
+	}
+	return list, rows.Err()
+}
+
+// Touch marks the device as used now.
+func (r *DeviceRepository) Touch(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE branch_devices SET last_used_at=NOW() WHERE id=?`, id)
+	return err
+}
+
+// Delete revokes the device, its token stops working.
+func (r *DeviceRepository) Delete(ctx context.Context, id, companyID, branchID int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM branch_devices WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
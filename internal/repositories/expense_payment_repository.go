@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// ExpensePaymentRepository stores payments made against unpaid expenses.
+type ExpensePaymentRepository struct {
+	db *sql.DB
+}
+
+func NewExpensePaymentRepository(db *sql.DB) *ExpensePaymentRepository {
+	return &ExpensePaymentRepository{db: db}
+}
+
+// Pay records the payment and adds it to the paid amount of the expense in
+// one transaction. False is returned if the payment exceeds what is left to
+// pay.
+func (r *ExpensePaymentRepository) Pay(ctx context.Context, p *models.ExpensePayment) (id int, ok bool, err error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		if err != nil || !ok {
+			_ = tx.Rollback()
+		}
+	}()
+	res, err := tx.ExecContext(ctx, `UPDATE expenses SET paid_amount = paid_amount + ?, paid = (paid_amount >= total)
+                WHERE id=? AND company_id=? AND branch_id=? AND paid_amount + ? <= total`, p.Amount, p.ExpenseID, companyID, branchID, p.Amount)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, false, err
+	}
+	res, err = tx.ExecContext(ctx, `INSERT INTO expense_payments (expense_id, company_id, branch_id, amount, payment_type_id, from_cashbox, note, user_id, paid_at)
+                VALUES (?, ?, ?, ?, NULLIF(?,0), ?, ?, NULLIF(?,0), ?)`, p.ExpenseID, companyID, branchID, p.Amount, p.PaymentTypeID, p.FromCashbox, p.Note, p.UserID, p.PaidAt)
+	if err != nil {
+		return 0, false, err
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return int(newID), true, nil
+}
+
+// Cancel removes a payment recorded by Pay and takes it off the paid amount
+// of the expense.
+func (r *ExpensePaymentRepository) Cancel(ctx context.Context, p *models.ExpensePayment) (err error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.ExecContext(ctx, `DELETE FROM expense_payments WHERE id=? AND company_id=? AND branch_id=?`, p.ID, companyID, branchID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE expenses SET paid_amount = GREATEST(paid_amount - ?, 0), paid = (paid_amount >= total)
+                WHERE id=? AND company_id=? AND branch_id=?`, p.Amount, p.ExpenseID, companyID, branchID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *ExpensePaymentRepository) GetByExpense(ctx context.Context, expenseID int) ([]models.ExpensePayment, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	query := `SELECT ep.id, ep.expense_id, ep.amount, IFNULL(ep.payment_type_id, 0), IFNULL(pt.name, ''), ep.from_cashbox, ep.note, IFNULL(ep.user_id, 0), ep.paid_at, ep.company_id, ep.branch_id
+                FROM expense_payments ep
+                LEFT JOIN payment_types pt ON ep.payment_type_id = pt.id
+                WHERE ep.expense_id=? AND ep.company_id=? AND ep.branch_id=?
+                ORDER BY ep.paid_at, ep.id`
+	rows, err := r.db.QueryContext(ctx, query, expenseID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.ExpensePayment
+	for rows.Next() {
+		var p models.ExpensePayment
+		if err := rows.Scan(&p.ID, &p.ExpenseID, &p.Amount, &p.PaymentTypeID, &p.PaymentType, &p.FromCashbox, &p.Note, &p.UserID, &p.PaidAt, &p.CompanyID, &p.BranchID); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"psclub-crm/internal/common"
 	"psclub-crm/internal/models"
+	"time"
 )
 
 type ExpenseRepository struct {
@@ -15,12 +16,34 @@ func NewExpenseRepository(db *sql.DB) *ExpenseRepository {
 	return &ExpenseRepository{db: db}
 }
 
+const expenseSelect = `SELECT e.id, e.date, e.title, IFNULL(e.category_id, 0), IFNULL(ec.name, ''), IFNULL(e.repair_category_id, 0), IFNULL(rc.name,''), e.total, e.description, e.paid, e.paid_amount, e.due_date, e.counterparty, IFNULL(e.recurring_expense_id, 0), e.created_at, e.company_id, e.branch_id
+                FROM expenses e
+                LEFT JOIN expense_categories ec ON e.category_id = ec.id
+                LEFT JOIN repair_categories rc ON e.repair_category_id = rc.id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExpense(row rowScanner) (*models.Expense, error) {
+	var e models.Expense
+	var due sql.NullTime
+	err := row.Scan(&e.ID, &e.Date, &e.Title, &e.CategoryID, &e.Category, &e.RepairCategoryID, &e.RepairCategory, &e.Total, &e.Description, &e.Paid, &e.PaidAmount, &due, &e.Counterparty, &e.RecurringExpenseID, &e.CreatedAt, &e.CompanyID, &e.BranchID)
+	if err != nil {
+		return nil, err
+	}
+	if due.Valid {
+		e.DueDate = &due.Time
+	}
+	return &e, nil
+}
+
 func (r *ExpenseRepository) Create(ctx context.Context, e *models.Expense) (int, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
-	query := `INSERT INTO expenses (date, title, category_id, repair_category_id, total, description, paid, created_at, company_id, branch_id)
-                VALUES (?, ?, NULLIF(?,0), NULLIF(?,0), ?, ?, ?, NOW(), ?, ?)`
-	res, err := r.db.ExecContext(ctx, query, e.Date, e.Title, e.CategoryID, e.RepairCategoryID, e.Total, e.Description, e.Paid, companyID, branchID)
+	query := `INSERT INTO expenses (date, title, category_id, repair_category_id, total, description, paid, paid_amount, due_date, counterparty, recurring_expense_id, created_at, company_id, branch_id)
+                VALUES (?, ?, NULLIF(?,0), NULLIF(?,0), ?, ?, ?, ?, ?, ?, NULLIF(?,0), NOW(), ?, ?)`
+	res, err := r.db.ExecContext(ctx, query, e.Date, e.Title, e.CategoryID, e.RepairCategoryID, e.Total, e.Description, e.Paid, e.PaidAmount, e.DueDate, e.Counterparty, e.RecurringExpenseID, companyID, branchID)
 	if err != nil {
 		return 0, err
 	}
@@ -31,10 +54,7 @@ func (r *ExpenseRepository) Create(ctx context.Context, e *models.Expense) (int,
 func (r *ExpenseRepository) GetAll(ctx context.Context) ([]models.Expense, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
-	query := `SELECT e.id, e.date, e.title, IFNULL(e.category_id, 0), IFNULL(ec.name, ''), IFNULL(e.repair_category_id, 0), IFNULL(rc.name,''), e.total, e.description, e.paid, e.created_at, e.company_id, e.branch_id
-                FROM expenses e
-                LEFT JOIN expense_categories ec ON e.category_id = ec.id
-                LEFT JOIN repair_categories rc ON e.repair_category_id = rc.id
+	query := expenseSelect + `
                 WHERE e.company_id=? AND e.branch_id=?
                 ORDER BY e.id DESC`
 	rows, err := r.db.QueryContext(ctx, query, companyID, branchID)
@@ -44,40 +64,65 @@ func (r *ExpenseRepository) GetAll(ctx context.Context) ([]models.Expense, error
 	defer rows.Close()
 	var result []models.Expense
 	for rows.Next() {
-		var e models.Expense
-		err := rows.Scan(&e.ID, &e.Date, &e.Title, &e.CategoryID, &e.Category, &e.RepairCategoryID, &e.RepairCategory, &e.Total, &e.Description, &e.Paid, &e.CreatedAt, &e.CompanyID, &e.BranchID)
+		e, err := scanExpense(rows)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, e)
+		result = append(result, *e)
 	}
 	return result, nil
 }
 
-func (r *ExpenseRepository) GetByID(ctx context.Context, id int) (*models.Expense, error) {
+// GetPayables returns expenses that are not fully paid yet, the closest due
+// date first. Expenses without due date go last.
+func (r *ExpenseRepository) GetPayables(ctx context.Context) ([]models.Expense, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
-	query := `SELECT e.id, e.date, e.title, IFNULL(e.category_id, 0), IFNULL(ec.name, ''), IFNULL(e.repair_category_id, 0), IFNULL(rc.name,''), e.total, e.description, e.paid, e.created_at, e.company_id, e.branch_id
-                FROM expenses e
-                LEFT JOIN expense_categories ec ON e.category_id = ec.id
-                LEFT JOIN repair_categories rc ON e.repair_category_id = rc.id
-                WHERE e.id = ? AND e.company_id=? AND e.branch_id=?`
-	var e models.Expense
-	err := r.db.QueryRowContext(ctx, query, id, companyID, branchID).Scan(&e.ID, &e.Date, &e.Title, &e.CategoryID, &e.Category, &e.RepairCategoryID, &e.RepairCategory, &e.Total, &e.Description, &e.Paid, &e.CreatedAt, &e.CompanyID, &e.BranchID)
+	query := expenseSelect + `
+                WHERE e.company_id=? AND e.branch_id=? AND e.paid = 0 AND e.paid_amount < e.total
+                ORDER BY e.due_date IS NULL, e.due_date, e.id`
+	rows, err := r.db.QueryContext(ctx, query, companyID, branchID)
 	if err != nil {
 		return nil, err
 	}
-	return &e, nil
+	defer rows.Close()
+	var result []models.Expense
+	for rows.Next() {
+		e, err := scanExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *e)
+	}
+	return result, rows.Err()
 }
 
+func (r *ExpenseRepository) GetByID(ctx context.Context, id int) (*models.Expense, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	query := expenseSelect + `
+                WHERE e.id = ? AND e.company_id=? AND e.branch_id=?`
+	return scanExpense(r.db.QueryRowContext(ctx, query, id, companyID, branchID))
+}
+
+// Update saves expense fields. Paid amount is changed only when the expense is
+// marked as paid, otherwise it is maintained by recorded payments.
 func (r *ExpenseRepository) Update(ctx context.Context, e *models.Expense) error {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
-	query := `UPDATE expenses SET date=?, title=?, category_id=NULLIF(?,0), repair_category_id=NULLIF(?,0), total=?, description=?, paid=? WHERE id=? AND company_id=? AND branch_id=?`
-	_, err := r.db.ExecContext(ctx, query, e.Date, e.Title, e.CategoryID, e.RepairCategoryID, e.Total, e.Description, e.Paid, e.ID, companyID, branchID)
+	query := `UPDATE expenses SET date=?, title=?, category_id=NULLIF(?,0), repair_category_id=NULLIF(?,0), total=?, description=?, paid=?, paid_amount=IF(?, ?, (SELECT IFNULL(SUM(amount), 0) FROM expense_payments WHERE expense_id=expenses.id)), due_date=?, counterparty=? WHERE id=? AND company_id=? AND branch_id=?`
+	_, err := r.db.ExecContext(ctx, query, e.Date, e.Title, e.CategoryID, e.RepairCategoryID, e.Total, e.Description, e.Paid, e.Paid, e.Total, e.DueDate, e.Counterparty, e.ID, companyID, branchID)
 	return err
 }
 
+// ExistsForRecurring reports whether an expense was already generated from the
+// recurring template for the given date.
+func (r *ExpenseRepository) ExistsForRecurring(ctx context.Context, recurringID int, date time.Time) (bool, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM expenses WHERE recurring_expense_id=? AND date=?`, recurringID, date.Format("2006-01-02")).Scan(&count)
+	return count > 0, err
+}
+
 func (r *ExpenseRepository) Delete(ctx context.Context, id int) error {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+// GiftCertificateRepository stores gift certificates and spends their
+// balances on booking payments.
+type GiftCertificateRepository struct {
+	db *sql.DB
+}
+
+func NewGiftCertificateRepository(db *sql.DB) *GiftCertificateRepository {
+	return &GiftCertificateRepository{db: db}
+}
+
+const giftCertificateColumns = `id, company_id, branch_id, code, nominal, balance, IFNULL(purchaser_client_id, 0), purchaser_name,
+        IFNULL(payment_type_id, 0), IFNULL(user_id, 0), sold_at, expires_at`
+
+func scanGiftCertificate(row interface{ Scan(...interface{}) error }) (*models.GiftCertificate, error) {
+	var g models.GiftCertificate
+	if err := row.Scan(&g.ID, &g.CompanyID, &g.BranchID, &g.Code, &g.Nominal, &g.Balance, &g.PurchaserClientID, &g.PurchaserName,
+		&g.PaymentTypeID, &g.UserID, &g.SoldAt, &g.ExpiresAt); err != nil {
+		return nil, err
+	}
+	g.Active = g.Balance > 0 && time.Now().Before(g.ExpiresAt)
+	return &g, nil
+}
+
+func (r *GiftCertificateRepository) Create(ctx context.Context, g *models.GiftCertificate) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO gift_certificates (company_id, branch_id, code, nominal, balance, purchaser_client_id, purchaser_name, payment_type_id, user_id, sold_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, NULLIF(?,0), ?, NULLIF(?,0), NULLIF(?,0), ?, ?)`,
+		g.CompanyID, g.BranchID, g.Code, g.Nominal, g.Balance, g.PurchaserClientID, g.PurchaserName, g.PaymentTypeID, g.UserID, g.SoldAt, g.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (r *GiftCertificateRepository) GetAll(ctx context.Context, companyID, branchID int) ([]models.GiftCertificate, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+giftCertificateColumns+`
+        FROM gift_certificates WHERE company_id=? AND branch_id=? ORDER BY sold_at DESC, id DESC`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []models.GiftCertificate{}
+	for rows.Next() {
+		g, err := scanGiftCertificate(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *g)
+	}
+	return result, rows.Err()
+}
+
+// GetByCode finds a certificate of the company by its code. Certificates
+// are accepted in every branch of the company.
+func (r *GiftCertificateRepository) GetByCode(ctx context.Context, companyID int, code string) (*models.GiftCertificate, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+giftCertificateColumns+`
+        FROM gift_certificates WHERE company_id=? AND code=?`, companyID, code)
+	return scanGiftCertificate(row)
+}
+
+// Redeem takes amount from the certificate balance and records a
+// certificate payment for the booking. False is returned if the certificate
+// has expired or its balance is not enough.
+func (r *GiftCertificateRepository) Redeem(ctx context.Context, companyID, branchID, certID, bookingID, amount int) (ok bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE gift_certificates SET balance=balance-?
+        WHERE id=? AND company_id=? AND balance >= ? AND expires_at > NOW()`, amount, certID, companyID, amount)
+	if err != nil {
+		log.Printf("update certificate balance error: %v", err)
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO booking_payments (booking_id, company_id, branch_id, payment_type_id, amount, is_deposit, method, gift_certificate_id, created_at)
+        VALUES (?, ?, ?, NULL, ?, 0, ?, ?, NOW())`,
+		bookingID, companyID, branchID, amount, models.PaymentMethodCertificate, certID); err != nil {
+		log.Printf("insert certificate payment error: %v", err)
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit certificate redemption error: %v", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// ReturnForBooking puts certificate payments of a booking back to the
+// certificate balances and removes them from the booking.
+func (r *GiftCertificateRepository) ReturnForBooking(ctx context.Context, companyID, branchID, bookingID int) (returned []models.BookingPayment, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, amount, gift_certificate_id FROM booking_payments
+        WHERE booking_id=? AND company_id=? AND branch_id=? AND method=? AND gift_certificate_id IS NOT NULL FOR UPDATE`,
+		bookingID, companyID, branchID, models.PaymentMethodCertificate)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		p := models.BookingPayment{BookingID: bookingID, Method: models.PaymentMethodCertificate}
+		if err = rows.Scan(&p.ID, &p.Amount, &p.GiftCertificateID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		returned = append(returned, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range returned {
+		if _, err = tx.ExecContext(ctx, `UPDATE gift_certificates SET balance=balance+? WHERE id=?`, p.Amount, p.GiftCertificateID); err != nil {
+			log.Printf("return certificate balance error: %v", err)
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM booking_payments WHERE id=?`, p.ID); err != nil {
+			log.Printf("delete certificate payment error: %v", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit certificate return error: %v", err)
+		return nil, err
+	}
+	return returned, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Create(ctx context.Context, a *models.LoginAttempt) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO login_attempts (phone, ip, user_agent, user_id, company_id, success, reason, created_at)
+        VALUES (?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?, ?, NOW())`,
+		a.Phone, a.IP, a.UserAgent, a.UserID, a.CompanyID, a.Success, a.Reason)
+	return err
+}
+
+// PhoneFailures counts failed logins with the phone within the window after
+// its last successful login. Throttled attempts are not counted.
+func (r *LoginAttemptRepository) PhoneFailures(ctx context.Context, phone string, window time.Duration) (models.LoginFailures, error) {
+	return r.failures(ctx, `
+        SELECT COUNT(*), IFNULL(TIMESTAMPDIFF(SECOND, MAX(created_at), NOW()), 0)
+        FROM login_attempts
+        WHERE phone=? AND success=0 AND reason<>? AND created_at > NOW() - INTERVAL ? SECOND
+          AND created_at > IFNULL((SELECT MAX(s.created_at) FROM login_attempts s WHERE s.phone=? AND s.success=1), '1970-01-01')`,
+		phone, models.LoginThrottled, int(window.Seconds()), phone)
+}
+
+// IPFailures counts failed logins from the IP within the window. A
+// successful login does not reset them, so one known account does not
+// open the way for guessing others.
+func (r *LoginAttemptRepository) IPFailures(ctx context.Context, ip string, window time.Duration) (models.LoginFailures, error) {
+	return r.failures(ctx, `
+        SELECT COUNT(*), IFNULL(TIMESTAMPDIFF(SECOND, MAX(created_at), NOW()), 0)
+        FROM login_attempts
+        WHERE ip=? AND success=0 AND reason<>? AND created_at > NOW() - INTERVAL ? SECOND`,
+		ip, models.LoginThrottled, int(window.Seconds()))
+}
+
+func (r *LoginAttemptRepository) failures(ctx context.Context, query string, args ...interface{}) (models.LoginFailures, error) {
+	var f models.LoginFailures
+	var since int64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&f.Count, &since); err != nil {
+		return f, err
+	}
+	f.SinceLast = time.Duration(since) * time.Second
+	return f, nil
+}
+
+// List returns the security log of the company, newest first.
+func (r *LoginAttemptRepository) List(ctx context.Context, companyID int, f models.LoginAttemptFilter) ([]models.LoginAttempt, error) {
+	query := `SELECT a.id, a.phone, a.ip, a.user_agent, IFNULL(a.user_id, 0), IFNULL(u.name, ''), IFNULL(a.company_id, 0), a.success, a.reason, a.created_at
+                FROM login_attempts a
+                LEFT JOIN users u ON a.user_id = u.id
+                WHERE a.company_id=?`
+	args := []interface{}{companyID}
+	if f.Phone != "" {
+		query += " AND a.phone=?"
+		args = append(args, f.Phone)
+	}
+	if f.UserID > 0 {
+		query += " AND a.user_id=?"
+		args = append(args, f.UserID)
+	}
+	if f.FailedOnly {
+		query += " AND a.success=0"
+	}
+	if !f.From.IsZero() {
+		query += " AND a.created_at >= ?"
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		query += " AND a.created_at < ?"
+		args = append(args, f.To)
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	query += " ORDER BY a.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, f.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.LoginAttempt
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.Phone, &a.IP, &a.UserAgent, &a.UserID, &a.UserName, &a.CompanyID, &a.Success, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
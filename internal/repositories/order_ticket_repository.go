@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"psclub-crm/internal/models"
+)
+
+type OrderTicketRepository struct {
+	db *sql.DB
+}
+
+func NewOrderTicketRepository(db *sql.DB) *OrderTicketRepository {
+	return &OrderTicketRepository{db: db}
+}
+
+const orderTicketSelect = `SELECT t.id, t.station_id, t.booking_id, IFNULL(t.booking_item_id, 0), t.item_id, IFNULL(pi.name, ''), t.quantity,
+               IFNULL(t.table_id, 0), IFNULL(tb.name, ''), t.status, IFNULL(t.user_id, 0), t.created_at, t.started_at, t.served_at
+               FROM order_tickets t
+               LEFT JOIN price_items pi ON t.item_id = pi.id
+               LEFT JOIN tables tb ON t.table_id = tb.id`
+
+func scanOrderTicket(sc interface{ Scan(...interface{}) error }) (models.OrderTicket, error) {
+	var t models.OrderTicket
+	var started, served sql.NullTime
+	err := sc.Scan(&t.ID, &t.StationID, &t.BookingID, &t.BookingItemID, &t.ItemID, &t.ItemName, &t.Quantity,
+		&t.TableID, &t.TableName, &t.Status, &t.UserID, &t.CreatedAt, &started, &served)
+	if started.Valid {
+		t.StartedAt = &started.Time
+	}
+	if served.Valid {
+		t.ServedAt = &served.Time
+	}
+	return t, err
+}
+
+func (r *OrderTicketRepository) Create(ctx context.Context, companyID, branchID int, t *models.OrderTicket) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO order_tickets (company_id, branch_id, station_id, booking_id, booking_item_id, item_id, quantity, table_id, status, user_id, created_at)
+        VALUES (?, ?, ?, ?, NULLIF(?,0), ?, ?, NULLIF(?,0), ?, NULLIF(?,0), NOW())`,
+		companyID, branchID, t.StationID, t.BookingID, t.BookingItemID, t.ItemID, t.Quantity, t.TableID, t.Status, t.UserID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (r *OrderTicketRepository) GetByID(ctx context.Context, id, companyID, branchID int) (*models.OrderTicket, error) {
+	t, err := scanOrderTicket(r.db.QueryRowContext(ctx, orderTicketSelect+` WHERE t.id=? AND t.company_id=? AND t.branch_id=?`, id, companyID, branchID))
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *OrderTicketRepository) query(ctx context.Context, where string, args ...interface{}) ([]models.OrderTicket, error) {
+	rows, err := r.db.QueryContext(ctx, orderTicketSelect+" WHERE "+where+" ORDER BY t.created_at, t.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.OrderTicket
+	for rows.Next() {
+		t, err := scanOrderTicket(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// GetByStation returns tickets of a station in the given statuses, oldest
+// first.
+func (r *OrderTicketRepository) GetByStation(ctx context.Context, companyID, branchID, stationID int, statuses []string) ([]models.OrderTicket, error) {
+	args := []interface{}{stationID, companyID, branchID}
+	for _, s := range statuses {
+		args = append(args, s)
+	}
+	where := "t.station_id=? AND t.company_id=? AND t.branch_id=? AND t.status IN (?" + strings.Repeat(",?", len(statuses)-1) + ")"
+	return r.query(ctx, where, args...)
+}
+
+// GetOpenByBooking returns not yet served tickets of a booking, of a single
+// line when bookingItemID is set.
+func (r *OrderTicketRepository) GetOpenByBooking(ctx context.Context, companyID, branchID, bookingID, bookingItemID int) ([]models.OrderTicket, error) {
+	where := "t.booking_id=? AND t.company_id=? AND t.branch_id=? AND t.status IN ('" + models.TicketNew + "','" + models.TicketInProgress + "')"
+	args := []interface{}{bookingID, companyID, branchID}
+	if bookingItemID > 0 {
+		where += " AND t.booking_item_id=?"
+		args = append(args, bookingItemID)
+	}
+	return r.query(ctx, where, args...)
+}
+
+// SetStatus moves a ticket from one status to another and stamps the time
+// of the step. False is returned if the ticket is not in the expected status.
+func (r *OrderTicketRepository) SetStatus(ctx context.Context, companyID, branchID, id int, from, to string) (bool, error) {
+	set := "status=?"
+	switch to {
+	case models.TicketInProgress:
+		set += ", started_at=NOW()"
+	case models.TicketServed:
+		set += ", served_at=NOW(), started_at=IFNULL(started_at, NOW())"
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE order_tickets SET `+set+` WHERE id=? AND company_id=? AND branch_id=? AND status=?`, to, id, companyID, branchID, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"psclub-crm/internal/models"
+)
+
+type OTPRepository struct {
+	db *sql.DB
+}
+
+func NewOTPRepository(db *sql.DB) *OTPRepository {
+	return &OTPRepository{db: db}
+}
+
+func (r *OTPRepository) Create(ctx context.Context, o *models.OTPCode) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO otp_codes (company_id, branch_id, phone, purpose, code_hash, expires_at, created_at)
+             VALUES (?, ?, ?, ?, ?, ?, NOW())`, o.CompanyID, o.BranchID, o.Phone, o.Purpose, o.CodeHash, o.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// GetActive returns the latest unused and not expired code, nil if none.
+func (r *OTPRepository) GetActive(ctx context.Context, companyID, branchID int, phone, purpose string) (*models.OTPCode, error) {
+	var o models.OTPCode
+	err := r.db.QueryRowContext(ctx, `SELECT id, company_id, branch_id, phone, purpose, code_hash, attempts, expires_at, created_at
+             FROM otp_codes
+             WHERE company_id=? AND branch_id=? AND phone=? AND purpose=? AND used_at IS NULL AND expires_at > NOW()
+             ORDER BY id DESC LIMIT 1`, companyID, branchID, phone, purpose).
+		Scan(&o.ID, &o.CompanyID, &o.BranchID, &o.Phone, &o.Purpose, &o.CodeHash, &o.Attempts, &o.ExpiresAt, &o.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *OTPRepository) IncAttempts(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE otp_codes SET attempts = attempts + 1 WHERE id=?`, id)
+	return err
+}
+
+// MarkUsed closes the code. False means it was already used concurrently.
+func (r *OTPRepository) MarkUsed(ctx context.Context, id int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE otp_codes SET used_at = NOW() WHERE id=? AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,364 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+// PassRepository stores pass types, passes sold to clients and their
+// redemptions.
+type PassRepository struct {
+	db *sql.DB
+}
+
+func NewPassRepository(db *sql.DB) *PassRepository {
+	return &PassRepository{db: db}
+}
+
+func (r *PassRepository) setTypeCategories(ctx context.Context, tx *sql.Tx, t *models.PassType) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pass_type_categories WHERE pass_type_id=?`, t.ID); err != nil {
+		return err
+	}
+	for _, catID := range t.TableCategoryIDs {
+		if _, err := tx.ExecContext(ctx, `INSERT IGNORE INTO pass_type_categories (pass_type_id, table_category_id) VALUES (?, ?)`, t.ID, catID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PassRepository) CreateType(ctx context.Context, t *models.PassType) (id int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO pass_types (company_id, branch_id, name, kind, amount, price, validity_days, is_active, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		t.CompanyID, t.BranchID, t.Name, t.Kind, t.Amount, t.Price, t.ValidityDays, t.IsActive)
+	if err != nil {
+		log.Printf("insert pass type error: %v", err)
+		return 0, err
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	t.ID = int(lastID)
+	if err = r.setTypeCategories(ctx, tx, t); err != nil {
+		log.Printf("insert pass type categories error: %v", err)
+		return 0, err
+	}
+	err = tx.Commit()
+	return t.ID, err
+}
+
+// typeCategories returns table categories of every pass type of the branch.
+func (r *PassRepository) typeCategories(ctx context.Context, companyID, branchID int) (map[int][]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT ptc.pass_type_id, ptc.table_category_id
+        FROM pass_type_categories ptc
+        JOIN pass_types pt ON pt.id = ptc.pass_type_id
+        WHERE pt.company_id=? AND pt.branch_id=?
+        ORDER BY ptc.table_category_id`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make(map[int][]int)
+	for rows.Next() {
+		var typeID, catID int
+		if err := rows.Scan(&typeID, &catID); err != nil {
+			return nil, err
+		}
+		res[typeID] = append(res[typeID], catID)
+	}
+	return res, rows.Err()
+}
+
+func (r *PassRepository) GetTypes(ctx context.Context, companyID, branchID int) ([]models.PassType, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, company_id, branch_id, name, kind, amount, price, validity_days, is_active
+        FROM pass_types WHERE company_id=? AND branch_id=? ORDER BY id`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.PassType
+	for rows.Next() {
+		var t models.PassType
+		if err := rows.Scan(&t.ID, &t.CompanyID, &t.BranchID, &t.Name, &t.Kind, &t.Amount, &t.Price, &t.ValidityDays, &t.IsActive); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	cats, err := r.typeCategories(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		result[i].TableCategoryIDs = cats[result[i].ID]
+	}
+	return result, nil
+}
+
+func (r *PassRepository) GetTypeByID(ctx context.Context, id, companyID, branchID int) (*models.PassType, error) {
+	var t models.PassType
+	err := r.db.QueryRowContext(ctx, `SELECT id, company_id, branch_id, name, kind, amount, price, validity_days, is_active
+        FROM pass_types WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID).
+		Scan(&t.ID, &t.CompanyID, &t.BranchID, &t.Name, &t.Kind, &t.Amount, &t.Price, &t.ValidityDays, &t.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	cats, err := r.typeCategories(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	t.TableCategoryIDs = cats[t.ID]
+	return &t, nil
+}
+
+func (r *PassRepository) UpdateType(ctx context.Context, t *models.PassType) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE pass_types SET name=?, kind=?, amount=?, price=?, validity_days=?, is_active=?
+        WHERE id=? AND company_id=? AND branch_id=?`,
+		t.Name, t.Kind, t.Amount, t.Price, t.ValidityDays, t.IsActive, t.ID, t.CompanyID, t.BranchID)
+	if err != nil {
+		log.Printf("update pass type error: %v", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// MySQL не считает строку измененной, если значения совпали
+		var exists int
+		if err = tx.QueryRowContext(ctx, `SELECT 1 FROM pass_types WHERE id=? AND company_id=? AND branch_id=?`, t.ID, t.CompanyID, t.BranchID).Scan(&exists); err != nil {
+			return err
+		}
+	}
+	if err = r.setTypeCategories(ctx, tx, t); err != nil {
+		log.Printf("update pass type categories error: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeactivateType takes a pass type off sale. Passes already sold keep
+// working.
+func (r *PassRepository) DeactivateType(ctx context.Context, id, companyID, branchID int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE pass_types SET is_active=0 WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID)
+	return err
+}
+
+func (r *PassRepository) CreateClientPass(ctx context.Context, p *models.ClientPass) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO client_passes (company_id, branch_id, client_id, pass_type_id, kind, total, balance, price, payment_type_id, user_id, sold_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?,0), NULLIF(?,0), ?, ?)`,
+		p.CompanyID, p.BranchID, p.ClientID, p.PassTypeID, p.Kind, p.Total, p.Balance, p.Price, p.PaymentTypeID, p.UserID, p.SoldAt, p.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+const clientPassColumns = `cp.id, cp.company_id, cp.branch_id, cp.client_id, cp.pass_type_id, pt.name, cp.kind, cp.total, cp.balance, cp.price,
+        IFNULL(cp.payment_type_id, 0), IFNULL(cp.user_id, 0), cp.sold_at, cp.expires_at`
+
+func scanClientPass(row interface{ Scan(...interface{}) error }) (*models.ClientPass, error) {
+	var p models.ClientPass
+	if err := row.Scan(&p.ID, &p.CompanyID, &p.BranchID, &p.ClientID, &p.PassTypeID, &p.PassTypeName, &p.Kind, &p.Total, &p.Balance, &p.Price,
+		&p.PaymentTypeID, &p.UserID, &p.SoldAt, &p.ExpiresAt); err != nil {
+		return nil, err
+	}
+	p.Active = p.Balance > 0 && time.Now().Before(p.ExpiresAt)
+	return &p, nil
+}
+
+// GetClientPasses returns passes of a client, newest first.
+func (r *PassRepository) GetClientPasses(ctx context.Context, companyID, branchID, clientID int) ([]models.ClientPass, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+clientPassColumns+`
+        FROM client_passes cp
+        JOIN pass_types pt ON pt.id = cp.pass_type_id
+        WHERE cp.company_id=? AND cp.branch_id=? AND cp.client_id=?
+        ORDER BY cp.sold_at DESC, cp.id DESC`, companyID, branchID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []models.ClientPass{}
+	for rows.Next() {
+		p, err := scanClientPass(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *p)
+	}
+	return result, rows.Err()
+}
+
+func (r *PassRepository) GetClientPass(ctx context.Context, id, companyID, branchID int) (*models.ClientPass, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+clientPassColumns+`
+        FROM client_passes cp
+        JOIN pass_types pt ON pt.id = cp.pass_type_id
+        WHERE cp.id=? AND cp.company_id=? AND cp.branch_id=?`, id, companyID, branchID)
+	return scanClientPass(row)
+}
+
+// IsTableAllowed reports whether a pass type is valid for the table. A type
+// without categories is valid for any table.
+func (r *PassRepository) IsTableAllowed(ctx context.Context, passTypeID, tableID int) (bool, error) {
+	var allowed bool
+	err := r.db.QueryRowContext(ctx, `SELECT NOT EXISTS (SELECT 1 FROM pass_type_categories WHERE pass_type_id=?)
+            OR EXISTS (SELECT 1 FROM pass_type_categories ptc JOIN tables t ON t.category_id = ptc.table_category_id
+                       WHERE ptc.pass_type_id=? AND t.id=?)`, passTypeID, passTypeID, tableID).Scan(&allowed)
+	return allowed, err
+}
+
+// IsLineRedeemed reports whether a booking line is already paid by a pass.
+func (r *PassRepository) IsLineRedeemed(ctx context.Context, companyID, branchID, bookingID, lineID int) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pass_redemptions
+        WHERE company_id=? AND branch_id=? AND booking_id=? AND booking_item_id=? AND returned_at IS NULL`,
+		companyID, branchID, bookingID, lineID).Scan(&n)
+	return n > 0, err
+}
+
+// Redeem takes units from the pass balance and records a pass payment for
+// the booking. False is returned if the pass has expired or its balance is
+// not enough.
+func (r *PassRepository) Redeem(ctx context.Context, companyID, branchID int, red *models.PassRedemption) (ok bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE client_passes SET balance=balance-?
+        WHERE id=? AND company_id=? AND branch_id=? AND balance >= ? AND expires_at > NOW()`,
+		red.Units, red.ClientPassID, companyID, branchID, red.Units)
+	if err != nil {
+		log.Printf("update pass balance error: %v", err)
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	res, err = tx.ExecContext(ctx, `INSERT INTO booking_payments (booking_id, company_id, branch_id, payment_type_id, amount, is_deposit, method, client_pass_id, created_at)
+        VALUES (?, ?, ?, NULL, ?, 0, ?, ?, NOW())`,
+		red.BookingID, companyID, branchID, red.Amount, models.PaymentMethodPass, red.ClientPassID)
+	if err != nil {
+		log.Printf("insert pass payment error: %v", err)
+		return false, err
+	}
+	payID, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	red.PaymentID = int(payID)
+	res, err = tx.ExecContext(ctx, `INSERT INTO pass_redemptions (company_id, branch_id, client_pass_id, booking_id, booking_item_id, payment_id, units, amount, user_id, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?,0), NOW())`,
+		companyID, branchID, red.ClientPassID, red.BookingID, red.BookingItemID, red.PaymentID, red.Units, red.Amount, red.UserID)
+	if err != nil {
+		log.Printf("insert pass redemption error: %v", err)
+		return false, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	red.ID = int(id)
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit pass redemption error: %v", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// ReturnRedemptions gives units back to the passes for a booking line or,
+// with lineID 0, for the whole booking. Pass payments are removed from the
+// booking. Returned redemptions are listed.
+func (r *PassRepository) ReturnRedemptions(ctx context.Context, companyID, branchID, bookingID, lineID int) (returned []models.PassRedemption, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := `SELECT id, client_pass_id, booking_id, booking_item_id, IFNULL(payment_id, 0), units, amount, IFNULL(user_id, 0), created_at
+        FROM pass_redemptions
+        WHERE company_id=? AND branch_id=? AND booking_id=? AND returned_at IS NULL`
+	args := []interface{}{companyID, branchID, bookingID}
+	if lineID > 0 {
+		query += ` AND booking_item_id=?`
+		args = append(args, lineID)
+	}
+	rows, err := tx.QueryContext(ctx, query+` FOR UPDATE`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var red models.PassRedemption
+		if err = rows.Scan(&red.ID, &red.ClientPassID, &red.BookingID, &red.BookingItemID, &red.PaymentID, &red.Units, &red.Amount, &red.UserID, &red.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		returned = append(returned, red)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, red := range returned {
+		if _, err = tx.ExecContext(ctx, `UPDATE client_passes SET balance=balance+? WHERE id=?`, red.Units, red.ClientPassID); err != nil {
+			log.Printf("return pass balance error: %v", err)
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM booking_payments WHERE id=? AND method=?`, red.PaymentID, models.PaymentMethodPass); err != nil {
+			log.Printf("delete pass payment error: %v", err)
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, `UPDATE pass_redemptions SET returned_at=NOW() WHERE id=?`, red.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit pass return error: %v", err)
+		return nil, err
+	}
+	return returned, nil
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+// PinRepository keeps PIN codes of users for the login on shared devices.
+type PinRepository struct {
+	db *sql.DB
+}
+
+func NewPinRepository(db *sql.DB) *PinRepository {
+	return &PinRepository{db: db}
+}
+
+// Set stores a new PIN of the user, the lockout is lifted.
+func (r *PinRepository) Set(ctx context.Context, userID int, hash string) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO user_pins (user_id, pin_hash, failed_attempts, locked_until, updated_at) VALUES (?, ?, 0, NULL, NOW())
+        ON DUPLICATE KEY UPDATE pin_hash=VALUES(pin_hash), failed_attempts=0, locked_until=NULL, updated_at=NOW()`,
+		userID, hash)
+	return err
+}
+
+// Get returns the PIN of the user or nil when the user has none.
+func (r *PinRepository) Get(ctx context.Context, userID int) (*models.UserPin, error) {
+	var p models.UserPin
+	err := r.db.QueryRowContext(ctx, `
+        SELECT user_id, pin_hash, failed_attempts, IFNULL(locked_until > NOW(), 0)
+        FROM user_pins WHERE user_id=?`, userID).
+		Scan(&p.UserID, &p.Hash, &p.FailedAttempts, &p.Locked)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Fail counts a wrong PIN. After maxAttempts wrong PINs in a row the PIN is
+// locked for the given time, it reports whether that happened.
+func (r *PinRepository) Fail(ctx context.Context, userID, maxAttempts int, lock time.Duration) (bool, error) {
+	if _, err := r.db.ExecContext(ctx, `UPDATE user_pins SET failed_attempts=failed_attempts+1 WHERE user_id=?`, userID); err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE user_pins SET failed_attempts=0, locked_until=DATE_ADD(NOW(), INTERVAL ? SECOND)
+        WHERE user_id=? AND failed_attempts>=?`, int(lock.Seconds()), userID, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Reset clears wrong attempts after a successful login.
+func (r *PinRepository) Reset(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE user_pins SET failed_attempts=0, locked_until=NULL WHERE user_id=?`, userID)
+	return err
+}
+
+func (r *PinRepository) Delete(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_pins WHERE user_id=?`, userID)
+	return err
+}
+
+// GetUsers lists staff with a PIN who have access to the branch, with their
+// role there.
+func (r *PinRepository) GetUsers(ctx context.Context, companyID, branchID int) ([]models.DeviceUser, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT u.id, u.name, ub.role
+        FROM user_branches ub
+        JOIN users u ON u.id = ub.user_id
+        JOIN user_pins p ON p.user_id = u.id
+        WHERE ub.company_id=? AND ub.branch_id=? AND u.company_id=?
+        ORDER BY u.name`, companyID, branchID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.DeviceUser
+	for rows.Next() {
+		var du models.DeviceUser
+		if err := rows.Scan(&du.ID, &du.Name, &du.Role); err != nil {
+			return nil, err
+		}
+		list = append(list, du)
+	}
+	return list, rows.Err()
+}
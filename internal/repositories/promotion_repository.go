@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"psclub-crm/internal/models"
+)
+
+// PromotionRepository stores promotions and the discounts they produced
+// for bookings.
+type PromotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+const promotionColumns = `id, company_id, branch_id, name, kind, value, IFNULL(category_id, 0), IFNULL(item_id, 0), days_of_week, time_from, time_to,
+        valid_from, valid_to, min_spend, min_client_visits, IFNULL(code, ''), usage_limit, used_count, stackable, priority, is_active`
+
+func scanPromotion(row interface{ Scan(...interface{}) error }) (*models.Promotion, error) {
+	var p models.Promotion
+	var validFrom, validTo sql.NullTime
+	if err := row.Scan(&p.ID, &p.CompanyID, &p.BranchID, &p.Name, &p.Kind, &p.Value, &p.CategoryID, &p.ItemID, &p.DaysOfWeek, &p.TimeFrom, &p.TimeTo,
+		&validFrom, &validTo, &p.MinSpend, &p.MinClientVisits, &p.Code, &p.UsageLimit, &p.UsedCount, &p.Stackable, &p.Priority, &p.IsActive); err != nil {
+		return nil, err
+	}
+	if validFrom.Valid {
+		p.ValidFrom = &validFrom.Time
+	}
+	if validTo.Valid {
+		p.ValidTo = &validTo.Time
+	}
+	return &p, nil
+}
+
+func (r *PromotionRepository) Create(ctx context.Context, p *models.Promotion) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO promotions (company_id, branch_id, name, kind, value, category_id, item_id, days_of_week, time_from, time_to,
+        valid_from, valid_to, min_spend, min_client_visits, code, usage_limit, stackable, priority, is_active, created_at)
+        VALUES (?, ?, ?, ?, ?, NULLIF(?,0), NULLIF(?,0), ?, ?, ?, ?, ?, ?, ?, NULLIF(?,''), ?, ?, ?, ?, NOW())`,
+		p.CompanyID, p.BranchID, p.Name, p.Kind, p.Value, p.CategoryID, p.ItemID, p.DaysOfWeek, p.TimeFrom, p.TimeTo,
+		p.ValidFrom, p.ValidTo, p.MinSpend, p.MinClientVisits, p.Code, p.UsageLimit, p.Stackable, p.Priority, p.IsActive)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (r *PromotionRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.Promotion, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []models.Promotion{}
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *p)
+	}
+	return result, rows.Err()
+}
+
+func (r *PromotionRepository) GetAll(ctx context.Context, companyID, branchID int) ([]models.Promotion, error) {
+	return r.query(ctx, `SELECT `+promotionColumns+` FROM promotions WHERE company_id=? AND branch_id=? ORDER BY priority DESC, id`, companyID, branchID)
+}
+
+// GetActive returns promotions that can be applied to bookings: active ones
+// without a code plus the one with the given code.
+func (r *PromotionRepository) GetActive(ctx context.Context, companyID, branchID int, code string) ([]models.Promotion, error) {
+	return r.query(ctx, `SELECT `+promotionColumns+` FROM promotions
+        WHERE company_id=? AND branch_id=? AND is_active=1 AND (code IS NULL OR code=?)
+        ORDER BY priority DESC, id`, companyID, branchID, code)
+}
+
+func (r *PromotionRepository) GetByID(ctx context.Context, id, companyID, branchID int) (*models.Promotion, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+promotionColumns+` FROM promotions WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID)
+	return scanPromotion(row)
+}
+
+// GetByCode returns nil if the company has no promotion with the code.
+func (r *PromotionRepository) GetByCode(ctx context.Context, companyID int, code string) (*models.Promotion, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+promotionColumns+` FROM promotions WHERE company_id=? AND code=?`, companyID, code)
+	p, err := scanPromotion(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *PromotionRepository) Update(ctx context.Context, p *models.Promotion) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE promotions SET name=?, kind=?, value=?, category_id=NULLIF(?,0), item_id=NULLIF(?,0), days_of_week=?, time_from=?, time_to=?,
+        valid_from=?, valid_to=?, min_spend=?, min_client_visits=?, code=NULLIF(?,''), usage_limit=?, stackable=?, priority=?, is_active=?
+        WHERE id=? AND company_id=? AND branch_id=?`,
+		p.Name, p.Kind, p.Value, p.CategoryID, p.ItemID, p.DaysOfWeek, p.TimeFrom, p.TimeTo,
+		p.ValidFrom, p.ValidTo, p.MinSpend, p.MinClientVisits, p.Code, p.UsageLimit, p.Stackable, p.Priority, p.IsActive,
+		p.ID, p.CompanyID, p.BranchID)
+	return err
+}
+
+// Deactivate stops a promotion. It is kept for the discount history of
+// bookings.
+func (r *PromotionRepository) Deactivate(ctx context.Context, id, companyID, branchID int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE promotions SET is_active=0 WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID)
+	return err
+}
+
+// UseCode counts one more use of a promo code. False is returned when the
+// usage limit is reached.
+func (r *PromotionRepository) UseCode(ctx context.Context, id int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE promotions SET used_count=used_count+1 WHERE id=? AND (usage_limit=0 OR used_count < usage_limit)`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseCode returns a use of a promo code, e.g. when the booking is
+// cancelled.
+func (r *PromotionRepository) ReleaseCode(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE promotions SET used_count=used_count-1 WHERE id=? AND used_count > 0`, id)
+	return err
+}
+
+// ReplaceBookingPromotions rewrites the discounts recorded for a booking.
+func (r *PromotionRepository) ReplaceBookingPromotions(ctx context.Context, companyID, branchID, bookingID int, list []models.BookingPromotion) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM booking_promotions WHERE booking_id=? AND company_id=? AND branch_id=?`, bookingID, companyID, branchID); err != nil {
+		log.Printf("delete booking promotions error: %v", err)
+		return err
+	}
+	for _, p := range list {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO booking_promotions (company_id, branch_id, booking_id, booking_item_id, promotion_id, amount, created_at)
+            VALUES (?, ?, ?, NULLIF(?,0), ?, ?, NOW())`, companyID, branchID, bookingID, p.BookingItemID, p.PromotionID, p.Amount); err != nil {
+			log.Printf("insert booking promotion error: %v", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// AddBookingPromotions records discounts of new lines of a booking.
+func (r *PromotionRepository) AddBookingPromotions(ctx context.Context, companyID, branchID, bookingID int, list []models.BookingPromotion) error {
+	for _, p := range list {
+		if _, err := r.db.ExecContext(ctx, `INSERT INTO booking_promotions (company_id, branch_id, booking_id, booking_item_id, promotion_id, amount, created_at)
+            VALUES (?, ?, ?, NULLIF(?,0), ?, ?, NOW())`, companyID, branchID, bookingID, p.BookingItemID, p.PromotionID, p.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteLinePromotions drops discounts of a voided booking line.
+func (r *PromotionRepository) DeleteLinePromotions(ctx context.Context, companyID, branchID, bookingID, lineID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM booking_promotions WHERE booking_id=? AND booking_item_id=? AND company_id=? AND branch_id=?`, bookingID, lineID, companyID, branchID)
+	return err
+}
+
+func (r *PromotionRepository) GetBookingPromotions(ctx context.Context, companyID, branchID, bookingID int) ([]models.BookingPromotion, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT bp.id, bp.booking_id, IFNULL(bp.booking_item_id, 0), bp.promotion_id, p.name, bp.amount
+        FROM booking_promotions bp
+        JOIN promotions p ON p.id = bp.promotion_id
+        WHERE bp.booking_id=? AND bp.company_id=? AND bp.branch_id=?
+        ORDER BY bp.id`, bookingID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.BookingPromotion
+	for rows.Next() {
+		var p models.BookingPromotion
+		if err := rows.Scan(&p.ID, &p.BookingID, &p.BookingItemID, &p.PromotionID, &p.PromotionName, &p.Amount); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
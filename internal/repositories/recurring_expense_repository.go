@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+type RecurringExpenseRepository struct {
+	db *sql.DB
+}
+
+func NewRecurringExpenseRepository(db *sql.DB) *RecurringExpenseRepository {
+	return &RecurringExpenseRepository{db: db}
+}
+
+const recurringExpenseSelect = `SELECT r.id, r.title, IFNULL(r.category_id, 0), IFNULL(ec.name, ''), r.total, IFNULL(r.description, ''), r.counterparty, r.schedule, r.day_of_month, r.day_of_week, r.cron, r.mark_paid, r.due_days, r.paused, r.next_run, r.last_run, r.created_at, r.company_id, r.branch_id
+                FROM recurring_expenses r
+                LEFT JOIN expense_categories ec ON r.category_id = ec.id`
+
+func scanRecurringExpense(row rowScanner) (*models.RecurringExpense, error) {
+	var e models.RecurringExpense
+	var lastRun sql.NullTime
+	err := row.Scan(&e.ID, &e.Title, &e.CategoryID, &e.Category, &e.Total, &e.Description, &e.Counterparty, &e.Schedule, &e.DayOfMonth, &e.DayOfWeek, &e.Cron, &e.MarkPaid, &e.DueDays, &e.Paused, &e.NextRun, &lastRun, &e.CreatedAt, &e.CompanyID, &e.BranchID)
+	if err != nil {
+		return nil, err
+	}
+	if lastRun.Valid {
+		e.LastRun = &lastRun.Time
+	}
+	return &e, nil
+}
+
+func (r *RecurringExpenseRepository) Create(ctx context.Context, e *models.RecurringExpense) (int, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	query := `INSERT INTO recurring_expenses (company_id, branch_id, title, category_id, total, description, counterparty, schedule, day_of_month, day_of_week, cron, mark_paid, due_days, paused, next_run)
+                VALUES (?, ?, ?, NULLIF(?,0), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := r.db.ExecContext(ctx, query, companyID, branchID, e.Title, e.CategoryID, e.Total, e.Description, e.Counterparty, e.Schedule, e.DayOfMonth, e.DayOfWeek, e.Cron, e.MarkPaid, e.DueDays, e.Paused, e.NextRun.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (r *RecurringExpenseRepository) GetAll(ctx context.Context) ([]models.RecurringExpense, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	rows, err := r.db.QueryContext(ctx, recurringExpenseSelect+`
+                WHERE r.company_id=? AND r.branch_id=?
+                ORDER BY r.next_run, r.id`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.RecurringExpense
+	for rows.Next() {
+		e, err := scanRecurringExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *e)
+	}
+	return result, rows.Err()
+}
+
+func (r *RecurringExpenseRepository) GetByID(ctx context.Context, id int) (*models.RecurringExpense, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return scanRecurringExpense(r.db.QueryRowContext(ctx, recurringExpenseSelect+`
+                WHERE r.id=? AND r.company_id=? AND r.branch_id=?`, id, companyID, branchID))
+}
+
+// GetDue returns active templates of all companies whose next run is on or
+// before date. Used by the scheduler, so it is not limited to a tenant.
+func (r *RecurringExpenseRepository) GetDue(ctx context.Context, date time.Time) ([]models.RecurringExpense, error) {
+	rows, err := r.db.QueryContext(ctx, recurringExpenseSelect+`
+                WHERE r.paused = 0 AND r.next_run <= ?
+                ORDER BY r.company_id, r.branch_id, r.next_run`, date.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.RecurringExpense
+	for rows.Next() {
+		e, err := scanRecurringExpense(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *e)
+	}
+	return result, rows.Err()
+}
+
+func (r *RecurringExpenseRepository) Update(ctx context.Context, e *models.RecurringExpense) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	query := `UPDATE recurring_expenses SET title=?, category_id=NULLIF(?,0), total=?, description=?, counterparty=?, schedule=?, day_of_month=?, day_of_week=?, cron=?, mark_paid=?, due_days=?, next_run=?
+                WHERE id=? AND company_id=? AND branch_id=?`
+	_, err := r.db.ExecContext(ctx, query, e.Title, e.CategoryID, e.Total, e.Description, e.Counterparty, e.Schedule, e.DayOfMonth, e.DayOfWeek, e.Cron, e.MarkPaid, e.DueDays, e.NextRun.Format("2006-01-02"), e.ID, companyID, branchID)
+	return err
+}
+
+// SetPaused pauses or resumes the template and stores the next run date.
+func (r *RecurringExpenseRepository) SetPaused(ctx context.Context, id int, paused bool, nextRun time.Time) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	_, err := r.db.ExecContext(ctx, `UPDATE recurring_expenses SET paused=?, next_run=? WHERE id=? AND company_id=? AND branch_id=?`, paused, nextRun.Format("2006-01-02"), id, companyID, branchID)
+	return err
+}
+
+// UpdateRun moves the template to its next run after the scheduler generated expenses.
+func (r *RecurringExpenseRepository) UpdateRun(ctx context.Context, id int, nextRun, lastRun time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE recurring_expenses SET next_run=?, last_run=? WHERE id=?`, nextRun.Format("2006-01-02"), lastRun.Format("2006-01-02"), id)
+	return err
+}
+
+func (r *RecurringExpenseRepository) Delete(ctx context.Context, id int) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	_, err := r.db.ExecContext(ctx, `DELETE FROM recurring_expenses WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID)
+	return err
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math"
 	"psclub-crm/internal/models"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -34,7 +36,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 	var result models.SummaryReport
 	fmt.Println("SummaryReport called with:", from, to, tFrom, tTo, userID)
 	cond, condArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	cond = "b.company_id=? AND b.branch_id=? AND " + cond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	cond = "b.company_id=? AND b.branch_id=? AND " + cond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 	query := fmt.Sprintf(`
        SELECT
            COALESCE(SUM(b.total_amount), 0) as total,
@@ -58,7 +60,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 
 	// Calculate total cost for Bar and Hookah categories
 	condCost, costArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	condCost = "b.company_id=? AND b.branch_id=? AND " + condCost + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	condCost = "b.company_id=? AND b.branch_id=? AND " + condCost + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b") + notVoided("bi")
 	costQuery := fmt.Sprintf(`
         SELECT COALESCE(SUM(
             bi.price * (1 - bi.discount / 100) * (1 - IFNULL(pt.hold_percent,0)/100) -
@@ -80,7 +82,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 	// Calculate load percent
 	var bookingsCount int
 	condCount, countArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	condCount = "b.company_id=? AND b.branch_id=? AND " + condCount + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	condCount = "b.company_id=? AND b.branch_id=? AND " + condCount + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM bookings b WHERE %s", condCount)
 	countArgs = append([]interface{}{companyID, branchID}, countArgs...)
 	if userID > 0 {
@@ -89,8 +91,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 	}
 	_ = r.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&bookingsCount)
 
-	var tableCount int
-	_ = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tables WHERE company_id=? AND branch_id=?`, companyID, branchID).Scan(&tableCount)
+	tableCount, _ := r.TableCount(ctx, companyID, branchID)
 
 	var workFrom, workTo string
 	_ = r.db.QueryRowContext(ctx, `SELECT work_time_from, work_time_to FROM settings WHERE company_id=? AND branch_id=? LIMIT 1`, companyID, branchID).Scan(&workFrom, &workTo)
@@ -112,7 +113,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 	// Age groups
 	var under18, age18to25, age26to35, age36Plus float64
 	condAge, ageArgs := buildTimeCondition("created_at", from, to, tFrom, tTo)
-	condAge = "company_id=? AND branch_id=? AND " + condAge + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0"
+	condAge = "company_id=? AND branch_id=? AND " + condAge + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0" + notCounted("")
 	ageQuery := fmt.Sprintf(`
                 SELECT
                     SUM(CASE WHEN TIMESTAMPDIFF(YEAR, date_of_birth, CURDATE()) < 18 THEN 1 ELSE 0 END),
@@ -140,7 +141,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 	// Channel statistics
 	// Use booking start time for consistent client counting
 	condCh, chArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	condCh = "b.company_id=? AND b.branch_id=? AND " + condCh + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0"
+	condCh = "b.company_id=? AND b.branch_id=? AND " + condCh + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0" + notCounted("b")
 	chQuery := fmt.Sprintf(`
                SELECT IFNULL(ch.name, ''), COUNT(*)
                FROM clients c
@@ -170,7 +171,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 	}
 
 	guestCond, guestArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	guestCond = "b.company_id=? AND b.branch_id=? AND " + guestCond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0 AND b.client_id IS NULL"
+	guestCond = "b.company_id=? AND b.branch_id=? AND " + guestCond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0 AND b.client_id IS NULL" + notCounted("b")
 	guestQuery := fmt.Sprintf("SELECT COUNT(*) FROM bookings b WHERE %s", guestCond)
 	guestArgs = append([]interface{}{companyID, branchID}, guestArgs...)
 	if userID > 0 {
@@ -196,7 +197,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 
 	// Category sales
 	condCat, catArgs := buildTimeCondition("bookings.start_time", from, to, tFrom, tTo)
-	condCat = "bookings.company_id=? AND bookings.branch_id=? AND " + condCat + " AND bookings.payment_status <> 'UNPAID' AND bookings.payment_type_id <> 0"
+	condCat = "bookings.company_id=? AND bookings.branch_id=? AND " + condCat + " AND bookings.payment_status <> 'UNPAID' AND bookings.payment_type_id <> 0" + notCounted("bookings") + notVoided("booking_items")
 	catQuery := fmt.Sprintf(`
                SELECT categories.name, SUM(booking_items.price  * (1 - booking_items.discount / 100) * (1 - IFNULL(pt.hold_percent,0)/100))
                FROM booking_items
@@ -223,7 +224,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 
 	// Top items by profit
 	condItem, itemArgs := buildTimeCondition("bookings.start_time", from, to, tFrom, tTo)
-	condItem = "bookings.company_id=? AND bookings.branch_id=? AND " + condItem + " AND bookings.payment_status <> 'UNPAID' AND bookings.payment_type_id <> 0"
+	condItem = "bookings.company_id=? AND bookings.branch_id=? AND " + condItem + " AND bookings.payment_status <> 'UNPAID' AND bookings.payment_type_id <> 0" + notCounted("bookings") + notVoided("booking_items")
 	itemQuery := fmt.Sprintf(`
     SELECT
         price_items.name,
@@ -287,7 +288,7 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 	prevFrom := from.Add(-(to.Sub(from)))
 	prevTo := from
 	condPrev, prevArgs := buildTimeCondition("b.start_time", prevFrom, prevTo, tFrom, tTo)
-	condPrev = "b.company_id=? AND b.branch_id=? AND " + condPrev + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	condPrev = "b.company_id=? AND b.branch_id=? AND " + condPrev + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 	prevQuery := fmt.Sprintf(`
        SELECT COALESCE(SUM(b.total_amount  * (1 - IFNULL(pt.hold_percent,0)/100)),0),
               COUNT(DISTINCT client_id) + SUM(CASE WHEN client_id IS NULL THEN 1 ELSE 0 END),
@@ -311,9 +312,74 @@ func (r *ReportRepository) SummaryReport(ctx context.Context, from, to time.Time
 		result.AvgCheckChange = float64(result.AvgCheck-prevAvgCheck) * 100.0 / float64(prevAvgCheck)
 	}
 
+	result.Cancellations = r.cancellations(ctx, from, to, tFrom, tTo, userID, companyID, branchID)
+
 	return &result, nil
 }
 
+// cancellations collects cancelled bookings of the period grouped by status
+// and reason. Refunded is the money returned to clients, Amount the total of
+// cancelled bookings.
+func (r *ReportRepository) cancellations(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) models.Cancellations {
+	var res models.Cancellations
+	cond, condArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
+	cond = "b.company_id=? AND b.branch_id=? AND " + cond + " AND b.status IN (" + cancelledStatuses + ")"
+	args := append([]interface{}{companyID, branchID}, condArgs...)
+	if userID > 0 {
+		cond += " AND b.user_id = ?"
+		args = append(args, userID)
+	}
+	query := fmt.Sprintf(`
+       SELECT b.status, IFNULL(b.cancel_reason, ''), COUNT(*), COALESCE(SUM(b.total_amount),0), COALESCE(SUM(r.amount),0)
+       FROM bookings b
+       LEFT JOIN (SELECT booking_id, SUM(amount) AS amount FROM booking_refunds GROUP BY booking_id) r ON r.booking_id = b.id
+       WHERE %s
+       GROUP BY b.status, b.cancel_reason`, cond)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return res
+	}
+	defer rows.Close()
+	byStatus := make(map[string]int)
+	var statuses []string
+	byReason := make(map[string]*models.ReasonRow)
+	var reasons []string
+	for rows.Next() {
+		var status, reason string
+		var cnt, amount, refunded int
+		if err := rows.Scan(&status, &reason, &cnt, &amount, &refunded); err != nil {
+			continue
+		}
+		res.Count += cnt
+		res.Amount += amount
+		res.Refunded += refunded
+		if _, ok := byStatus[status]; !ok {
+			statuses = append(statuses, status)
+		}
+		byStatus[status] += cnt
+		row, ok := byReason[reason]
+		if !ok {
+			row = &models.ReasonRow{Reason: reason}
+			byReason[reason] = row
+			reasons = append(reasons, reason)
+		}
+		row.Count += cnt
+		row.Sum += float64(refunded)
+	}
+	for _, st := range statuses {
+		res.ByStatus = append(res.ByStatus, models.DataPoint{Label: st, Value: byStatus[st]})
+	}
+	for _, reason := range reasons {
+		row := byReason[reason]
+		if row.Count > 0 {
+			row.Avg = row.Sum / float64(row.Count)
+		}
+		res.ByReason = append(res.ByReason, *row)
+	}
+	sort.Slice(res.ByReason, func(i, j int) bool { return res.ByReason[i].Count > res.ByReason[j].Count })
+	return res
+}
+
 // --- AdminsReport ---
 func (r *ReportRepository) AdminsReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) (*models.AdminsReport, error) {
 
@@ -334,7 +400,7 @@ func (r *ReportRepository) AdminsReport(ctx context.Context, from, to time.Time,
 	}
 
 	condAdmin, adminArgs := buildTimeCondition("b.start_time", from, to, adminTFrom, adminTTo)
-	condAdmin = "b.company_id=? AND b.branch_id=? AND " + condAdmin + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	condAdmin = "b.company_id=? AND b.branch_id=? AND " + condAdmin + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 
 	shiftDateExpr := "DATE(b.start_time)"
 	shiftArgs := make([]interface{}, 0, 1)
@@ -350,11 +416,6 @@ func (r *ReportRepository) AdminsReport(ctx context.Context, from, to time.Time,
         LEFT JOIN payment_types pt ON b.payment_type_id = pt.id
         WHERE %s
     ),
-    shifts AS (
-        SELECT user_id, COUNT(DISTINCT shift_date) AS shifts
-        FROM filtered_bookings
-        GROUP BY user_id
-    ),
     set_hookahs AS (
         SELECT si.price_set_id AS set_id,
                SUM(CASE WHEN LOWER(c2.name) LIKE '%%кальян%%' THEN si.quantity ELSE 0 END) AS hookah_qty
@@ -365,30 +426,28 @@ func (r *ReportRepository) AdminsReport(ctx context.Context, from, to time.Time,
         WHERE ps.company_id = ? AND ps.branch_id = ?
         GROUP BY si.price_set_id
     ),
-    items AS (
-        SELECT fb.user_id,
+    daily AS (
+        SELECT fb.user_id, fb.shift_date,
                SUM(CASE WHEN pi.is_set = 0 AND LOWER(categories.name) LIKE '%%кальян%%' THEN bi.quantity ELSE 0 END) AS hookah_qty,
                SUM(CASE WHEN pi.is_set = 0 AND LOWER(categories.name) LIKE '%%кальян%%' THEN bi.price * bi.quantity * (1 - fb.hold_percent/100) ELSE 0 END) AS hookah_rev,
                SUM(CASE WHEN pi.is_set = 1 THEN bi.quantity ELSE 0 END) AS set_qty,
                SUM(CASE WHEN pi.is_set = 1 THEN bi.price * bi.quantity * (1 - fb.hold_percent/100) ELSE 0 END) AS set_rev,
                SUM(CASE WHEN pi.is_set = 1 THEN bi.quantity * COALESCE(sh.hookah_qty, 0) ELSE 0 END) AS set_hookah_qty
         FROM filtered_bookings fb
-        JOIN booking_items bi ON fb.id = bi.booking_id
-        JOIN price_items pi ON bi.item_id = pi.id
+        LEFT JOIN booking_items bi ON fb.id = bi.booking_id AND bi.status <> 'voided'
+        LEFT JOIN price_items pi ON bi.item_id = pi.id
         LEFT JOIN categories ON pi.category_id = categories.id
         LEFT JOIN set_hookahs sh ON pi.id = sh.set_id
-        GROUP BY fb.user_id
+        GROUP BY fb.user_id, fb.shift_date
     )
-    SELECT u.id, u.name,
-           COALESCE(s.shifts, 0) AS shifts,
-           COALESCE(items.hookah_qty, 0) + COALESCE(items.set_hookah_qty, 0) AS hookah_qty,
-           COALESCE(items.set_qty, 0) AS set_qty,
-           COALESCE(items.hookah_rev, 0) AS hookah_rev,
-           COALESCE(items.set_rev, 0) AS set_rev,
+    SELECT u.id, u.name, d.shift_date,
+           COALESCE(d.hookah_qty, 0) + COALESCE(d.set_hookah_qty, 0) AS hookah_qty,
+           COALESCE(d.set_qty, 0) AS set_qty,
+           COALESCE(d.hookah_rev, 0) AS hookah_rev,
+           COALESCE(d.set_rev, 0) AS set_rev,
            u.salary_shift, u.salary_hookah, u.hookah_salary_type, u.salary_bar
     FROM users u
-    LEFT JOIN shifts s ON u.id = s.user_id
-    LEFT JOIN items ON u.id = items.user_id
+    LEFT JOIN daily d ON u.id = d.user_id
     WHERE u.role = 'admin' AND u.company_id=? AND u.branch_id=?`, shiftDateExpr, condAdmin)
 	args := append([]interface{}{}, shiftArgs...)
 	args = append(args, companyID, branchID)
@@ -400,8 +459,12 @@ func (r *ReportRepository) AdminsReport(ctx context.Context, from, to time.Time,
 		args = append(args, userID)
 	}
 	query += `
-    GROUP BY u.id, u.name, s.shifts, items.hookah_qty, items.set_hookah_qty, items.set_qty, items.hookah_rev, items.set_rev, u.salary_shift, u.salary_hookah, u.hookah_salary_type, u.salary_bar
-    ORDER BY u.name`
+    ORDER BY u.name, u.id, d.shift_date`
+
+	rates, err := r.salaryRates(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -410,65 +473,154 @@ func (r *ReportRepository) AdminsReport(ctx context.Context, from, to time.Time,
 	defer rows.Close()
 
 	var report models.AdminsReport
+	var current *adminSalary
+	flush := func() {
+		if current != nil {
+			report.Admins = append(report.Admins, current.row())
+		}
+	}
 	for rows.Next() {
 		var (
 			id           int
 			name         string
-			shifts       int
+			shiftDate    sql.NullTime
 			hookahsFloat float64
 			setsFloat    float64
 			hookahRev    float64
 			setRev       float64
-			shiftSalary  int
-			hookahValue  float64
-			hookahType   string
-			setPercent   float64
+			fallback     models.UserSalaryRate
 		)
-		if err := rows.Scan(&id, &name, &shifts, &hookahsFloat, &setsFloat, &hookahRev, &setRev, &shiftSalary, &hookahValue, &hookahType, &setPercent); err != nil {
+		if err := rows.Scan(&id, &name, &shiftDate, &hookahsFloat, &setsFloat, &hookahRev, &setRev,
+			&fallback.SalaryShift, &fallback.SalaryHookah, &fallback.HookahSalaryType, &fallback.SalaryBar); err != nil {
 			return nil, err
 		}
+		if current == nil || current.userID != id {
+			flush()
+			current = &adminSalary{userID: id, name: name, fallback: fallback}
+		}
+		if !shiftDate.Valid {
+			continue
+		}
+		rate := rateOn(rates[id], shiftDate.Time, fallback)
+		current.add(rate, hookahsFloat, setsFloat, hookahRev, setRev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	flush()
 
-		hookahs := int(math.Round(hookahsFloat))
-		sets := int(math.Round(setsFloat))
-		shiftTotal := shifts * shiftSalary
-		var hookahTotal int
-		if hookahType == "percent" {
-			hookahTotal = int(math.Round(hookahRev * hookahValue / 100))
-		} else {
-			hookahTotal = int(math.Round(float64(hookahs) * hookahValue))
+	return &report, nil
+
+}
+
+// salaryRates loads salary history of all users in the branch ordered by
+// effective date so the rate valid on a specific day can be picked.
+func (r *ReportRepository) salaryRates(ctx context.Context, companyID, branchID int) (map[int][]models.UserSalaryRate, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, salary_shift, salary_hookah, hookah_salary_type, salary_bar, effective_from
+        FROM user_salary_rates WHERE company_id=? AND branch_id=? ORDER BY user_id, effective_from, id`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[int][]models.UserSalaryRate)
+	for rows.Next() {
+		var rate models.UserSalaryRate
+		if err := rows.Scan(&rate.ID, &rate.UserID, &rate.SalaryShift, &rate.SalaryHookah, &rate.HookahSalaryType, &rate.SalaryBar, &rate.EffectiveFrom); err != nil {
+			return nil, err
+		}
+		result[rate.UserID] = append(result[rate.UserID], rate)
+	}
+	return result, rows.Err()
+}
+
+// rateOn returns the last rate that became effective on or before day. When
+// the user has no history the current values from the users table are used.
+func rateOn(rates []models.UserSalaryRate, day time.Time, fallback models.UserSalaryRate) models.UserSalaryRate {
+	dayKey := day.Format("2006-01-02")
+	found := fallback
+	for _, rate := range rates {
+		if rate.EffectiveFrom.Format("2006-01-02") > dayKey {
+			break
 		}
-		setTotal := int(math.Round(setRev * setPercent / 100))
-		salary := shiftTotal + hookahTotal + setTotal
+		found = rate
+	}
+	return found
+}
 
+// adminSalary accumulates salary of one admin split by the rates that were
+// valid during the period.
+type adminSalary struct {
+	userID   int
+	name     string
+	fallback models.UserSalaryRate
+	segments []*salarySegment
+}
+
+type salarySegment struct {
+	rate      models.UserSalaryRate
+	shifts    int
+	hookahs   float64
+	sets      float64
+	hookahRev float64
+	setRev    float64
+}
+
+func (a *adminSalary) add(rate models.UserSalaryRate, hookahs, sets, hookahRev, setRev float64) {
+	var seg *salarySegment
+	if n := len(a.segments); n > 0 && a.segments[n-1].rate.ID == rate.ID {
+		seg = a.segments[n-1]
+	} else {
+		seg = &salarySegment{rate: rate}
+		a.segments = append(a.segments, seg)
+	}
+	seg.shifts++
+	seg.hookahs += hookahs
+	seg.sets += sets
+	seg.hookahRev += hookahRev
+	seg.setRev += setRev
+}
+
+func (a *adminSalary) row() models.AdminReportRow {
+	row := models.AdminReportRow{Name: a.name}
+	segments := a.segments
+	if len(segments) == 0 {
+		segments = []*salarySegment{{rate: a.fallback}}
+	}
+	var hookahsTotal, setsTotal float64
+	var details []string
+	for _, seg := range segments {
+		hookahs := int(math.Round(seg.hookahs))
+		shiftTotal := seg.shifts * seg.rate.SalaryShift
+		var hookahTotal int
 		var hookahDetail string
-		if hookahType == "percent" {
-			hookahDetail = fmt.Sprintf("%d₸ × %.0f%% = %d₸", int(math.Round(hookahRev)), hookahValue, hookahTotal)
+		if seg.rate.HookahSalaryType == "percent" {
+			hookahTotal = int(math.Round(seg.hookahRev * seg.rate.SalaryHookah / 100))
+			hookahDetail = fmt.Sprintf("%d₸ × %.0f%% = %d₸", int(math.Round(seg.hookahRev)), seg.rate.SalaryHookah, hookahTotal)
 		} else {
-			hookahDetail = fmt.Sprintf("%d × %.0f₸ = %d₸", hookahs, hookahValue, hookahTotal)
+			hookahTotal = int(math.Round(float64(hookahs) * seg.rate.SalaryHookah))
+			hookahDetail = fmt.Sprintf("%d × %.0f₸ = %d₸", hookahs, seg.rate.SalaryHookah, hookahTotal)
 		}
-		detail := fmt.Sprintf("Смены: %d × %d = %d₸, кальяны: %s, сеты: %d₸ × %.0f%% = %d₸",
-			shifts, shiftSalary, shiftTotal,
+		setTotal := int(math.Round(seg.setRev * seg.rate.SalaryBar / 100))
+
+		row.Shifts += seg.shifts
+		row.Salary += shiftTotal + hookahTotal + setTotal
+		hookahsTotal += seg.hookahs
+		setsTotal += seg.sets
+		details = append(details, fmt.Sprintf("Смены: %d × %d = %d₸, кальяны: %s, сеты: %d₸ × %.0f%% = %d₸",
+			seg.shifts, seg.rate.SalaryShift, shiftTotal,
 			hookahDetail,
-			int(math.Round(setRev)), setPercent, setTotal)
-
-		report.Admins = append(report.Admins, models.AdminReportRow{
-			Name:         name,
-			Shifts:       shifts,
-			HookahsSold:  hookahs,
-			SetsSold:     sets,
-			Salary:       salary,
-			SalaryDetail: detail,
-		})
+			int(math.Round(seg.setRev)), seg.rate.SalaryBar, setTotal))
 	}
-
-	return &report, nil
-
+	row.HookahsSold = int(math.Round(hookahsTotal))
+	row.SetsSold = int(math.Round(setsTotal))
+	row.SalaryDetail = strings.Join(details, "; ")
+	return row
 }
 
 // --- SalesReport ---
 func (r *ReportRepository) SalesReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) (*models.SalesReport, error) {
 	condUser, userArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	condUser = "b.company_id=? AND b.branch_id=? AND " + condUser + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	condUser = "b.company_id=? AND b.branch_id=? AND " + condUser + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 	userQuery := fmt.Sprintf(`
        SELECT u.id, u.name,
               COUNT(DISTINCT DATE(b.start_time)) AS days,
@@ -518,7 +670,7 @@ func (r *ReportRepository) SalesReport(ctx context.Context, from, to time.Time,
        FROM bookings b
        JOIN users u ON b.user_id = u.id
        LEFT JOIN payment_types pt ON b.payment_type_id = pt.id
-       LEFT JOIN booking_items bi ON b.id = bi.booking_id
+       LEFT JOIN booking_items bi ON b.id = bi.booking_id AND bi.status <> 'voided'
        LEFT JOIN price_items pi ON bi.item_id = pi.id
        LEFT JOIN categories ON pi.category_id = categories.id
        WHERE %s AND u.company_id=? AND u.branch_id=?`, condUser)
@@ -573,7 +725,7 @@ func (r *ReportRepository) SalesReport(ctx context.Context, from, to time.Time,
 	condExp, expArgs := buildTimeCondition("e.date", from, to, tFrom, tTo)
 	condExp = "e.company_id=? AND e.branch_id=? AND " + condExp
 	expQuery := fmt.Sprintf(`
-       SELECT IFNULL(ec.name, IFNULL(rc.name, '')) as category, SUM(e.total)
+       SELECT IFNULL(ec.name, IFNULL(rc.name, '')) as category, SUM(e.total), SUM(LEAST(e.paid_amount, e.total))
        FROM expenses e
         LEFT JOIN expense_categories ec ON e.category_id = ec.id
         LEFT JOIN repair_categories rc ON e.repair_category_id = rc.id
@@ -586,18 +738,20 @@ func (r *ReportRepository) SalesReport(ctx context.Context, from, to time.Time,
 	}
 	defer expRows.Close()
 	var expenses []models.ExpenseTotal
-	var totalExp float64
+	var totalExp, paidExp float64
 	for expRows.Next() {
 		var e models.ExpenseTotal
-		if err := expRows.Scan(&e.Title, &e.Total); err != nil {
+		var paid float64
+		if err := expRows.Scan(&e.Title, &e.Total, &paid); err != nil {
 			return nil, err
 		}
 		expenses = append(expenses, e)
 		totalExp += e.Total
+		paidExp += paid
 	}
 
 	condCat2, catArgs2 := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	condCat2 = "b.company_id=? AND b.branch_id=? AND " + condCat2 + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	condCat2 = "b.company_id=? AND b.branch_id=? AND " + condCat2 + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b") + notVoided("bi")
 	catQuery2 := fmt.Sprintf(`
         SELECT categories.name, SUM((bi.price * (1 - bi.discount / 100)) * (1 - IFNULL(pt.hold_percent,0)/100))
         FROM booking_items bi
@@ -631,19 +785,24 @@ func (r *ReportRepository) SalesReport(ctx context.Context, from, to time.Time,
 
 	// Income by payment type
 	payCond, payArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	payCond = "b.company_id=? AND b.branch_id=? AND " + payCond + " AND b.payment_status <> 'UNPAID'"
+	payCond = "b.company_id=? AND b.branch_id=? AND " + payCond + " AND b.payment_status <> 'UNPAID'" + notCounted("b")
 	payQuery := fmt.Sprintf(`
-       SELECT IFNULL(pt.name,''), SUM(bp.amount * (1 - IFNULL(pt.hold_percent,0)/100))
+       SELECT CASE bp.method
+                  WHEN '`+models.PaymentMethodPass+`' THEN 'Абонемент'
+                  WHEN '`+models.PaymentMethodCertificate+`' THEN 'Сертификат'
+                  WHEN '`+models.PaymentMethodWallet+`' THEN 'Счет клиента'
+                  ELSE IFNULL(pt.name,'') END AS pay_name,
+              SUM(bp.amount * (1 - IFNULL(pt.hold_percent,0)/100))
        FROM bookings b
        LEFT JOIN booking_payments bp ON b.id = bp.booking_id AND b.company_id = bp.company_id AND b.branch_id = bp.branch_id
        LEFT JOIN payment_types pt ON bp.payment_type_id = pt.id
-       WHERE %s AND bp.payment_type_id <> 0`, payCond)
+       WHERE %s AND (bp.payment_type_id <> 0 OR bp.method <> '')`, payCond)
 	payArgs = append([]interface{}{companyID, branchID}, payArgs...)
 	if userID > 0 {
 		payQuery += " AND b.user_id = ?"
 		payArgs = append(payArgs, userID)
 	}
-	payQuery += ` GROUP BY pt.name`
+	payQuery += ` GROUP BY pay_name`
 	payRows, err := r.db.QueryContext(ctx, payQuery, payArgs...)
 	if err != nil {
 		return nil, err
@@ -658,25 +817,296 @@ func (r *ReportRepository) SalesReport(ctx context.Context, from, to time.Time,
 		payIncome = append(payIncome, inc)
 	}
 
+	// Депозиты по предварительным броням
+	depCond, depArgs := buildTimeCondition("bp.created_at", from, to, tFrom, tTo)
+	depQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(bp.amount),0)
+       FROM booking_payments bp
+       JOIN bookings b ON bp.booking_id = b.id
+       WHERE bp.company_id=? AND bp.branch_id=? AND bp.is_deposit = 1 AND %s`, depCond)
+	depArgs = append([]interface{}{companyID, branchID}, depArgs...)
+	if userID > 0 {
+		depQuery += " AND b.user_id = ?"
+		depArgs = append(depArgs, userID)
+	}
+	var depositIncome float64
+	_ = r.db.QueryRowContext(ctx, depQuery, depArgs...).Scan(&depositIncome)
+
+	// Удержанные депозиты: возвраты сначала покрывают обычные оплаты брони
+	forfCond, forfArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
+	forfQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(GREATEST(d.amount - GREATEST(IFNULL(rf.amount,0) - IF(b.payment_status <> 'UNPAID', IFNULL(p.amount,0), 0), 0), 0)),0)
+       FROM bookings b
+       JOIN (SELECT booking_id, SUM(amount) AS amount FROM booking_payments WHERE is_deposit = 1 GROUP BY booking_id) d ON d.booking_id = b.id
+       LEFT JOIN (SELECT booking_id, SUM(amount) AS amount FROM booking_payments WHERE is_deposit = 0 GROUP BY booking_id) p ON p.booking_id = b.id
+       LEFT JOIN (SELECT booking_id, SUM(amount) AS amount FROM booking_refunds GROUP BY booking_id) rf ON rf.booking_id = b.id
+       WHERE b.company_id=? AND b.branch_id=? AND %s AND b.status IN (`+cancelledStatuses+`)`, forfCond)
+	forfArgs = append([]interface{}{companyID, branchID}, forfArgs...)
+	if userID > 0 {
+		forfQuery += " AND b.user_id = ?"
+		forfArgs = append(forfArgs, userID)
+	}
+	var forfeited float64
+	_ = r.db.QueryRowContext(ctx, forfQuery, forfArgs...).Scan(&forfeited)
+
+	// Продажи абонементов
+	passCond, passArgs := buildTimeCondition("cp.sold_at", from, to, tFrom, tTo)
+	passQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(cp.price * (1 - IFNULL(pt.hold_percent,0)/100)),0)
+       FROM client_passes cp
+       LEFT JOIN payment_types pt ON cp.payment_type_id = pt.id
+       WHERE cp.company_id=? AND cp.branch_id=? AND %s`, passCond)
+	passArgs = append([]interface{}{companyID, branchID}, passArgs...)
+	if userID > 0 {
+		passQuery += " AND cp.user_id = ?"
+		passArgs = append(passArgs, userID)
+	}
+	var passSales float64
+	_ = r.db.QueryRowContext(ctx, passQuery, passArgs...).Scan(&passSales)
+
+	// Часы, оплаченные абонементами, в бронях из дохода по категориям
+	redCond, redArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
+	redCond = "b.company_id=? AND b.branch_id=? AND " + redCond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
+	redQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(bp.amount),0)
+       FROM booking_payments bp
+       JOIN bookings b ON bp.booking_id = b.id
+       WHERE %s AND bp.method = '`+models.PaymentMethodPass+`'`, redCond)
+	redArgs = append([]interface{}{companyID, branchID}, redArgs...)
+	if userID > 0 {
+		redQuery += " AND b.user_id = ?"
+		redArgs = append(redArgs, userID)
+	}
+	var passRedeemed float64
+	_ = r.db.QueryRowContext(ctx, redQuery, redArgs...).Scan(&passRedeemed)
+
+	// Продажи подарочных сертификатов и оплаты ими
+	certCond, certArgs := buildTimeCondition("g.sold_at", from, to, tFrom, tTo)
+	certQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(g.nominal * (1 - IFNULL(pt.hold_percent,0)/100)),0)
+       FROM gift_certificates g
+       LEFT JOIN payment_types pt ON g.payment_type_id = pt.id
+       WHERE g.company_id=? AND g.branch_id=? AND %s`, certCond)
+	certArgs = append([]interface{}{companyID, branchID}, certArgs...)
+	if userID > 0 {
+		certQuery += " AND g.user_id = ?"
+		certArgs = append(certArgs, userID)
+	}
+	var certSales float64
+	_ = r.db.QueryRowContext(ctx, certQuery, certArgs...).Scan(&certSales)
+
+	certRedCond, certRedArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
+	certRedCond = "b.company_id=? AND b.branch_id=? AND " + certRedCond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
+	certRedQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(bp.amount),0)
+       FROM booking_payments bp
+       JOIN bookings b ON bp.booking_id = b.id
+       WHERE %s AND bp.method = '`+models.PaymentMethodCertificate+`'`, certRedCond)
+	certRedArgs = append([]interface{}{companyID, branchID}, certRedArgs...)
+	if userID > 0 {
+		certRedQuery += " AND b.user_id = ?"
+		certRedArgs = append(certRedArgs, userID)
+	}
+	var certRedeemed float64
+	_ = r.db.QueryRowContext(ctx, certRedQuery, certRedArgs...).Scan(&certRedeemed)
+
+	// Пополнения счетов клиентов и оплаты с них
+	walletCond, walletArgs := buildTimeCondition("e.created_at", from, to, tFrom, tTo)
+	walletQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(e.amount * (1 - IFNULL(pt.hold_percent,0)/100)),0)
+       FROM client_wallet_entries e
+       LEFT JOIN payment_types pt ON e.payment_type_id = pt.id
+       WHERE e.company_id=? AND e.branch_id=? AND e.kind = '`+models.WalletTopUp+`' AND %s`, walletCond)
+	walletArgs = append([]interface{}{companyID, branchID}, walletArgs...)
+	if userID > 0 {
+		walletQuery += " AND e.user_id = ?"
+		walletArgs = append(walletArgs, userID)
+	}
+	var walletTopUps float64
+	_ = r.db.QueryRowContext(ctx, walletQuery, walletArgs...).Scan(&walletTopUps)
+
+	walletPayCond, walletPayArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
+	walletPayCond = "b.company_id=? AND b.branch_id=? AND " + walletPayCond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
+	walletPayQuery := fmt.Sprintf(`
+       SELECT COALESCE(SUM(bp.amount),0)
+       FROM booking_payments bp
+       JOIN bookings b ON bp.booking_id = b.id
+       WHERE %s AND bp.method = '`+models.PaymentMethodWallet+`'`, walletPayCond)
+	walletPayArgs = append([]interface{}{companyID, branchID}, walletPayArgs...)
+	if userID > 0 {
+		walletPayQuery += " AND b.user_id = ?"
+		walletPayArgs = append(walletPayArgs, userID)
+	}
+	var walletPaid float64
+	_ = r.db.QueryRowContext(ctx, walletPayQuery, walletPayArgs...).Scan(&walletPaid)
+
 	const taxPercent = 0
-	netProfit := totalInc*(1-taxPercent) - totalExp
+	// удержанный депозит остается доходом клуба, хотя сама бронь отменена.
+	// Деньги за абонемент, сертификат и на счет клиента получены заранее,
+	// поэтому оплаченное ими не считается доходом второй раз
+	netProfit := totalInc*(1-taxPercent) + forfeited + passSales - passRedeemed + certSales - certRedeemed + walletTopUps - walletPaid - totalExp
 
 	return &models.SalesReport{
-		Users:            users,
-		Expenses:         expenses,
-		IncomeByCategory: incomes,
-		IncomeByPayment:  payIncome,
-		TotalIncome:      totalInc,
-		TotalExpenses:    totalExp,
-		NetProfit:        netProfit,
+		Users:                  users,
+		Expenses:               expenses,
+		IncomeByCategory:       incomes,
+		IncomeByPayment:        payIncome,
+		TotalIncome:            totalInc,
+		TotalExpenses:          totalExp,
+		PaidExpenses:           paidExp,
+		UnpaidExpenses:         totalExp - paidExp,
+		NetProfit:              netProfit,
+		DepositIncome:          depositIncome,
+		ForfeitedDeposits:      forfeited,
+		PassSales:              passSales,
+		PassRedemptions:        passRedeemed,
+		CertificateSales:       certSales,
+		CertificateRedemptions: certRedeemed,
+		WalletTopUps:           walletTopUps,
+		WalletPayments:         walletPaid,
 	}, nil
 }
 
+// --- CertificatesReport ---
+// CertificatesReport lists gift certificates of the branch that still have
+// money on them. Balances of expired certificates are shown separately.
+func (r *ReportRepository) CertificatesReport(ctx context.Context, companyID, branchID int) (*models.CertificatesReport, error) {
+	rows, err := r.db.QueryContext(ctx, `
+       SELECT id, company_id, branch_id, code, nominal, balance, IFNULL(purchaser_client_id, 0), purchaser_name,
+              IFNULL(payment_type_id, 0), IFNULL(user_id, 0), sold_at, expires_at
+       FROM gift_certificates
+       WHERE company_id=? AND branch_id=? AND balance > 0
+       ORDER BY expires_at`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	now := time.Now()
+	res := &models.CertificatesReport{Certificates: []models.GiftCertificate{}}
+	for rows.Next() {
+		var g models.GiftCertificate
+		if err := rows.Scan(&g.ID, &g.CompanyID, &g.BranchID, &g.Code, &g.Nominal, &g.Balance, &g.PurchaserClientID, &g.PurchaserName,
+			&g.PaymentTypeID, &g.UserID, &g.SoldAt, &g.ExpiresAt); err != nil {
+			return nil, err
+		}
+		if !now.Before(g.ExpiresAt) {
+			res.ExpiredBalance += float64(g.Balance)
+			continue
+		}
+		g.Active = true
+		res.TotalOutstanding += float64(g.Balance)
+		res.Count++
+		res.Certificates = append(res.Certificates, g)
+	}
+	return res, rows.Err()
+}
+
+// --- WalletsReport ---
+// WalletsReport lists clients of the branch with money on account.
+func (r *ReportRepository) WalletsReport(ctx context.Context, companyID, branchID int) (*models.WalletsReport, error) {
+	rows, err := r.db.QueryContext(ctx, `
+       SELECT id, name, phone, wallet_balance
+       FROM clients
+       WHERE company_id=? AND branch_id=? AND wallet_balance <> 0
+       ORDER BY wallet_balance DESC`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := &models.WalletsReport{Clients: []models.ClientWalletLine{}}
+	for rows.Next() {
+		var l models.ClientWalletLine
+		if err := rows.Scan(&l.ClientID, &l.Name, &l.Phone, &l.Balance); err != nil {
+			return nil, err
+		}
+		res.TotalOutstanding += l.Balance
+		res.Count++
+		res.Clients = append(res.Clients, l)
+	}
+	return res, rows.Err()
+}
+
+// --- PayablesReport ---
+// PayablesReport groups unpaid expenses by days overdue relative to asOf.
+func (r *ReportRepository) PayablesReport(ctx context.Context, asOf time.Time, companyID, branchID int) (*models.PayablesReport, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT e.counterparty, e.due_date, e.total - e.paid_amount
+        FROM expenses e
+        WHERE e.company_id=? AND e.branch_id=? AND e.paid = 0 AND e.paid_amount < e.total`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := models.PayablesReport{
+		Buckets: []models.AgingBucket{
+			{Label: "Срок не наступил"},
+			{Label: "1-30"},
+			{Label: "31-60"},
+			{Label: "61-90"},
+			{Label: "90+"},
+		},
+	}
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	byCounterparty := make(map[string]*models.CounterpartyDebt)
+	var order []string
+	for rows.Next() {
+		var (
+			counterparty string
+			due          sql.NullTime
+			amount       float64
+		)
+		if err := rows.Scan(&counterparty, &due, &amount); err != nil {
+			return nil, err
+		}
+		overdueDays := 0
+		if due.Valid {
+			dueDay := time.Date(due.Time.Year(), due.Time.Month(), due.Time.Day(), 0, 0, 0, 0, time.UTC)
+			overdueDays = int(day.Sub(dueDay).Hours() / 24)
+		}
+		idx := 0
+		switch {
+		case overdueDays <= 0:
+			idx = 0
+		case overdueDays <= 30:
+			idx = 1
+		case overdueDays <= 60:
+			idx = 2
+		case overdueDays <= 90:
+			idx = 3
+		default:
+			idx = 4
+		}
+		report.Buckets[idx].Count++
+		report.Buckets[idx].Amount += amount
+		report.TotalOutstanding += amount
+
+		cp, ok := byCounterparty[counterparty]
+		if !ok {
+			cp = &models.CounterpartyDebt{Counterparty: counterparty}
+			byCounterparty[counterparty] = cp
+			order = append(order, counterparty)
+		}
+		cp.Amount += amount
+		if overdueDays > 0 {
+			cp.Overdue += amount
+			report.TotalOverdue += amount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, name := range order {
+		report.Counterparties = append(report.Counterparties, *byCounterparty[name])
+	}
+	return &report, nil
+}
+
 // --- AnalyticsReport ---
 func (r *ReportRepository) AnalyticsReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) (*models.AnalyticsReport, error) {
 	// Daily revenue
 	condDaily, dailyArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-	condDaily = "b.company_id=? AND b.branch_id=? AND " + condDaily + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+	condDaily = "b.company_id=? AND b.branch_id=? AND " + condDaily + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 	dailyQuery := fmt.Sprintf(`
        SELECT DATE(b.start_time), SUM(b.total_amount * (1 - IFNULL(pt.hold_percent,0)/100)) FROM bookings b
        LEFT JOIN payment_types pt ON b.payment_type_id = pt.id
@@ -700,7 +1130,7 @@ func (r *ReportRepository) AnalyticsReport(ctx context.Context, from, to time.Ti
 
 	// Hourly load
 	condHourly, hourlyArgs := buildTimeCondition("start_time", from, to, tFrom, tTo)
-	condHourly = "company_id=? AND branch_id=? AND " + condHourly + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0"
+	condHourly = "company_id=? AND branch_id=? AND " + condHourly + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0" + notCounted("")
 	hourlyQuery := fmt.Sprintf(`
        SELECT HOUR(start_time), COUNT(*) FROM bookings
        WHERE %s`, condHourly)
@@ -723,7 +1153,7 @@ func (r *ReportRepository) AnalyticsReport(ctx context.Context, from, to time.Ti
 
 	// Category stats
 	condAnalCat, catArgs := buildTimeCondition("booking_items.created_at", from, to, tFrom, tTo)
-	condAnalCat = "bookings.company_id=? AND bookings.branch_id=? AND " + condAnalCat + " AND bookings.payment_status <> 'UNPAID' AND bookings.payment_type_id <> 0"
+	condAnalCat = "bookings.company_id=? AND bookings.branch_id=? AND " + condAnalCat + " AND bookings.payment_status <> 'UNPAID' AND bookings.payment_type_id <> 0" + notCounted("bookings") + notVoided("booking_items")
 	catQuery := fmt.Sprintf(`
        SELECT categories.name, SUM(booking_items.quantity), SUM(booking_items.price * (1 - IFNULL(pt.hold_percent,0)/100))
        FROM booking_items
@@ -757,14 +1187,49 @@ func (r *ReportRepository) AnalyticsReport(ctx context.Context, from, to time.Ti
 		DailyRevenue:  daily,
 		HourlyLoad:    hourly,
 		CategoryStats: cats,
+		PrepTimes:     r.prepTimes(ctx, from, to, tFrom, tTo, userID, companyID, branchID),
 	}, nil
 }
 
+// prepTimes returns average waiting and preparation time of served order
+// tickets per station.
+func (r *ReportRepository) prepTimes(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) []models.PrepStat {
+	cond, args := buildTimeCondition("t.created_at", from, to, tFrom, tTo)
+	cond = "t.company_id=? AND t.branch_id=? AND " + cond + " AND t.status = '" + models.TicketServed + "'"
+	args = append([]interface{}{companyID, branchID}, args...)
+	query := fmt.Sprintf(`
+       SELECT s.name, COUNT(*),
+              COALESCE(AVG(TIMESTAMPDIFF(SECOND, t.created_at, t.started_at)),0) / 60,
+              COALESCE(AVG(TIMESTAMPDIFF(SECOND, t.started_at, t.served_at)),0) / 60
+       FROM order_tickets t
+       JOIN stations s ON t.station_id = s.id
+       WHERE %s`, cond)
+	if userID > 0 {
+		query += " AND t.user_id = ?"
+		args = append(args, userID)
+	}
+	query += " GROUP BY s.id, s.name ORDER BY s.name"
+	res := []models.PrepStat{}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return res
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.PrepStat
+		if err := rows.Scan(&p.Station, &p.Tickets, &p.AvgWaitMinutes, &p.AvgPrepMinutes); err != nil {
+			return res
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
 // --- DiscountsReport ---
 func (r *ReportRepository) DiscountsReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) (*models.DiscountsReport, error) {
 	var total, count, avg int
 	condSum, sumArgs := buildTimeCondition("start_time", from, to, tFrom, tTo)
-	condSum = "company_id=? AND branch_id=? AND " + condSum + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0"
+	condSum = "company_id=? AND branch_id=? AND " + condSum + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0" + notCounted("")
 	sumQuery := fmt.Sprintf(`
        SELECT COALESCE(SUM(discount),0), COUNT(*), COALESCE(AVG(discount),0)
        FROM bookings
@@ -777,7 +1242,7 @@ func (r *ReportRepository) DiscountsReport(ctx context.Context, from, to time.Ti
 	_ = r.db.QueryRowContext(ctx, sumQuery, sumArgs...).Scan(&total, &count, &avg)
 
 	condReason, reasonArgs := buildTimeCondition("start_time", from, to, tFrom, tTo)
-	condReason = "company_id=? AND branch_id=? AND " + condReason + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0"
+	condReason = "company_id=? AND branch_id=? AND " + condReason + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0" + notCounted("")
 	reasonQuery := fmt.Sprintf(`
        SELECT discount_reason, COUNT(*), SUM(discount), COALESCE(AVG(discount),0)
        FROM bookings
@@ -801,7 +1266,7 @@ func (r *ReportRepository) DiscountsReport(ctx context.Context, from, to time.Ti
 	}
 
 	condDist, distArgs := buildTimeCondition("start_time", from, to, tFrom, tTo)
-	condDist = "company_id=? AND branch_id=? AND " + condDist + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0"
+	condDist = "company_id=? AND branch_id=? AND " + condDist + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0" + notCounted("")
 	distQuery := fmt.Sprintf(`
        SELECT discount, COUNT(*) FROM bookings
        WHERE discount > 0 AND %s`, condDist)
@@ -825,7 +1290,7 @@ func (r *ReportRepository) DiscountsReport(ctx context.Context, from, to time.Ti
 
 	// Retrieve all orders with a discount within the period
 	condOrders, orderArgs := buildTimeCondition("start_time", from, to, tFrom, tTo)
-	condOrders = "company_id=? AND branch_id=? AND " + condOrders + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0"
+	condOrders = "company_id=? AND branch_id=? AND " + condOrders + " AND payment_status <> 'UNPAID' AND payment_type_id <> 0" + notCounted("")
 	orderQuery := fmt.Sprintf(`
        SELECT id, client_id, table_id, user_id, start_time, end_time, note,
                discount, discount_reason, total_amount, bonus_used,
@@ -873,9 +1338,77 @@ func (r *ReportRepository) DiscountsReport(ctx context.Context, from, to time.Ti
 		TopReasons:        reasons,
 		DistributionBySum: dist,
 		Orders:            orders,
+		Cancellations:     r.cancellations(ctx, from, to, tFrom, tTo, userID, companyID, branchID),
+		ByPromotion:       r.promotionDiscounts(ctx, from, to, tFrom, tTo, userID, companyID, branchID),
 	}, nil
 }
 
+// promotionDiscounts sums discounts given by each promotion, count is the
+// number of bookings which got it.
+func (r *ReportRepository) promotionDiscounts(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) []models.PromotionRow {
+	res := []models.PromotionRow{}
+	cond, condArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
+	cond = "b.company_id=? AND b.branch_id=? AND " + cond + notCounted("b")
+	args := append([]interface{}{companyID, branchID}, condArgs...)
+	if userID > 0 {
+		cond += " AND b.user_id = ?"
+		args = append(args, userID)
+	}
+	query := fmt.Sprintf(`
+       SELECT p.id, p.name, COUNT(DISTINCT b.id), COALESCE(SUM(bp.amount),0)
+       FROM booking_promotions bp
+       JOIN bookings b ON b.id = bp.booking_id
+       JOIN promotions p ON p.id = bp.promotion_id
+       WHERE %s
+       GROUP BY p.id, p.name
+       ORDER BY SUM(bp.amount) DESC`, cond)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return res
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row models.PromotionRow
+		if err := rows.Scan(&row.PromotionID, &row.Name, &row.Count, &row.Amount); err != nil {
+			continue
+		}
+		res = append(res, row)
+	}
+	return res
+}
+
+// tableOccupiedHours sums hours a table was in use. Moved and merged
+// bookings are counted by their table history, others by booking time.
+func (r *ReportRepository) tableOccupiedHours(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID, tableID int) float64 {
+	cond, args := buildTimeCondition("s.start_time", from, to, tFrom, tTo)
+	cond = "s.company_id=? AND s.branch_id=? AND s.table_id=? AND " + cond + notCounted("b")
+	args = append([]interface{}{companyID, branchID, tableID}, args...)
+	query := fmt.Sprintf(`SELECT COALESCE(SUM(TIMESTAMPDIFF(MINUTE, s.start_time, s.end_time)),0)
+        FROM booking_table_segments s
+        JOIN bookings b ON s.booking_id = b.id
+        WHERE %s`, cond)
+	if userID > 0 {
+		query += " AND b.user_id = ?"
+		args = append(args, userID)
+	}
+	var segMinutes float64
+	_ = r.db.QueryRowContext(ctx, query, args...).Scan(&segMinutes)
+
+	condB, argsB := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
+	condB = "b.company_id=? AND b.branch_id=? AND b.table_id=? AND " + condB + notCounted("b")
+	argsB = append([]interface{}{companyID, branchID, tableID}, argsB...)
+	queryB := fmt.Sprintf(`SELECT COALESCE(SUM(TIMESTAMPDIFF(MINUTE, b.start_time, b.end_time)),0)
+        FROM bookings b
+        WHERE %s AND NOT EXISTS (SELECT 1 FROM booking_table_segments s WHERE s.booking_id = b.id)`, condB)
+	if userID > 0 {
+		queryB += " AND b.user_id = ?"
+		argsB = append(argsB, userID)
+	}
+	var bookingMinutes float64
+	_ = r.db.QueryRowContext(ctx, queryB, argsB...).Scan(&bookingMinutes)
+	return (segMinutes + bookingMinutes) / 60
+}
+
 func (r *ReportRepository) TablesReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) ([]models.TableReport, error) {
 	var workFrom, workTo string
 	_ = r.db.QueryRowContext(ctx, `SELECT work_time_from, work_time_to FROM settings WHERE company_id=? AND branch_id=? LIMIT 1`, companyID, branchID).Scan(&workFrom, &workTo)
@@ -906,7 +1439,7 @@ func (r *ReportRepository) TablesReport(ctx context.Context, from, to time.Time,
 		}
 
 		cond, condArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-		cond = "b.company_id=? AND b.branch_id=? AND b.table_id=? AND " + cond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+		cond = "b.company_id=? AND b.branch_id=? AND b.table_id=? AND " + cond + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 		args := append([]interface{}{companyID, branchID, tID}, condArgs...)
 		query := fmt.Sprintf(`SELECT COALESCE(SUM(b.total_amount),0), COALESCE(SUM(b.total_amount * (1 - IFNULL(pt.hold_percent,0)/100)),0), COALESCE(COUNT(DISTINCT b.client_id) + SUM(CASE WHEN b.client_id IS NULL THEN 1 ELSE 0 END),0), COALESCE(ROUND(AVG(b.total_amount * (1 - IFNULL(pt.hold_percent,0)/100))),0), COUNT(*) FROM bookings b LEFT JOIN payment_types pt ON b.payment_type_id = pt.id WHERE %s`, cond)
 		if userID > 0 {
@@ -918,13 +1451,14 @@ func (r *ReportRepository) TablesReport(ctx context.Context, from, to time.Time,
 		if err := r.db.QueryRowContext(ctx, query, args...).Scan(&total, &totalRev, &clients, &avgCheck, &bookingsCount); err != nil {
 			return nil, err
 		}
+		occupied := r.tableOccupiedHours(ctx, from, to, tFrom, tTo, userID, companyID, branchID, tID)
 		loadPercent := 0.0
 		if capacity > 0 {
-			loadPercent = float64(bookingsCount) * 100 / capacity
+			loadPercent = occupied * 100 / capacity
 		}
 
 		condCost, costArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-		condCost = "b.company_id=? AND b.branch_id=? AND b.table_id=? AND " + condCost + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+		condCost = "b.company_id=? AND b.branch_id=? AND b.table_id=? AND " + condCost + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b") + notVoided("bi")
 		costArgs = append([]interface{}{companyID, branchID, tID}, costArgs...)
 		costQuery := fmt.Sprintf(`SELECT COALESCE(SUM(
             bi.price * (1 - bi.discount / 100) * (1 - IFNULL(pt.hold_percent,0)/100) -
@@ -944,7 +1478,7 @@ func (r *ReportRepository) TablesReport(ctx context.Context, from, to time.Time,
 		_ = r.db.QueryRowContext(ctx, costQuery, costArgs...).Scan(&totalCost)
 
 		condPay, payArgs := buildTimeCondition("b.start_time", from, to, tFrom, tTo)
-		condPay = "b.company_id=? AND b.branch_id=? AND b.table_id=? AND " + condPay + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0"
+		condPay = "b.company_id=? AND b.branch_id=? AND b.table_id=? AND " + condPay + " AND b.payment_status <> 'UNPAID' AND b.payment_type_id <> 0" + notCounted("b")
 		payArgs = append([]interface{}{companyID, branchID, tID}, payArgs...)
 		payQuery := fmt.Sprintf(`SELECT IFNULL(pt.name,''), COALESCE(SUM(b.total_amount * (1 - IFNULL(pt.hold_percent,0)/100)),0)
             FROM bookings b
@@ -976,6 +1510,7 @@ func (r *ReportRepository) TablesReport(ctx context.Context, from, to time.Time,
 			TotalRevenue:      totalRev,
 			AvgCheck:          avgCheck,
 			LoadPercent:       loadPercent,
+			OccupiedHours:     occupied,
 			Visits:            clients,
 			PaymentTypeIncome: payStats,
 			TotalCost:         totalCost,
@@ -984,3 +1519,10 @@ func (r *ReportRepository) TablesReport(ctx context.Context, from, to time.Time,
 
 	return result, nil
 }
+
+// TableCount returns the number of tables in the branch.
+func (r *ReportRepository) TableCount(ctx context.Context, companyID, branchID int) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tables WHERE company_id=? AND branch_id=?`, companyID, branchID).Scan(&n)
+	return n, err
+}
@@ -17,14 +17,16 @@ func NewSettingsRepository(db *sql.DB) *SettingsRepository {
 func (r *SettingsRepository) Get(ctx context.Context, companyID, branchID int) (*models.Settings, error) {
 	// Получить текущие настройки + имя текущей платежной системы
 	query := `
-               SELECT s.id, s.payment_type, s.block_time, s.bonus_percent, s.work_time_from, s.work_time_to, s.tables_count, s.notification_time, s.company_id, s.branch_id
+               SELECT s.id, s.payment_type, s.block_time, s.bonus_percent, s.work_time_from, s.work_time_to, s.tables_count, s.notification_time,
+                      s.deposit_forfeit_percent, s.reservation_grace_minutes, s.deposit_refund_hours, s.cleanup_buffer_minutes, s.company_id, s.branch_id
                FROM settings s
                WHERE s.company_id=? AND s.branch_id=?
                LIMIT 1
         `
 	var s models.Settings
 	err := r.db.QueryRowContext(ctx, query, companyID, branchID).Scan(
-		&s.ID, &s.PaymentType, &s.BlockTime, &s.BonusPercent, &s.WorkTimeFrom, &s.WorkTimeTo, &s.TablesCount, &s.NotificationTime, &s.CompanyID, &s.BranchID,
+		&s.ID, &s.PaymentType, &s.BlockTime, &s.BonusPercent, &s.WorkTimeFrom, &s.WorkTimeTo, &s.TablesCount, &s.NotificationTime,
+		&s.DepositForfeitPercent, &s.ReservationGraceMinutes, &s.DepositRefundHours, &s.CleanupBufferMinutes, &s.CompanyID, &s.BranchID,
 	)
 	if err != nil {
 		return nil, err
@@ -72,19 +74,20 @@ func (r *SettingsRepository) Get(ctx context.Context, companyID, branchID int) (
 func (r *SettingsRepository) Update(ctx context.Context, s *models.Settings) error {
 	query := `
                UPDATE settings
-               SET payment_type = ?, block_time = ?, bonus_percent = ?, work_time_from = ?, work_time_to = ?, tables_count = ?, notification_time = ?
+               SET payment_type = ?, block_time = ?, bonus_percent = ?, work_time_from = ?, work_time_to = ?, tables_count = ?, notification_time = ?,
+                   deposit_forfeit_percent = ?, reservation_grace_minutes = ?, deposit_refund_hours = ?, cleanup_buffer_minutes = ?
                WHERE id = ? AND company_id=? AND branch_id=?
        `
-	_, err := r.db.ExecContext(ctx, query, s.PaymentType, s.BlockTime, s.BonusPercent, s.WorkTimeFrom, s.WorkTimeTo, s.TablesCount, s.NotificationTime, s.ID, s.CompanyID, s.BranchID)
+	_, err := r.db.ExecContext(ctx, query, s.PaymentType, s.BlockTime, s.BonusPercent, s.WorkTimeFrom, s.WorkTimeTo, s.TablesCount, s.NotificationTime, s.DepositForfeitPercent, s.ReservationGraceMinutes, s.DepositRefundHours, s.CleanupBufferMinutes, s.ID, s.CompanyID, s.BranchID)
 	return err
 }
 
 func (r *SettingsRepository) Create(ctx context.Context, s *models.Settings) (int, error) {
 	query := `
-               INSERT INTO settings (payment_type, block_time, bonus_percent, work_time_from, work_time_to, tables_count, notification_time, company_id, branch_id)
-               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+               INSERT INTO settings (payment_type, block_time, bonus_percent, work_time_from, work_time_to, tables_count, notification_time, deposit_forfeit_percent, reservation_grace_minutes, deposit_refund_hours, cleanup_buffer_minutes, company_id, branch_id)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
        `
-	res, err := r.db.ExecContext(ctx, query, s.PaymentType, s.BlockTime, s.BonusPercent, s.WorkTimeFrom, s.WorkTimeTo, s.TablesCount, s.NotificationTime, s.CompanyID, s.BranchID)
+	res, err := r.db.ExecContext(ctx, query, s.PaymentType, s.BlockTime, s.BonusPercent, s.WorkTimeFrom, s.WorkTimeTo, s.TablesCount, s.NotificationTime, s.DepositForfeitPercent, s.ReservationGraceMinutes, s.DepositRefundHours, s.CleanupBufferMinutes, s.CompanyID, s.BranchID)
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"psclub-crm/internal/models"
+)
+
+type StationRepository struct {
+	db *sql.DB
+}
+
+func NewStationRepository(db *sql.DB) *StationRepository {
+	return &StationRepository{db: db}
+}
+
+func (r *StationRepository) Create(ctx context.Context, s *models.Station) (int, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO stations (company_id, branch_id, name, created_at) VALUES (?, ?, ?, NOW())`, s.CompanyID, s.BranchID, s.Name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (r *StationRepository) GetAll(ctx context.Context, companyID, branchID int) ([]models.Station, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, company_id, branch_id, name FROM stations WHERE company_id=? AND branch_id=? ORDER BY id`, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.Station
+	for rows.Next() {
+		var s models.Station
+		if err := rows.Scan(&s.ID, &s.CompanyID, &s.BranchID, &s.Name); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+func (r *StationRepository) GetByID(ctx context.Context, id, companyID, branchID int) (*models.Station, error) {
+	var s models.Station
+	err := r.db.QueryRowContext(ctx, `SELECT id, company_id, branch_id, name FROM stations WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID).
+		Scan(&s.ID, &s.CompanyID, &s.BranchID, &s.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetByName returns nil if the branch has no station with the name.
+func (r *StationRepository) GetByName(ctx context.Context, name string, companyID, branchID int) (*models.Station, error) {
+	var s models.Station
+	err := r.db.QueryRowContext(ctx, `SELECT id, company_id, branch_id, name FROM stations WHERE name=? AND company_id=? AND branch_id=?`, name, companyID, branchID).
+		Scan(&s.ID, &s.CompanyID, &s.BranchID, &s.Name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *StationRepository) Update(ctx context.Context, s *models.Station) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE stations SET name=? WHERE id=? AND company_id=? AND branch_id=?`, s.Name, s.ID, s.CompanyID, s.BranchID)
+	return err
+}
+
+func (r *StationRepository) Delete(ctx context.Context, id, companyID, branchID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM stations WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID)
+	return err
+}
@@ -3,9 +3,12 @@ package repositories
 import (
 	"context"
 	"database/sql"
-	"time"
+
+	"psclub-crm/internal/models"
 )
 
+// TokenRepository keeps refresh tokens, every token is a session of the
+// user on one device.
 type TokenRepository struct {
 	db *sql.DB
 }
@@ -14,28 +17,91 @@ func NewTokenRepository(db *sql.DB) *TokenRepository {
 	return &TokenRepository{db: db}
 }
 
-func (r *TokenRepository) Save(ctx context.Context, userID int, hash string, exp time.Time) error {
-	query := `INSERT INTO refresh_tokens (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, NOW())`
-	_, err := r.db.ExecContext(ctx, query, userID, hash, exp)
-	return err
+// Create starts a session and returns its id.
+func (r *TokenRepository) Create(ctx context.Context, s *models.Session, hash string) (int, error) {
+	query := `INSERT INTO refresh_tokens (user_id, branch_id, token_hash, expires_at, device_name, device_id, ip, user_agent, created_at, last_used_at)
+              VALUES (?, ?, ?, ?, ?, NULLIF(?,0), ?, ?, NOW(), NOW())`
+	res, err := r.db.ExecContext(ctx, query, s.UserID, s.BranchID, hash, s.ExpiresAt, s.DeviceName, s.DeviceID, s.IP, s.UserAgent)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
 }
 
-func (r *TokenRepository) Delete(ctx context.Context, hash string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE token_hash=?`, hash)
+// Rotate gives the session a new token. The device name is kept when the
+// new one is empty.
+func (r *TokenRepository) Rotate(ctx context.Context, s *models.Session, hash string) error {
+	query := `UPDATE refresh_tokens
+              SET token_hash=?, expires_at=?, branch_id=?, device_name=IF(?='', device_name, ?), ip=?, user_agent=?, last_used_at=NOW()
+              WHERE id=? AND user_id=?`
+	_, err := r.db.ExecContext(ctx, query, hash, s.ExpiresAt, s.BranchID, s.DeviceName, s.DeviceName, s.IP, s.UserAgent, s.ID, s.UserID)
 	return err
 }
 
-func (r *TokenRepository) Get(ctx context.Context, hash string) (int, time.Time, error) {
-	query := `SELECT user_id, expires_at FROM refresh_tokens WHERE token_hash=?`
-	var userID int
-	var exp time.Time
-	err := r.db.QueryRowContext(ctx, query, hash).Scan(&userID, &exp)
+const sessionColumns = `id, user_id, IFNULL(branch_id,0), device_name, IFNULL(device_id,0), ip, user_agent, created_at, last_used_at, expires_at`
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	var s models.Session
+	var lastUsed sql.NullTime
+	if err := row.Scan(&s.ID, &s.UserID, &s.BranchID, &s.DeviceName, &s.DeviceID, &s.IP, &s.UserAgent, &s.CreatedAt, &lastUsed, &s.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		s.LastUsedAt = &lastUsed.Time
+	}
+	return &s, nil
+}
+
+// GetByHash returns the session of a refresh token.
+func (r *TokenRepository) GetByHash(ctx context.Context, hash string) (*models.Session, error) {
+	return scanSession(r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM refresh_tokens WHERE token_hash=?`, hash))
+}
+
+// GetByUser lists sessions of the user which are not expired, recently used
+// first.
+func (r *TokenRepository) GetByUser(ctx context.Context, userID int) ([]models.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM refresh_tokens
+        WHERE user_id=? AND expires_at > NOW() ORDER BY IFNULL(last_used_at, created_at) DESC`, userID)
 	if err != nil {
-		return 0, time.Time{}, err
+		return nil, err
 	}
-	return userID, exp, nil
+	defer rows.Close()
+	var list []models.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *s)
+	}
+	return list, rows.Err()
+}
+
+// Exists tells whether the session is still active.
+func (r *TokenRepository) Exists(ctx context.Context, userID, id int) (bool, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM refresh_tokens WHERE id=? AND user_id=? AND expires_at > NOW()`, id, userID).Scan(&n)
+	return n > 0, err
+}
+
+// DeleteByID ends one session of the user.
+func (r *TokenRepository) DeleteByID(ctx context.Context, userID, id int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE id=? AND user_id=?`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteByDevice ends all PIN sessions started on the device.
+func (r *TokenRepository) DeleteByDevice(ctx context.Context, deviceID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE device_id=?`, deviceID)
+	return err
 }
 
+// DeleteByUser ends all sessions of the user.
 func (r *TokenRepository) DeleteByUser(ctx context.Context, userID int) error {
 	_, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id=?`, userID)
 	return err
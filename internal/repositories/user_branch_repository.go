@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"psclub-crm/internal/models"
+)
+
+type UserBranchRepository struct {
+	db *sql.DB
+}
+
+func NewUserBranchRepository(db *sql.DB) *UserBranchRepository {
+	return &UserBranchRepository{db: db}
+}
+
+// Save grants the user access to the branch or changes the role there.
+func (r *UserBranchRepository) Save(ctx context.Context, ub *models.UserBranch) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO user_branches (user_id, company_id, branch_id, role) VALUES (?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE company_id=VALUES(company_id), role=VALUES(role)`,
+		ub.UserID, ub.CompanyID, ub.BranchID, ub.Role)
+	return err
+}
+
+// Get returns the access of the user to the branch or nil when there is none.
+func (r *UserBranchRepository) Get(ctx context.Context, userID, branchID int) (*models.UserBranch, error) {
+	var ub models.UserBranch
+	err := r.db.QueryRowContext(ctx, `
+        SELECT ub.user_id, ub.company_id, ub.branch_id, IFNULL(b.name,''), ub.role
+        FROM user_branches ub
+        LEFT JOIN branches b ON b.id = ub.branch_id
+        WHERE ub.user_id=? AND ub.branch_id=?`, userID, branchID).
+		Scan(&ub.UserID, &ub.CompanyID, &ub.BranchID, &ub.BranchName, &ub.Role)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ub, nil
+}
+
+func (r *UserBranchRepository) GetByUser(ctx context.Context, userID int) ([]models.UserBranch, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT ub.user_id, ub.company_id, ub.branch_id, IFNULL(b.name,''), ub.role
+        FROM user_branches ub
+        LEFT JOIN branches b ON b.id = ub.branch_id
+        WHERE ub.user_id=? ORDER BY ub.branch_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []models.UserBranch
+	for rows.Next() {
+		var ub models.UserBranch
+		if err := rows.Scan(&ub.UserID, &ub.CompanyID, &ub.BranchID, &ub.BranchName, &ub.Role); err != nil {
+			return nil, err
+		}
+		list = append(list, ub)
+	}
+	return list, rows.Err()
+}
+
+// Replace sets the branches the user has access to, the home branch is kept.
+func (r *UserBranchRepository) Replace(ctx context.Context, userID, homeBranchID int, list []models.UserBranch) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_branches WHERE user_id=? AND branch_id<>?`, userID, homeBranchID); err != nil {
+		return err
+	}
+	for _, ub := range list {
+		if ub.BranchID == homeBranchID {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_branches (user_id, company_id, branch_id, role) VALUES (?, ?, ?, ?)`,
+			userID, ub.CompanyID, ub.BranchID, ub.Role); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -16,7 +16,16 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// execer is what *sql.DB and *sql.Tx have in common for writes.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
 func (r *UserRepository) Create(ctx context.Context, u *models.User) (int, error) {
+	return insertUser(ctx, r.db, u)
+}
+
+func insertUser(ctx context.Context, db execer, u *models.User) (int, error) {
 	permissionsJSON, err := json.Marshal(u.Permissions)
 	if err != nil {
 		return 0, err
@@ -25,7 +34,7 @@ func (r *UserRepository) Create(ctx context.Context, u *models.User) (int, error
 	query := `
        INSERT INTO users (name, phone, password, company_id, branch_id, role, permissions, salary_hookah, hookah_salary_type, salary_bar, salary_shift, created_at, updated_at)
        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`
-	res, err := r.db.ExecContext(ctx, query,
+	res, err := db.ExecContext(ctx, query,
 		u.Name, u.Phone, u.Password, u.CompanyID, u.BranchID, u.Role, permissionsJSON,
 		u.SalaryHookah, u.HookahSalaryType, u.SalaryBar, u.SalaryShift)
 	if err != nil {
@@ -37,7 +46,7 @@ func (r *UserRepository) Create(ctx context.Context, u *models.User) (int, error
 }
 
 func (r *UserRepository) GetAll(ctx context.Context, companyID, branchID int) ([]models.User, error) {
-	query := `SELECT id, name, phone, password, company_id, branch_id, role, permissions, salary_hookah, hookah_salary_type, salary_bar, salary_shift, created_at, updated_at FROM users WHERE role != 'director' AND company_id=? AND branch_id=? ORDER BY id`
+	query := `SELECT id, name, phone, password, company_id, branch_id, role, permissions, salary_hookah, hookah_salary_type, salary_bar, salary_shift, created_at, updated_at FROM users WHERE role NOT IN ('director', 'owner') AND company_id=? AND branch_id=? ORDER BY id`
 	rows, err := r.db.QueryContext(ctx, query, companyID, branchID)
 	if err != nil {
 		return nil, err
@@ -147,6 +156,12 @@ func (r *UserRepository) Update(ctx context.Context, u *models.User) error {
 	return err
 }
 
+// SetPassword stores a new password hash of the user.
+func (r *UserRepository) SetPassword(ctx context.Context, id int, hash string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET password=?, updated_at=NOW() WHERE id=?`, hash, id)
+	return err
+}
+
 func (r *UserRepository) Delete(ctx context.Context, id, companyID, branchID int) error {
 	_, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id=? AND company_id=? AND branch_id=?`, id, companyID, branchID)
 	return err
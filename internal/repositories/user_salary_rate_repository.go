@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"psclub-crm/internal/models"
+)
+
+type UserSalaryRateRepository struct {
+	db *sql.DB
+}
+
+func NewUserSalaryRateRepository(db *sql.DB) *UserSalaryRateRepository {
+	return &UserSalaryRateRepository{db: db}
+}
+
+func (r *UserSalaryRateRepository) Create(ctx context.Context, rate *models.UserSalaryRate) (int, error) {
+	query := `INSERT INTO user_salary_rates (user_id, company_id, branch_id, salary_shift, salary_hookah, hookah_salary_type, salary_bar, effective_from, created_at)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())`
+	res, err := r.db.ExecContext(ctx, query, rate.UserID, rate.CompanyID, rate.BranchID, rate.SalaryShift, rate.SalaryHookah, rate.HookahSalaryType, rate.SalaryBar, rate.EffectiveFrom.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// GetByUser returns salary history of a user ordered from the newest rate to the oldest.
+func (r *UserSalaryRateRepository) GetByUser(ctx context.Context, userID, companyID, branchID int) ([]models.UserSalaryRate, error) {
+	query := `SELECT id, user_id, company_id, branch_id, salary_shift, salary_hookah, hookah_salary_type, salary_bar, effective_from, created_at
+              FROM user_salary_rates
+              WHERE user_id=? AND company_id=? AND branch_id=?
+              ORDER BY effective_from DESC, id DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []models.UserSalaryRate
+	for rows.Next() {
+		var rate models.UserSalaryRate
+		if err := rows.Scan(&rate.ID, &rate.UserID, &rate.CompanyID, &rate.BranchID, &rate.SalaryShift, &rate.SalaryHookah, &rate.HookahSalaryType, &rate.SalaryBar, &rate.EffectiveFrom, &rate.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, rate)
+	}
+	return result, nil
+}
+
+// DeleteOnDate removes the rate of a user that starts on the given date. It is used
+// when a rate for the same day is re-entered so that only one rate per day stays.
+func (r *UserSalaryRateRepository) DeleteOnDate(ctx context.Context, userID, companyID, branchID int, date string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_salary_rates WHERE user_id=? AND company_id=? AND branch_id=? AND effective_from=?`,
+		userID, companyID, branchID, date)
+	return err
+}
@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"psclub-crm/internal/models"
+)
+
+// WalletRepository keeps client wallet balances together with the journal
+// of their changes.
+type WalletRepository struct {
+	db *sql.DB
+}
+
+func NewWalletRepository(db *sql.DB) *WalletRepository {
+	return &WalletRepository{db: db}
+}
+
+// change moves a client balance by e.Amount and writes the journal entry.
+// A negative change is not applied when the balance is not enough, false is
+// returned then.
+func (r *WalletRepository) change(ctx context.Context, tx *sql.Tx, e *models.WalletEntry) (bool, error) {
+	res, err := tx.ExecContext(ctx, `UPDATE clients SET wallet_balance=wallet_balance+?
+        WHERE id=? AND company_id=? AND branch_id=? AND wallet_balance+? >= 0`, e.Amount, e.ClientID, e.CompanyID, e.BranchID, e.Amount)
+	if err != nil {
+		log.Printf("update wallet balance error: %v", err)
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT wallet_balance FROM clients WHERE id=?`, e.ClientID).Scan(&e.BalanceAfter); err != nil {
+		return false, err
+	}
+	res, err = tx.ExecContext(ctx, `INSERT INTO client_wallet_entries (company_id, branch_id, client_id, kind, amount, balance_after, payment_type_id, booking_id, user_id, note, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, NULLIF(?,0), NULLIF(?,0), NULLIF(?,0), ?, NOW())`,
+		e.CompanyID, e.BranchID, e.ClientID, e.Kind, e.Amount, e.BalanceAfter, e.PaymentTypeID, e.BookingID, e.UserID, e.Note)
+	if err != nil {
+		log.Printf("insert wallet entry error: %v", err)
+		return false, err
+	}
+	id, err := res.LastInsertId()
+	e.ID = int(id)
+	return true, err
+}
+
+// TopUp puts money on a client wallet.
+func (r *WalletRepository) TopUp(ctx context.Context, e *models.WalletEntry) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	ok, err := r.change(ctx, tx, e)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		err = sql.ErrNoRows
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit wallet top-up error: %v", err)
+	}
+	return err
+}
+
+// Pay takes money from a client wallet, e.Amount is negative, and adds it to
+// the booking as a wallet payment. Returns the id of the payment, 0 if the
+// balance is not enough.
+func (r *WalletRepository) Pay(ctx context.Context, e *models.WalletEntry) (paymentID int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	ok, err := r.change(ctx, tx, e)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		_ = tx.Rollback()
+		return 0, nil
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO booking_payments (booking_id, company_id, branch_id, payment_type_id, amount, is_deposit, method, wallet_client_id, created_at)
+        VALUES (?, ?, ?, NULL, ?, 0, ?, ?, NOW())`,
+		e.BookingID, e.CompanyID, e.BranchID, -e.Amount, models.PaymentMethodWallet, e.ClientID)
+	if err != nil {
+		log.Printf("insert wallet payment error: %v", err)
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit wallet payment error: %v", err)
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// ReturnForBooking puts wallet payments of a booking back to the client
+// wallets and removes them from the booking.
+func (r *WalletRepository) ReturnForBooking(ctx context.Context, companyID, branchID, bookingID, userID int) ([]models.WalletEntry, error) {
+	return r.returnPayments(ctx, companyID, branchID, bookingID, 0, userID)
+}
+
+// ReturnPayment puts one wallet payment of a booking back to the client
+// wallet and removes it from the booking.
+func (r *WalletRepository) ReturnPayment(ctx context.Context, companyID, branchID, bookingID, paymentID, userID int) ([]models.WalletEntry, error) {
+	return r.returnPayments(ctx, companyID, branchID, bookingID, paymentID, userID)
+}
+
+// returnPayments returns wallet payments of the booking, only the given one
+// when paymentID is set.
+func (r *WalletRepository) returnPayments(ctx context.Context, companyID, branchID, bookingID, paymentID, userID int) (returned []models.WalletEntry, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("begin tx error: %v", err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, amount, wallet_client_id FROM booking_payments
+        WHERE booking_id=? AND company_id=? AND branch_id=? AND method=? AND wallet_client_id IS NOT NULL AND (?=0 OR id=?) FOR UPDATE`,
+		bookingID, companyID, branchID, models.PaymentMethodWallet, paymentID, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	var payIDs []int
+	for rows.Next() {
+		var payID int
+		e := models.WalletEntry{CompanyID: companyID, BranchID: branchID, BookingID: bookingID, UserID: userID, Kind: models.WalletReturn}
+		if err = rows.Scan(&payID, &e.Amount, &e.ClientID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		payIDs = append(payIDs, payID)
+		returned = append(returned, e)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range returned {
+		if _, err = r.change(ctx, tx, &returned[i]); err != nil {
+			return nil, err
+		}
+		if _, err = tx.ExecContext(ctx, `DELETE FROM booking_payments WHERE id=?`, payIDs[i]); err != nil {
+			log.Printf("delete wallet payment error: %v", err)
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("commit wallet return error: %v", err)
+		return nil, err
+	}
+	return returned, nil
+}
+
+// GetEntries returns the wallet journal of a client, newest first.
+func (r *WalletRepository) GetEntries(ctx context.Context, companyID, branchID, clientID int) ([]models.WalletEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT e.id, e.company_id, e.branch_id, e.client_id, e.kind, e.amount, e.balance_after,
+               IFNULL(e.payment_type_id, 0), IFNULL(pt.name, ''), IFNULL(e.booking_id, 0), IFNULL(e.user_id, 0), e.note, e.created_at
+        FROM client_wallet_entries e
+        LEFT JOIN payment_types pt ON pt.id = e.payment_type_id
+        WHERE e.client_id=? AND e.company_id=? AND e.branch_id=?
+        ORDER BY e.id DESC`, clientID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []models.WalletEntry{}
+	for rows.Next() {
+		var e models.WalletEntry
+		if err := rows.Scan(&e.ID, &e.CompanyID, &e.BranchID, &e.ClientID, &e.Kind, &e.Amount, &e.BalanceAfter,
+			&e.PaymentTypeID, &e.PaymentType, &e.BookingID, &e.UserID, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
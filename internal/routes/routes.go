@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"psclub-crm/internal/handlers"
 	"psclub-crm/internal/middleware"
+	"psclub-crm/internal/ratelimit"
 )
 
 func SetupRoutes(
@@ -31,7 +32,23 @@ func SetupRoutes(
 	settingsHandler *handlers.SettingsHandler,
 	reportHandler *handlers.ReportHandler,
 	inventoryHandler *handlers.InventoryHandler,
+	payableHandler *handlers.PayableHandler,
+	recurringExpenseHandler *handlers.RecurringExpenseHandler,
+	auditHandler *handlers.AuditHandler,
+	availabilityHandler *handlers.AvailabilityHandler,
+	stationHandler *handlers.StationHandler,
+	ticketHandler *handlers.TicketHandler,
+	passHandler *handlers.PassHandler,
+	certificateHandler *handlers.GiftCertificateHandler,
+	promotionHandler *handlers.PromotionHandler,
+	walletHandler *handlers.WalletHandler,
+	publicBookingHandler *handlers.PublicBookingHandler,
+	deviceHandler *handlers.DeviceHandler,
+	passwordHandler *handlers.PasswordHandler,
+	publicLimiter *ratelimit.Limiter,
+	signupLimiter *ratelimit.Limiter,
 	authSecret string,
+	sessions middleware.SessionChecker,
 ) {
 	api := r.Group("/api")
 
@@ -40,18 +57,40 @@ func SetupRoutes(
 	{
 		auth.POST("/login", authHandler.Login)
 		auth.POST("/refresh", authHandler.Refresh)
+		// регистрация новой компании вместе с директором
+		auth.GET("/templates", authHandler.Templates)
+		auth.POST("/signup", middleware.RateLimitByIP(signupLimiter), authHandler.Signup)
+		// вход по PIN на подключенном планшете филиала
+		auth.GET("/device/users", middleware.RateLimitByIP(publicLimiter), deviceHandler.GetUsers)
+		auth.POST("/pin-login", middleware.RateLimitByIP(publicLimiter), deviceHandler.PinLogin)
+		// восстановление пароля по коду из SMS или от директора
+		auth.POST("/forgot-password", middleware.RateLimitByIP(publicLimiter), passwordHandler.RequestReset)
+		auth.POST("/reset-password", middleware.RateLimitByIP(publicLimiter), passwordHandler.ResetPassword)
 	}
 
-	companies := api.Group("/companies")
+	// --- Онлайн-бронирование для гостей, без токена сотрудника
+	public := api.Group("/public/branches/:branch_id", middleware.RateLimitByIP(publicLimiter), publicBookingHandler.ResolveBranch)
 	{
-		companies.POST("", companyHandler.Create)
+		public.GET("/availability", availabilityHandler.GetAvailability)
+		public.POST("/estimate", publicBookingHandler.Estimate)
+		public.POST("/otp", publicBookingHandler.RequestCode)
+		public.POST("/reservations", publicBookingHandler.Reserve)
 	}
 
-	api.Use(middleware.Auth(authSecret))
+	api.Use(middleware.Auth(authSecret, sessions))
 
 	authProtected := api.Group("/auth")
 	{
 		authProtected.POST("/register", authHandler.Register)
+		authProtected.GET("/branches", authHandler.Branches)
+		authProtected.POST("/switch-branch", authHandler.SwitchBranch)
+		authProtected.GET("/sessions", authHandler.Sessions)
+		authProtected.DELETE("/sessions/:id", authHandler.RevokeSession)
+		authProtected.POST("/logout", authHandler.Logout)
+		authProtected.POST("/logout-all", authHandler.LogoutAll)
+		authProtected.PUT("/pin", deviceHandler.SetPin)
+		authProtected.DELETE("/pin", deviceHandler.RemovePin)
+		authProtected.POST("/change-password", passwordHandler.ChangePassword)
 	}
 
 	// --- Клиенты
@@ -62,6 +101,37 @@ func SetupRoutes(
 		clients.GET("/:id", clientHandler.GetClientByID)
 		clients.PUT("/:id", clientHandler.UpdateClient)
 		clients.DELETE("/:id", clientHandler.DeleteClient)
+		clients.GET("/:id/passes", passHandler.GetClientPasses)
+		clients.POST("/:id/passes", passHandler.SellPass)
+		clients.GET("/:id/wallet", walletHandler.GetEntries)
+		clients.POST("/:id/wallet/top-up", walletHandler.TopUp)
+	}
+
+	// --- Абонементы
+	passTypes := api.Group("/pass-types")
+	{
+		passTypes.POST("", passHandler.CreatePassType)
+		passTypes.GET("", passHandler.GetPassTypes)
+		passTypes.PUT("/:id", passHandler.UpdatePassType)
+		passTypes.DELETE("/:id", passHandler.DeletePassType)
+	}
+
+	// --- Подарочные сертификаты
+	certificates := api.Group("/gift-certificates")
+	{
+		certificates.POST("", certificateHandler.SellCertificate)
+		certificates.GET("", certificateHandler.GetAllCertificates)
+		certificates.GET("/lookup", certificateHandler.LookupCertificate)
+	}
+
+	// --- Акции и промокоды
+	promotions := api.Group("/promotions")
+	{
+		promotions.POST("", promotionHandler.CreatePromotion)
+		promotions.GET("", promotionHandler.GetPromotions)
+		promotions.GET("/:id", promotionHandler.GetPromotion)
+		promotions.PUT("/:id", promotionHandler.UpdatePromotion)
+		promotions.DELETE("/:id", promotionHandler.DeletePromotion)
 	}
 
 	// --- Каналы привлечения
@@ -80,6 +150,10 @@ func SetupRoutes(
 		users.POST("", userHandler.CreateUser)
 		users.GET("", userHandler.GetAllUsers)
 		users.GET("/:id", userHandler.GetUserByID)
+		users.GET("/:id/salary-history", userHandler.GetSalaryHistory)
+		users.GET("/:id/branches", userHandler.GetUserBranches)
+		users.PUT("/:id/branches", middleware.RequireRole("director", "owner"), userHandler.SetUserBranches)
+		users.POST("/:id/reset-password", middleware.RequireRole("director", "owner"), passwordHandler.IssueResetCode)
 		users.PUT("/:id", userHandler.UpdateUser)
 		users.DELETE("/:id", userHandler.DeleteUser)
 	}
@@ -94,11 +168,28 @@ func SetupRoutes(
 		tableCategories.DELETE("/:id", tableCategoryHandler.DeleteCategory)
 	}
 
+	// --- Станции приготовления и очередь заказов
+	stations := api.Group("/stations")
+	{
+		stations.POST("", stationHandler.CreateStation)
+		stations.GET("", stationHandler.GetAllStations)
+		stations.PUT("/:id", stationHandler.UpdateStation)
+		stations.DELETE("/:id", stationHandler.DeleteStation)
+		stations.GET("/:id/tickets", stationHandler.GetTickets)
+		stations.GET("/:id/tickets/stream", stationHandler.StreamTickets)
+	}
+	tickets := api.Group("/tickets")
+	{
+		tickets.POST("/:id/start", ticketHandler.StartTicket)
+		tickets.POST("/:id/serve", ticketHandler.ServeTicket)
+	}
+
 	// --- Столы
 	tables := api.Group("/tables")
 	{
 		tables.POST("", tableHandler.CreateTable)
 		tables.GET("", tableHandler.GetAllTables)
+		tables.GET("/availability", availabilityHandler.GetAvailability)
 		tables.GET("/:id", tableHandler.GetTableByID)
 		tables.PUT("/:id", tableHandler.UpdateTable)
 		tables.DELETE("/:id", tableHandler.DeleteTable)
@@ -189,6 +280,21 @@ func SetupRoutes(
 		bookings.GET("/:id", bookingHandler.GetBookingByID)
 		bookings.PUT("/:id", bookingHandler.UpdateBooking)
 		bookings.DELETE("/:id", bookingHandler.DeleteBooking)
+		bookings.POST("/:id/cancel", bookingHandler.CancelBooking)
+		bookings.POST("/:id/deposit", bookingHandler.AddDeposit)
+		bookings.POST("/:id/confirm", bookingHandler.ConfirmBooking)
+		bookings.POST("/:id/seat", bookingHandler.SeatBooking)
+		bookings.POST("/:id/complete", bookingHandler.CompleteBooking)
+		bookings.POST("/:id/no-show", bookingHandler.MarkNoShow)
+		bookings.POST("/:id/move", bookingHandler.MoveBooking)
+		bookings.POST("/merge", bookingHandler.MergeBookings)
+		bookings.POST("/:id/split", bookingHandler.SplitBooking)
+		bookings.DELETE("/:id/split", bookingHandler.RemoveSplit)
+		bookings.POST("/:id/items", bookingHandler.AddBookingItem)
+		bookings.POST("/:id/items/:line_id/void", bookingHandler.VoidBookingItem)
+		bookings.POST("/:id/items/:line_id/pass", bookingHandler.RedeemPass)
+		bookings.POST("/:id/certificate", bookingHandler.RedeemCertificate)
+		bookings.POST("/:id/wallet", bookingHandler.PayFromWallet)
 		// Можно добавить эндпоинт для получения позиций бронирования:
 		// bookings.GET("/:id/items", bookingHandler.GetBookingItemsByBookingID)
 	}
@@ -219,6 +325,27 @@ func SetupRoutes(
 		expenses.GET("/:id", expenseHandler.GetExpenseByID)
 		expenses.PUT("/:id", expenseHandler.UpdateExpense)
 		expenses.DELETE("/:id", expenseHandler.DeleteExpense)
+		expenses.GET("/:id/payments", payableHandler.GetPayments)
+		expenses.POST("/:id/payments", payableHandler.AddPayment)
+	}
+
+	// --- Регулярные расходы
+	recurringExpenses := api.Group("/recurring-expenses")
+	{
+		recurringExpenses.POST("", recurringExpenseHandler.Create)
+		recurringExpenses.GET("", recurringExpenseHandler.GetAll)
+		recurringExpenses.GET("/upcoming", recurringExpenseHandler.Upcoming)
+		recurringExpenses.GET("/:id", recurringExpenseHandler.GetByID)
+		recurringExpenses.PUT("/:id", recurringExpenseHandler.Update)
+		recurringExpenses.DELETE("/:id", recurringExpenseHandler.Delete)
+		recurringExpenses.POST("/:id/pause", recurringExpenseHandler.Pause)
+		recurringExpenses.POST("/:id/resume", recurringExpenseHandler.Resume)
+	}
+
+	// --- Задолженности поставщикам
+	payables := api.Group("/payables")
+	{
+		payables.GET("", payableHandler.GetPayables)
 	}
 
 	// --- Инвентаризация
@@ -243,7 +370,8 @@ func SetupRoutes(
 	{
 		cashbox.GET("", cashboxHandler.GetCashbox)
 		cashbox.GET("/day", cashboxHandler.GetDay)
-		cashbox.PUT("/:id", cashboxHandler.UpdateCashbox)
+		// прямое изменение остатка доступно только директору
+		cashbox.PUT("/:id", middleware.RequireRole("director", "owner"), cashboxHandler.UpdateCashbox)
 		cashbox.POST("/inventory", cashboxHandler.Inventory)
 		cashbox.POST("/replenish", cashboxHandler.Replenish)
 		cashbox.GET("/history", cashboxHandler.GetHistory)
@@ -260,6 +388,36 @@ func SetupRoutes(
 		settings.DELETE("/:id", settingsHandler.DeleteSettings)
 	}
 
+	// --- Филиалы компании
+	branches := api.Group("/branches", middleware.RequireRole("director", "owner"))
+	{
+		branches.GET("", companyHandler.GetBranches)
+		branches.GET("/:id", companyHandler.GetBranch)
+		branches.POST("", companyHandler.CreateBranch)
+		branches.PUT("/:id", companyHandler.UpdateBranch)
+		branches.DELETE("/:id", companyHandler.DeleteBranch)
+	}
+
+	// --- Общие планшеты филиала
+	devices := api.Group("/devices", middleware.RequireRole("director", "owner"))
+	{
+		devices.GET("", deviceHandler.GetDevices)
+		devices.POST("", deviceHandler.EnrollDevice)
+		devices.DELETE("/:id", deviceHandler.RevokeDevice)
+	}
+
+	// --- Журнал попыток входа сотрудников компании
+	security := api.Group("/security", middleware.RequireRole("director", "owner"))
+	{
+		security.GET("/login-attempts", authHandler.LoginAttempts)
+	}
+
+	// --- Журнал изменений
+	audit := api.Group("/audit", middleware.RequireRole("director", "owner"))
+	{
+		audit.GET("", auditHandler.List)
+	}
+
 	// --- Отчёты (фильтрация по периодам через query-параметры)
 	reports := api.Group("/reports")
 	{
@@ -269,5 +427,18 @@ func SetupRoutes(
 		reports.GET("/analytics", reportHandler.GetAnalyticsReport)
 		reports.GET("/tables", reportHandler.GetTablesReport)
 		reports.GET("/discounts", reportHandler.GetDiscountsReport)
+		reports.GET("/payables", reportHandler.GetPayablesReport)
+		reports.GET("/certificates", reportHandler.GetCertificatesReport)
+		reports.GET("/wallets", reportHandler.GetWalletsReport)
+
+		// сводные отчеты по всем филиалам - только для владельца компании
+		company := reports.Group("/company", middleware.RequireRole("owner"))
+		{
+			company.GET("/summary", reportHandler.GetCompanySummaryReport)
+			company.GET("/sales", reportHandler.GetCompanySalesReport)
+			company.GET("/admins", reportHandler.GetCompanyAdminsReport)
+			company.GET("/tables", reportHandler.GetCompanyTablesReport)
+			company.GET("/branches", reportHandler.GetBranchComparison)
+		}
 	}
 }
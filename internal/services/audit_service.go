@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// redactedFields are never written to the audit log.
+var redactedFields = map[string]struct{}{
+	"password": {},
+	"pin":      {},
+}
+
+// AuditService records who changed what. Failures are only logged so that
+// auditing never breaks the main operation. A nil *AuditService is valid and
+// records nothing.
+type AuditService struct {
+	repo *repositories.AuditRepository
+}
+
+func NewAuditService(r *repositories.AuditRepository) *AuditService {
+	return &AuditService{repo: r}
+}
+
+type auditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Record saves an audit entry for entity. before is nil for created objects,
+// after is nil for deleted ones. Nothing is saved if no field changed.
+func (s *AuditService) Record(ctx context.Context, entity string, entityID int, action string, before, after interface{}) {
+	if s == nil {
+		return
+	}
+	diff, changed := auditDiff(before, after)
+	if !changed {
+		return
+	}
+	raw, err := json.Marshal(diff)
+	if err != nil {
+		log.Printf("audit %s/%d: %v", entity, entityID, err)
+		return
+	}
+	entry := models.AuditLog{
+		Entity:   entity,
+		EntityID: entityID,
+		Action:   action,
+		Diff:     raw,
+	}
+	entry.CompanyID, _ = ctx.Value(common.CtxCompanyID).(int)
+	entry.BranchID, _ = ctx.Value(common.CtxBranchID).(int)
+	entry.UserID, _ = ctx.Value(common.CtxUserID).(int)
+	entry.Role, _ = ctx.Value(common.CtxRole).(string)
+	if err := s.repo.Create(ctx, &entry); err != nil {
+		log.Printf("audit %s/%d: %v", entity, entityID, err)
+	}
+}
+
+func (s *AuditService) List(ctx context.Context, companyID, branchID int, f models.AuditFilter) ([]models.AuditLog, error) {
+	return s.repo.List(ctx, companyID, branchID, f)
+}
+
+// auditDiff compares JSON representations of before and after and returns
+// changed top-level fields.
+func auditDiff(before, after interface{}) (map[string]auditChange, bool) {
+	oldFields := auditFields(before)
+	newFields := auditFields(after)
+	diff := make(map[string]auditChange)
+	for k, v := range oldFields {
+		nv, ok := newFields[k]
+		if ok && reflect.DeepEqual(v, nv) {
+			continue
+		}
+		diff[k] = auditChange{Old: v, New: nv}
+	}
+	for k, v := range newFields {
+		if _, ok := oldFields[k]; !ok {
+			diff[k] = auditChange{New: v}
+		}
+	}
+	for k, ch := range diff {
+		if _, ok := redactedFields[k]; ok {
+			if ch.Old != nil {
+				ch.Old = "***"
+			}
+			if ch.New != nil {
+				ch.New = "***"
+			}
+			diff[k] = ch
+		}
+	}
+	return diff, len(diff) > 0
+}
+
+func auditFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return map[string]interface{}{}
+	}
+	// служебные поля не несут смысла для истории изменений
+	delete(fields, "created_at")
+	delete(fields, "updated_at")
+	return fields
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"psclub-crm/internal/models"
+)
+
+// Вход на общих планшетах администраторов: директор подключает планшет к
+// филиалу и получает токен устройства, на подключенном планшете сотрудники
+// переключаются по PIN-коду. Вход по PIN короткий и без refresh-токена.
+
+const (
+	pinMaxAttempts = 5
+	pinLockout     = 15 * time.Minute
+)
+
+// EnrollDevice enrolls a device in the branch and returns it with its token.
+// The token is shown only once, only its hash is kept.
+func (s *AuthService) EnrollDevice(ctx context.Context, companyID, branchID, userID int, name string) (*models.Device, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrInvalidDevice
+	}
+	token, err := randomString(32)
+	if err != nil {
+		return nil, "", err
+	}
+	d := &models.Device{CompanyID: companyID, BranchID: branchID, Name: name, CreatedBy: userID, CreatedAt: time.Now()}
+	d.ID, err = s.deviceRepo.Create(ctx, d, s.hashToken(token))
+	if err != nil {
+		return nil, "", err
+	}
+	return d, token, nil
+}
+
+// Devices lists the devices enrolled in the branch.
+func (s *AuthService) Devices(ctx context.Context, companyID, branchID int) ([]models.Device, error) {
+	list, err := s.deviceRepo.GetAll(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []models.Device{}
+	}
+	return list, nil
+}
+
+// RevokeDevice disables the device token, PIN login on it stops working and
+// the staff logged in on it are logged out.
+func (s *AuthService) RevokeDevice(ctx context.Context, companyID, branchID, id int) error {
+	ok, err := s.deviceRepo.Delete(ctx, id, companyID, branchID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeviceNotFound
+	}
+	return s.tokenRepo.DeleteByDevice(ctx, id)
+}
+
+func (s *AuthService) device(ctx context.Context, token string) (*models.Device, error) {
+	if token == "" {
+		return nil, ErrUnknownDevice
+	}
+	d, err := s.deviceRepo.GetByHash(ctx, s.hashToken(token))
+	if err != nil {
+		return nil, ErrUnknownDevice
+	}
+	return d, nil
+}
+
+// DeviceUsers lists the staff who can log in with a PIN on the device.
+func (s *AuthService) DeviceUsers(ctx context.Context, token string) ([]models.DeviceUser, error) {
+	d, err := s.device(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.pinRepo.GetUsers(ctx, d.CompanyID, d.BranchID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []models.DeviceUser{}
+	}
+	return list, nil
+}
+
+// SetPin sets the PIN of the user, the password confirms that it is the
+// user. A new PIN lifts the lockout.
+func (s *AuthService) SetPin(ctx context.Context, userID int, password, pin string) error {
+	if !validPin(pin) {
+		return ErrInvalidPinCode
+	}
+	u, err := s.userRepo.GetByIDNoTenant(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pin), 12)
+	if err != nil {
+		return err
+	}
+	return s.pinRepo.Set(ctx, userID, string(hashed))
+}
+
+// RemovePin disables PIN login of the user.
+func (s *AuthService) RemovePin(ctx context.Context, userID int) error {
+	return s.pinRepo.Delete(ctx, userID)
+}
+
+// PinLogin logs the user in on an enrolled device with the role the user
+// has in the branch of the device. It returns a short-lived access token,
+// the session ends when it expires. After pinMaxAttempts wrong PINs the PIN
+// is locked for pinLockout.
+func (s *AuthService) PinLogin(ctx context.Context, token string, userID int, pin string, device models.Session) (string, *models.User, error) {
+	d, err := s.device(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	ub, err := s.branchRepo.Get(ctx, userID, d.BranchID)
+	if err != nil {
+		return "", nil, err
+	}
+	p, err := s.pinRepo.Get(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if ub == nil || ub.CompanyID != d.CompanyID || p == nil {
+		return "", nil, ErrWrongPin
+	}
+	if p.Locked {
+		return "", nil, ErrPinLocked
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(p.Hash), []byte(pin)); err != nil {
+		locked, err := s.pinRepo.Fail(ctx, userID, pinMaxAttempts, pinLockout)
+		if err != nil {
+			return "", nil, err
+		}
+		if locked {
+			return "", nil, ErrPinLocked
+		}
+		return "", nil, ErrWrongPin
+	}
+	if err := s.pinRepo.Reset(ctx, userID); err != nil {
+		return "", nil, err
+	}
+	if err := s.deviceRepo.Touch(ctx, d.ID); err != nil {
+		return "", nil, err
+	}
+	u, err := s.userRepo.GetByIDNoTenant(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	u.BranchID = ub.BranchID
+	u.Role = ub.Role
+	device.DeviceName = d.Name
+	device.DeviceID = d.ID
+	// refresh-токен сессии не выдается, вход закончится вместе с access-токеном
+	if _, err := s.saveSession(ctx, u, &device, s.pinTTL); err != nil {
+		return "", nil, err
+	}
+	access, err := generateJWT(u, device.ID, s.accessSecret, s.pinTTL)
+	if err != nil {
+		return "", nil, err
+	}
+	return access, u, nil
+}
+
+func validPin(pin string) bool {
+	if len(pin) < 4 || len(pin) > 6 {
+		return false
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"errors"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"time"
 
 	"psclub-crm/internal/models"
@@ -20,10 +21,18 @@ import (
 type AuthService struct {
 	userRepo      *repositories.UserRepository
 	tokenRepo     *repositories.TokenRepository
+	branchRepo    *repositories.UserBranchRepository
+	companyRepo   *repositories.CompanyRepository
+	deviceRepo    *repositories.DeviceRepository
+	pinRepo       *repositories.PinRepository
+	attemptRepo   *repositories.LoginAttemptRepository
+	templates     *TemplateService
 	accessSecret  string
 	refreshSecret string
 	accessTTL     time.Duration
 	refreshTTL    time.Duration
+	// pinTTL - время жизни входа по PIN на общем устройстве
+	pinTTL time.Duration
 }
 
 type jwtClaims struct {
@@ -31,23 +40,36 @@ type jwtClaims struct {
 	CompanyID int    `json:"company_id"`
 	BranchID  int    `json:"branch_id"`
 	Role      string `json:"role"`
+	SessionID int    `json:"sid"`
 	Exp       int64  `json:"exp"`
 }
 
-func NewAuthService(userRepo *repositories.UserRepository, tokenRepo *repositories.TokenRepository,
-	accessSecret, refreshSecret string, accessTTL, refreshTTL time.Duration) *AuthService {
+func NewAuthService(userRepo *repositories.UserRepository, tokenRepo *repositories.TokenRepository, branchRepo *repositories.UserBranchRepository,
+	companyRepo *repositories.CompanyRepository, deviceRepo *repositories.DeviceRepository, pinRepo *repositories.PinRepository,
+	attemptRepo *repositories.LoginAttemptRepository, templates *TemplateService,
+	accessSecret, refreshSecret string, accessTTL, refreshTTL, pinTTL time.Duration) *AuthService {
+	if pinTTL <= 0 {
+		pinTTL = 15 * time.Minute
+	}
 	return &AuthService{
 		userRepo:      userRepo,
 		tokenRepo:     tokenRepo,
+		branchRepo:    branchRepo,
+		companyRepo:   companyRepo,
+		deviceRepo:    deviceRepo,
+		pinRepo:       pinRepo,
+		attemptRepo:   attemptRepo,
+		templates:     templates,
 		accessSecret:  accessSecret,
 		refreshSecret: refreshSecret,
 		accessTTL:     accessTTL,
 		refreshTTL:    refreshTTL,
+		pinTTL:        pinTTL,
 	}
 }
 
 // Register creates a new user and returns token pair.
-func (s *AuthService) Register(ctx context.Context, u *models.User) (string, string, error) {
+func (s *AuthService) Register(ctx context.Context, u *models.User, device models.Session) (string, string, error) {
 	existing, err := s.userRepo.GetByPhone(ctx, u.Phone)
 	if err != nil {
 		return "", "", err
@@ -55,6 +77,12 @@ func (s *AuthService) Register(ctx context.Context, u *models.User) (string, str
 	if existing != nil {
 		return "", "", errors.New("user already exists")
 	}
+	if u.Role == "" {
+		u.Role = models.RoleAdmin
+	}
+	if err := checkRole(ctx, u.Role); err != nil {
+		return "", "", err
+	}
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(u.Password), 12)
 	if err != nil {
@@ -66,68 +94,283 @@ func (s *AuthService) Register(ctx context.Context, u *models.User) (string, str
 		return "", "", err
 	}
 	u.ID = id
-	return s.generateTokenPair(ctx, u)
+	if err := s.branchRepo.Save(ctx, &models.UserBranch{UserID: id, CompanyID: u.CompanyID, BranchID: u.BranchID, Role: u.Role}); err != nil {
+		return "", "", err
+	}
+	return s.generateTokenPair(ctx, u, &device)
+}
+
+// Signup opens a new company: the company, its first branch set up from
+// the chosen template and the owner account are created together, so a
+// failure leaves nothing behind. Returns the token pair of the owner.
+func (s *AuthService) Signup(ctx context.Context, companyName, branchName, template string, u *models.User, device models.Session) (string, string, error) {
+	companyName = strings.TrimSpace(companyName)
+	branchName = strings.TrimSpace(branchName)
+	u.Name = strings.TrimSpace(u.Name)
+	u.Phone = strings.TrimSpace(u.Phone)
+	if companyName == "" || u.Name == "" || u.Phone == "" || len(u.Password) < 6 {
+		return "", "", ErrInvalidSignup
+	}
+	if branchName == "" {
+		branchName = companyName
+	}
+	t, err := s.templates.Get(template)
+	if err != nil {
+		return "", "", err
+	}
+	existing, err := s.userRepo.GetByPhone(ctx, u.Phone)
+	if err != nil {
+		return "", "", err
+	}
+	if existing != nil {
+		return "", "", ErrUserExists
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(u.Password), 12)
+	if err != nil {
+		return "", "", err
+	}
+	u.Password = string(hashed)
+	u.Role = models.RoleOwner
+	if _, _, err := s.companyRepo.CreateCompany(ctx, companyName, branchName, t, u); err != nil {
+		return "", "", err
+	}
+	return s.generateTokenPair(ctx, u, &device)
+}
+
+// Templates lists the templates a new club can start from.
+func (s *AuthService) Templates() []models.SeedTemplate {
+	return s.templates.List()
 }
 
-// Login verifies credentials and returns new tokens.
-func (s *AuthService) Login(ctx context.Context, phone, password string) (string, string, string, []string, string, int, int, int, error) {
+// Login verifies credentials and returns new tokens. Every login starts a
+// new session, sessions on other devices stay. Unknown phone and wrong
+// password give the same error, repeated failures are throttled.
+func (s *AuthService) Login(ctx context.Context, phone, password string, device models.Session) (string, string, string, []string, string, int, int, int, error) {
+	phone = strings.TrimSpace(phone)
+	throttled, err := s.loginThrottled(ctx, phone, device.IP)
+	if err != nil {
+		return "", "", "", nil, "", 0, 0, 0, err
+	}
+	if throttled {
+		s.recordLogin(ctx, phone, nil, device, models.LoginThrottled)
+		return "", "", "", nil, "", 0, 0, 0, ErrLoginThrottled
+	}
 	u, err := s.userRepo.GetByPhone(ctx, phone)
 	if err != nil {
 		return "", "", "", nil, "", 0, 0, 0, err
 	}
 	if u == nil {
-		return "", "", "", nil, "", 0, 0, 0, errors.New("invalid credentials1")
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		s.recordLogin(ctx, phone, nil, device, models.LoginUnknownPhone)
+		return "", "", "", nil, "", 0, 0, 0, ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
-		return "", "", "", nil, "", 0, 0, 0, errors.New("invalid credentials2")
+		s.recordLogin(ctx, phone, u, device, models.LoginWrongPassword)
+		return "", "", "", nil, "", 0, 0, 0, ErrInvalidCredentials
 	}
+	s.recordLogin(ctx, phone, u, device, models.LoginSuccess)
 
-	token1, token2, err := s.generateTokenPair(ctx, u)
+	token1, token2, err := s.generateTokenPair(ctx, u, &device)
 
 	return token1, token2, u.Role, u.Permissions, u.Name, u.ID, u.CompanyID, u.BranchID, err
 }
 
-// Refresh validates refresh token and returns a new pair.
-func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (string, string, error) {
-	hash := s.hashToken(refreshToken)
-	userID, exp, err := s.tokenRepo.Get(ctx, hash)
+// Refresh validates refresh token and returns a new pair. The session keeps
+// its id, only the token is rotated.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, device models.Session) (string, string, error) {
+	sess, err := s.tokenRepo.GetByHash(ctx, s.hashToken(refreshToken))
 	if err != nil {
 		return "", "", errors.New("invalid token")
 	}
-	if time.Now().After(exp) {
-		_ = s.tokenRepo.Delete(ctx, hash)
+	if time.Now().After(sess.ExpiresAt) {
+		_, _ = s.tokenRepo.DeleteByID(ctx, sess.UserID, sess.ID)
 		return "", "", errors.New("token expired")
 	}
-	// rotate token
-	_ = s.tokenRepo.Delete(ctx, hash)
-	u, err := s.userRepo.GetByIDNoTenant(ctx, userID)
+	u, err := s.userRepo.GetByIDNoTenant(ctx, sess.UserID)
 	if err != nil {
 		return "", "", err
 	}
-	return s.generateTokenPair(ctx, u)
+	// сессия остается в выбранном филиале, пока у сотрудника есть туда доступ
+	if sess.BranchID > 0 && sess.BranchID != u.BranchID {
+		branches, err := s.userBranches(ctx, u)
+		if err != nil {
+			return "", "", err
+		}
+		for _, ub := range branches {
+			if ub.BranchID == sess.BranchID {
+				u.BranchID = ub.BranchID
+				u.Role = ub.Role
+				break
+			}
+		}
+	}
+	sess.IP, sess.UserAgent = device.IP, device.UserAgent
+	sess.DeviceName = device.DeviceName
+	return s.generateTokenPair(ctx, u, sess)
 }
 
-func (s *AuthService) generateTokenPair(ctx context.Context, u *models.User) (string, string, error) {
-	access, err := generateJWT(u, s.accessSecret, s.accessTTL)
+// Branches lists the branches the user can work in, the home branch first.
+func (s *AuthService) Branches(ctx context.Context, userID int) ([]models.UserBranch, error) {
+	u, err := s.userRepo.GetByIDNoTenant(ctx, userID)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	refreshRaw, err := randomString(32)
+	return s.userBranches(ctx, u)
+}
+
+func (s *AuthService) userBranches(ctx context.Context, u *models.User) ([]models.UserBranch, error) {
+	if u.Role == models.RoleOwner {
+		return s.ownerBranches(ctx, u)
+	}
+	list, err := s.branchRepo.GetByUser(ctx, u.ID)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	hash := s.hashToken(refreshRaw)
-	exp := time.Now().Add(s.refreshTTL)
-	if err := s.tokenRepo.DeleteByUser(ctx, u.ID); err != nil {
+	res := []models.UserBranch{{UserID: u.ID, CompanyID: u.CompanyID, BranchID: u.BranchID, Role: u.Role}}
+	for _, ub := range list {
+		if ub.BranchID == u.BranchID {
+			res[0].BranchName = ub.BranchName
+			continue
+		}
+		if ub.CompanyID == u.CompanyID {
+			res = append(res, ub)
+		}
+	}
+	return res, nil
+}
+
+// ownerBranches gives the owner every branch of the company, branches
+// opened later included.
+func (s *AuthService) ownerBranches(ctx context.Context, u *models.User) ([]models.UserBranch, error) {
+	list, err := s.companyRepo.GetBranches(ctx, u.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	res := []models.UserBranch{{UserID: u.ID, CompanyID: u.CompanyID, BranchID: u.BranchID, Role: u.Role}}
+	for _, b := range list {
+		if b.ID == u.BranchID {
+			res[0].BranchName = b.Name
+			continue
+		}
+		res = append(res, models.UserBranch{UserID: u.ID, CompanyID: u.CompanyID, BranchID: b.ID, BranchName: b.Name, Role: u.Role})
+	}
+	return res, nil
+}
+
+// SwitchBranch issues a token pair for another branch the user has access
+// to, with the role the user has there. The current session moves to that
+// branch.
+func (s *AuthService) SwitchBranch(ctx context.Context, userID, sessionID, branchID int, device models.Session) (string, string, *models.UserBranch, error) {
+	u, err := s.userRepo.GetByIDNoTenant(ctx, userID)
+	if err != nil {
+		return "", "", nil, err
+	}
+	branches, err := s.userBranches(ctx, u)
+	if err != nil {
+		return "", "", nil, err
+	}
+	for _, ub := range branches {
+		if ub.BranchID != branchID {
+			continue
+		}
+		u.BranchID = ub.BranchID
+		u.Role = ub.Role
+		device.ID = sessionID
+		if sessionID > 0 {
+			if ok, err := s.tokenRepo.Exists(ctx, userID, sessionID); err != nil {
+				return "", "", nil, err
+			} else if !ok {
+				device.ID = 0
+			}
+		}
+		access, refresh, err := s.generateTokenPair(ctx, u, &device)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return access, refresh, &ub, nil
+	}
+	return "", "", nil, ErrBranchForbidden
+}
+
+// Sessions lists the devices the user is logged in on.
+func (s *AuthService) Sessions(ctx context.Context, userID, currentID int) ([]models.Session, error) {
+	list, err := s.tokenRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []models.Session{}
+	}
+	for i := range list {
+		list[i].Current = list[i].ID == currentID
+	}
+	return list, nil
+}
+
+// RevokeSession logs the user out on one device.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, id int) error {
+	ok, err := s.tokenRepo.DeleteByID(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// LogoutAll logs the user out on all devices.
+func (s *AuthService) LogoutAll(ctx context.Context, userID int) error {
+	return s.tokenRepo.DeleteByUser(ctx, userID)
+}
+
+// SessionActive tells whether an access token of the session may still be
+// used. Tokens issued before sessions were tracked have no session.
+func (s *AuthService) SessionActive(ctx context.Context, userID, sessionID int) bool {
+	if sessionID == 0 {
+		return true
+	}
+	ok, err := s.tokenRepo.Exists(ctx, userID, sessionID)
+	return err == nil && ok
+}
+
+// generateTokenPair issues tokens for the session, a session without id is
+// started.
+func (s *AuthService) generateTokenPair(ctx context.Context, u *models.User, sess *models.Session) (string, string, error) {
+	refreshRaw, err := s.saveSession(ctx, u, sess, s.refreshTTL)
+	if err != nil {
 		return "", "", err
 	}
-	if err := s.tokenRepo.Save(ctx, u.ID, hash, exp); err != nil {
+	access, err := generateJWT(u, sess.ID, s.accessSecret, s.accessTTL)
+	if err != nil {
 		return "", "", err
 	}
 	return access, refreshRaw, nil
 }
 
+// saveSession gives the session a new refresh token valid for ttl and
+// returns the token.
+func (s *AuthService) saveSession(ctx context.Context, u *models.User, sess *models.Session, ttl time.Duration) (string, error) {
+	refreshRaw, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	hash := s.hashToken(refreshRaw)
+	sess.UserID = u.ID
+	sess.BranchID = u.BranchID
+	sess.ExpiresAt = time.Now().Add(ttl)
+	if sess.ID > 0 {
+		err = s.tokenRepo.Rotate(ctx, sess, hash)
+	} else {
+		sess.ID, err = s.tokenRepo.Create(ctx, sess, hash)
+	}
+	if err != nil {
+		return "", err
+	}
+	return refreshRaw, nil
+}
+
 func (s *AuthService) hashToken(t string) string {
 	h := hmac.New(sha256.New, []byte(s.refreshSecret))
 	h.Write([]byte(t))
@@ -142,9 +385,9 @@ func randomString(n int) (string, error) {
 	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-func generateJWT(u *models.User, secret string, ttl time.Duration) (string, error) {
+func generateJWT(u *models.User, sessionID int, secret string, ttl time.Duration) (string, error) {
 	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
-	payloadBytes, err := json.Marshal(jwtClaims{UserID: u.ID, CompanyID: u.CompanyID, BranchID: u.BranchID, Role: u.Role, Exp: time.Now().Add(ttl).Unix()})
+	payloadBytes, err := json.Marshal(jwtClaims{UserID: u.ID, CompanyID: u.CompanyID, BranchID: u.BranchID, Role: u.Role, SessionID: sessionID, Exp: time.Now().Add(ttl).Unix()})
 	if err != nil {
 		return "", err
 	}
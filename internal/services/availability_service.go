@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// AvailabilityService builds the free-slot calendar of tables.
+type AvailabilityService struct {
+	tableRepo    *repositories.TableRepository
+	bookingRepo  *repositories.BookingRepository
+	settingsRepo *repositories.SettingsRepository
+}
+
+func NewAvailabilityService(tr *repositories.TableRepository, br *repositories.BookingRepository, sr *repositories.SettingsRepository) *AvailabilityService {
+	return &AvailabilityService{tableRepo: tr, bookingRepo: br, settingsRepo: sr}
+}
+
+// businessDay returns work day bounds of the given date according to
+// settings.work_time_from/to. A day ending after midnight ends next date.
+func businessDay(date time.Time, settings *models.Settings) (time.Time, time.Time) {
+	endOfDate := time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, date.Location())
+	return getWorkDayRange(endOfDate, settings.WorkTimeFrom, settings.WorkTimeTo)
+}
+
+// Availability returns free slots of every table (optionally of one
+// category) for the business day of date, zero date means the current day.
+// Only slots at least duration long are returned. Bookings are extended by
+// the cleanup buffer on both sides.
+func (s *AvailabilityService) Availability(ctx context.Context, companyID, branchID int, date time.Time, duration time.Duration, categoryID int) (*models.Availability, error) {
+	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	var dayStart, dayEnd time.Time
+	if date.IsZero() {
+		dayStart, dayEnd = getWorkDayRange(time.Now(), settings.WorkTimeFrom, settings.WorkTimeTo)
+	} else {
+		dayStart, dayEnd = businessDay(date, settings)
+	}
+	tables, err := s.tableRepo.GetAll(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	buffer := time.Duration(settings.CleanupBufferMinutes) * time.Minute
+	bookings, err := s.bookingRepo.GetByPeriod(ctx, companyID, branchID, dayStart.Add(-buffer), dayEnd.Add(buffer))
+	if err != nil {
+		return nil, err
+	}
+	busy := make(map[int][]models.TimeSlot)
+	for _, b := range bookings {
+		busy[b.TableID] = append(busy[b.TableID], models.TimeSlot{
+			From: b.StartTime.Add(-buffer),
+			To:   b.EndTime.Add(buffer),
+		})
+	}
+
+	// прошедшее время сегодняшнего дня не предлагаем
+	from := dayStart
+	if now := time.Now().In(dayStart.Location()); now.After(from) {
+		from = now.Truncate(time.Minute)
+	}
+
+	res := &models.Availability{DayStart: dayStart, DayEnd: dayEnd, Tables: []models.TableAvailability{}}
+	for _, t := range tables {
+		if categoryID > 0 && t.CategoryID != categoryID {
+			continue
+		}
+		res.Tables = append(res.Tables, models.TableAvailability{
+			TableID:    t.ID,
+			TableName:  t.Name,
+			Number:     t.Number,
+			CategoryID: t.CategoryID,
+			FreeSlots:  freeSlots(from, dayEnd, busy[t.ID], duration),
+		})
+	}
+	return res, nil
+}
+
+// freeSlots returns gaps between busy intervals within [from, to) that are at
+// least minLen long.
+func freeSlots(from, to time.Time, busy []models.TimeSlot, minLen time.Duration) []models.TimeSlot {
+	sort.Slice(busy, func(i, j int) bool { return busy[i].From.Before(busy[j].From) })
+	slots := []models.TimeSlot{}
+	cur := from
+	add := func(end time.Time) {
+		if end.After(cur) && end.Sub(cur) >= minLen {
+			slots = append(slots, models.TimeSlot{From: cur, To: end})
+		}
+	}
+	for _, b := range busy {
+		if !b.To.After(cur) {
+			continue
+		}
+		if b.From.After(to) || b.From.Equal(to) {
+			break
+		}
+		if b.From.After(cur) {
+			add(b.From)
+		}
+		cur = b.To
+	}
+	if cur.Before(to) {
+		add(to)
+	}
+	return slots
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// CancelBooking moves an open booking to a final status. Refund lines are
+// checked against what was paid with each payment type, cash refunds are
+// taken from the cashbox. Stock and bonus reversals are written to the
+// booking ledger.
+func (s *BookingService) CancelBooking(ctx context.Context, id int, req *models.BookingCancellation) (*models.Booking, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return nil, ErrCancelReasonRequired
+	}
+	if req.Status == "" {
+		req.Status = models.BookingStatusCancelled
+	}
+	if req.Status != models.BookingStatusCancelled && req.Status != models.BookingStatusNoShow {
+		return nil, ErrInvalidBookingStatus
+	}
+
+	b, err := s.repo.GetByID(ctx, companyID, branchID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsOpenBookingStatus(b.Status) {
+		return nil, ErrBookingNotActive
+	}
+	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.bookingItemRepo.GetByBookingID(ctx, companyID, branchID, id)
+	if err != nil {
+		return nil, err
+	}
+	pays, _ := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, id)
+	b.Items = items
+	b.Payments = pays
+
+	// сколько оплачено каждым типом оплаты, депозит внесен в любом случае
+	isPaid := strings.ToLower(b.PaymentStatus) == "paid"
+	paidByType := make(map[int]int)
+	totalPaid := 0
+	for _, p := range pays {
+		// оплата абонементом, сертификатом или со счета клиента возвращается
+		// туда же, а не деньгами
+		if p.Method != "" {
+			continue
+		}
+		if isPaid || p.IsDeposit {
+			paidByType[p.PaymentTypeID] += p.Amount
+			totalPaid += p.Amount
+		}
+	}
+	refundByType := make(map[int]int)
+	totalRefund := 0
+	var refunds []models.BookingRefund
+	for _, rf := range req.Refunds {
+		if rf.Amount == 0 {
+			continue
+		}
+		if rf.Amount < 0 {
+			return nil, ErrInvalidAmount
+		}
+		refundByType[rf.PaymentTypeID] += rf.Amount
+		if refundByType[rf.PaymentTypeID] > paidByType[rf.PaymentTypeID] {
+			return nil, ErrRefundExceedsPaid
+		}
+		totalRefund += rf.Amount
+		rf.BookingID = id
+		rf.UserID = userID
+		refunds = append(refunds, rf)
+	}
+
+	status := req.Status
+	switch {
+	case totalRefund > 0 && totalRefund == totalPaid:
+		status = models.BookingStatusRefunded
+	case totalRefund > 0:
+		status = models.BookingStatusPartiallyRefunded
+	}
+
+	// переводим бронь в финальный статус до изменения остатков, чтобы
+	// повторная отмена не вернула товары и деньги дважды
+	ok, err := s.repo.Cancel(ctx, companyID, branchID, id, status, req.Reason, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrBookingNotActive
+	}
+
+	s.tickets.CancelOpen(ctx, id, 0)
+	s.returnPasses(ctx, companyID, branchID, id, 0)
+	s.returnCertificates(ctx, companyID, branchID, id)
+	s.returnWallet(ctx, companyID, branchID, id)
+	s.releasePromoCode(ctx, b.PromoCode)
+
+	// заявка с сайта ничего со склада не списывала
+	if holdsStock(b.Status) && (req.RestoreStock == nil || *req.RestoreStock) {
+		for _, it := range s.increaseStock(ctx, activeItems(items)) {
+			s.writeLedger(ctx, companyID, branchID, &models.BookingLedgerEntry{
+				BookingID: id,
+				EntryType: models.LedgerStock,
+				ItemID:    it.ItemID,
+				Quantity:  it.Quantity,
+				Note:      "Возврат на склад",
+			})
+		}
+	}
+
+	// бонусы и визит по предварительной броне начисляются только при закрытии
+	if b.Status == models.BookingStatusActive {
+		_ = s.loadShares(ctx, companyID, branchID, b)
+		// списанные бонусы возвращаются, только если клиенту что-то вернули
+		returnBonus := totalRefund > 0 || totalPaid == 0
+		s.revertClientTotals(ctx, companyID, branchID, b, settings, totalRefund, returnBonus)
+	}
+
+	if len(refunds) > 0 {
+		if err := s.refundRepo.Create(ctx, companyID, branchID, id, refunds); err != nil {
+			log.Printf("booking refund create error: %v", err)
+		}
+		if s.cashboxService != nil {
+			cash := 0
+			for _, rf := range refunds {
+				if s.isCashPayment(ctx, rf.PaymentTypeID) {
+					cash += rf.Amount
+				}
+			}
+			if cash > 0 {
+				_ = s.cashboxService.RemoveIncome(ctx, float64(cash))
+			}
+		}
+	}
+
+	before := *b
+	before.Refunds = nil
+	b.Status = status
+	b.CancelReason = req.Reason
+	b.CancelledBy = userID
+	b.Refunds = refunds
+	s.audit.Record(ctx, "booking", id, "cancel", &before, b)
+	return b, nil
+}
+
+// revertClientTotals cancels bonuses and the visit counted for the booking
+// participants. Income is reduced by the refunded amount, split between
+// participants in proportion to their shares. Spent bonuses go back to the
+// participants only with returnBonus.
+func (s *BookingService) revertClientTotals(ctx context.Context, companyID, branchID int, b *models.Booking, settings *models.Settings, refunded int, returnBonus bool) {
+	for _, p := range participants(b) {
+		if p.ClientID <= 0 {
+			continue
+		}
+		// отменяем начисленные бонусы и возвращаем использованные
+		paid := p.Amount - p.BonusUsed
+		if paid < 0 {
+			paid = 0
+		}
+		bonus := int(float64(paid) * float64(settings.BonusPercent) / 100)
+		if bonus > 0 {
+			_ = s.clientRepo.AddBonus(ctx, p.ClientID, -bonus)
+			s.writeLedger(ctx, companyID, branchID, &models.BookingLedgerEntry{
+				BookingID: b.ID,
+				EntryType: models.LedgerBonus,
+				ClientID:  p.ClientID,
+				Amount:    -bonus,
+				Note:      "Отмена начисленных бонусов",
+			})
+		}
+		if p.BonusUsed > 0 && returnBonus {
+			_ = s.clientRepo.AddBonus(ctx, p.ClientID, p.BonusUsed)
+			s.writeLedger(ctx, companyID, branchID, &models.BookingLedgerEntry{
+				BookingID: b.ID,
+				EntryType: models.LedgerBonus,
+				ClientID:  p.ClientID,
+				Amount:    p.BonusUsed,
+				Note:      "Возврат списанных бонусов",
+			})
+		}
+		income := refunded
+		if len(b.Shares) > 0 && b.TotalAmount > 0 {
+			income = refunded * p.Amount / b.TotalAmount
+		}
+		_ = s.clientRepo.AddVisits(ctx, p.ClientID, -1)
+		_ = s.clientRepo.AddIncome(ctx, p.ClientID, -income)
+	}
+}
+
+func (s *BookingService) writeLedger(ctx context.Context, companyID, branchID int, e *models.BookingLedgerEntry) {
+	if s.ledgerRepo == nil {
+		return
+	}
+	if err := s.ledgerRepo.Create(ctx, companyID, branchID, e); err != nil {
+		log.Printf("booking ledger error: %v", err)
+	}
+}
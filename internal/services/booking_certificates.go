@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// RedeemCertificate pays part of an open booking bill from a gift
+// certificate. The amount may be less than the certificate balance, the
+// rest stays on the certificate for later visits.
+func (s *BookingService) RedeemCertificate(ctx context.Context, bookingID int, req *models.CertificateRedeem) (*models.GiftCertificate, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	b, err := s.openBookingForLines(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := s.certificateRepo.GetByCode(ctx, companyID, normalizeCertificateCode(req.Code))
+	if err != nil {
+		return nil, err
+	}
+	if !cert.Active || cert.Balance < req.Amount {
+		return nil, ErrCertificateExhausted
+	}
+	pays, err := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	paid := 0
+	for _, p := range pays {
+		paid += p.Amount
+	}
+	if paid+req.Amount > b.TotalAmount-b.BonusUsed {
+		return nil, ErrOverpayment
+	}
+
+	ok, err := s.certificateRepo.Redeem(ctx, companyID, branchID, cert.ID, bookingID, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCertificateExhausted
+	}
+	before := *cert
+	cert.Balance -= req.Amount
+	cert.Active = cert.Balance > 0
+	s.audit.Record(ctx, "gift_certificate", cert.ID, "redeem", &before, cert)
+	s.audit.Record(ctx, "booking", bookingID, "redeem_certificate", nil, req)
+	return cert, nil
+}
+
+// returnCertificates puts money paid by gift certificates for a cancelled
+// booking back to the certificates.
+func (s *BookingService) returnCertificates(ctx context.Context, companyID, branchID, bookingID int) {
+	returned, err := s.certificateRepo.ReturnForBooking(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		log.Printf("return certificate payments error: %v", err)
+		return
+	}
+	for _, p := range returned {
+		s.audit.Record(ctx, "gift_certificate", p.GiftCertificateID, "return", p, nil)
+	}
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// activeItems drops voided lines of a bill.
+func activeItems(items []models.BookingItem) []models.BookingItem {
+	var res []models.BookingItem
+	for _, it := range items {
+		if it.Status != models.BookingItemVoided {
+			res = append(res, it)
+		}
+	}
+	return res
+}
+
+// keepLineAuthors matches edited lines with the stored ones by line id. A
+// stored line keeps its author and order time whatever the client sends, an
+// unknown or repeated id makes the line a new one.
+func keepLineAuthors(items, current []models.BookingItem) {
+	stored := make(map[int]models.BookingItem)
+	for _, it := range current {
+		stored[it.ID] = it
+	}
+	for i := range items {
+		o, ok := stored[items[i].ID]
+		if items[i].ID <= 0 || !ok {
+			items[i].ID = 0
+			items[i].UserID = 0
+			items[i].CreatedAt = time.Time{}
+			continue
+		}
+		delete(stored, o.ID)
+		items[i].UserID = o.UserID
+		items[i].CreatedAt = o.CreatedAt
+	}
+}
+
+// keepLaterLines keeps in the edited bill the stored lines added after the
+// client loaded the booking (b.UpdatedAt), e.g. through POST
+// /bookings/:id/items: an edit made on an older copy must not void them.
+func keepLaterLines(b *models.Booking, current []models.BookingItem) {
+	if b.UpdatedAt.IsZero() {
+		return
+	}
+	sent := make(map[int]bool)
+	for _, it := range b.Items {
+		sent[it.ID] = true
+	}
+	for _, it := range current {
+		if sent[it.ID] || !it.CreatedAt.After(b.UpdatedAt) {
+			continue
+		}
+		b.Items = append(b.Items, it)
+		b.TotalAmount += int(math.Round(lineCost(it)))
+	}
+}
+
+// stockChanges compares the edited bill with the stored lines and returns
+// the quantities to put back to stock and to take from it. Untouched lines
+// give nothing, a changed quantity gives only the difference.
+func stockChanges(items, current []models.BookingItem) (released, taken []models.BookingItem) {
+	stored := make(map[int]models.BookingItem)
+	for _, it := range current {
+		stored[it.ID] = it
+	}
+	for _, it := range items {
+		o, ok := stored[it.ID]
+		if it.ID == 0 || !ok {
+			taken = append(taken, it)
+			continue
+		}
+		delete(stored, it.ID)
+		switch {
+		case o.ItemID != it.ItemID:
+			released = append(released, o)
+			taken = append(taken, it)
+		case it.Quantity > o.Quantity:
+			it.Quantity -= o.Quantity
+			taken = append(taken, it)
+		case it.Quantity < o.Quantity:
+			o.Quantity -= it.Quantity
+			released = append(released, o)
+		}
+	}
+	for _, it := range current {
+		if _, ok := stored[it.ID]; ok {
+			released = append(released, it)
+		}
+	}
+	return released, taken
+}
+
+// removedLineReason is written to lines dropped from the bill by an edit.
+const removedLineReason = "Удалена при изменении брони"
+
+// ticketQuantities returns by line index how much of the edited bill has to
+// go to the stations: a new line or a line with another item goes whole, a
+// kept line only with the quantity added to it.
+func ticketQuantities(items, current []models.BookingItem) map[int]float64 {
+	stored := make(map[int]models.BookingItem)
+	for _, it := range current {
+		stored[it.ID] = it
+	}
+	res := make(map[int]float64)
+	for i, it := range items {
+		o, ok := stored[it.ID]
+		switch {
+		case it.ID == 0 || !ok || o.ItemID != it.ItemID:
+			res[i] = it.Quantity
+		case it.Quantity > o.Quantity:
+			res[i] = it.Quantity - o.Quantity
+		}
+	}
+	return res
+}
+
+// releaseLines cancels open station tickets and returns pass units of lines
+// voided by an edit of the bill.
+func (s *BookingService) releaseLines(ctx context.Context, companyID, branchID, bookingID int, lineIDs []int) {
+	for _, id := range lineIDs {
+		s.tickets.CancelOpen(ctx, bookingID, id)
+		s.returnPasses(ctx, companyID, branchID, bookingID, id)
+	}
+}
+
+// stampItems marks new lines with the employee who added them.
+func stampItems(ctx context.Context, items []models.BookingItem) {
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	for i := range items {
+		if items[i].UserID == 0 {
+			items[i].UserID = userID
+		}
+	}
+}
+
+// openBookingForLines returns a booking whose bill can be changed line by line.
+func (s *BookingService) openBookingForLines(ctx context.Context, companyID, branchID, id int) (*models.Booking, error) {
+	b, err := s.repo.GetByID(ctx, companyID, branchID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsOpenBookingStatus(b.Status) {
+		return nil, ErrBookingNotActive
+	}
+	shares, err := s.shareRepo.GetByBookingID(ctx, companyID, branchID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) > 0 {
+		return nil, ErrBookingSplit
+	}
+	return b, nil
+}
+
+// AddBookingItem adds a line to a running booking. The line is priced by the
+// price list and active promotions, stock is taken for this line only and the
+// booking total grows by its cost.
+func (s *BookingService) AddBookingItem(ctx context.Context, bookingID int, it *models.BookingItem) (*models.BookingItem, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if it.Quantity <= 0 || it.Discount < 0 || it.Discount > 100 {
+		return nil, ErrInvalidAmount
+	}
+	b, err := s.openBookingForLines(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	pi, err := s.priceItemRepo.GetByID(ctx, it.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	it.ID = 0
+	it.BookingID = bookingID
+	it.ItemName = pi.Name
+	it.ItemPrice = pi.SalePrice
+	it.Price = int(math.Round(pi.SalePrice * it.Quantity))
+	it.Status = models.BookingItemActive
+	it.UserID, _ = ctx.Value(common.CtxUserID).(int)
+	it.PromotionID = 0
+	discounts, err := s.promoteLine(ctx, companyID, branchID, b, it)
+	if err != nil {
+		return nil, err
+	}
+	line := []models.BookingItem{*it}
+
+	hold := holdsStock(b.Status)
+	if hold {
+		if err := s.checkStock(ctx, line); err != nil {
+			return nil, err
+		}
+		if err := s.decreaseStock(ctx, line); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.bookingItemRepo.Add(ctx, companyID, branchID, it, int(math.Round(lineCost(*it)))); err != nil {
+		if hold {
+			s.increaseStock(ctx, line)
+		}
+		return nil, err
+	}
+	if len(discounts) > 0 {
+		rec := promotionRecords(&models.Booking{ID: bookingID, Items: []models.BookingItem{*it}}, discounts)
+		if err := s.promotionRepo.AddBookingPromotions(ctx, companyID, branchID, bookingID, rec); err != nil {
+			log.Printf("save booking promotions error: %v", err)
+		}
+	}
+	// предварительная бронь отправит заказ на станции при посадке гостей
+	if b.Status == models.BookingStatusActive || b.Status == models.BookingStatusSeated {
+		s.tickets.CreateForLines(ctx, b, []models.BookingItem{*it})
+	}
+	s.audit.Record(ctx, "booking", bookingID, "add_item", nil, it)
+	return s.bookingItemRepo.GetByID(ctx, companyID, branchID, bookingID, it.ID)
+}
+
+// VoidBookingItem cancels a line of a running booking with a reason. The
+// line stays in the bill history, its stock is returned and its cost is
+// taken off the booking total. Hours paid for the line by a pass go back to
+// the pass.
+func (s *BookingService) VoidBookingItem(ctx context.Context, bookingID, lineID int, reason string) (*models.BookingItem, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrVoidReasonRequired
+	}
+	b, err := s.openBookingForLines(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	line, err := s.bookingItemRepo.GetByID(ctx, companyID, branchID, bookingID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.bookingItemRepo.Void(ctx, companyID, branchID, bookingID, lineID, reason, userID, int(math.Round(lineCost(*line))))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrItemVoided
+	}
+	s.tickets.CancelOpen(ctx, bookingID, lineID)
+	s.returnPasses(ctx, companyID, branchID, bookingID, lineID)
+	if err := s.promotionRepo.DeleteLinePromotions(ctx, companyID, branchID, bookingID, lineID); err != nil {
+		log.Printf("delete line promotions error: %v", err)
+	}
+	if holdsStock(b.Status) {
+		for _, it := range s.increaseStock(ctx, []models.BookingItem{*line}) {
+			s.writeLedger(ctx, companyID, branchID, &models.BookingLedgerEntry{
+				BookingID: bookingID,
+				EntryType: models.LedgerStock,
+				ItemID:    it.ItemID,
+				Quantity:  it.Quantity,
+				Note:      "Отмена позиции: " + reason,
+			})
+		}
+	}
+	voided, err := s.bookingItemRepo.GetByID(ctx, companyID, branchID, bookingID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "booking", bookingID, "void_item", line, voided)
+	return voided, nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// ensureSegment records the initial table period of a booking that has not
+// been moved yet.
+func (s *BookingService) ensureSegment(ctx context.Context, companyID, branchID int, b *models.Booking) error {
+	if len(b.Segments) > 0 || b.TableID <= 0 {
+		return nil
+	}
+	seg := models.BookingTableSegment{BookingID: b.ID, TableID: b.TableID, StartTime: b.StartTime, EndTime: b.EndTime}
+	id, err := s.segmentRepo.Create(ctx, companyID, branchID, &seg)
+	if err != nil {
+		return err
+	}
+	seg.ID = id
+	b.Segments = append(b.Segments, seg)
+	return nil
+}
+
+// MoveBooking continues a running session at another table from the given
+// moment (now by default). Hours lines are split at the move time, the
+// remaining part is charged by the new tariff if one is given.
+func (s *BookingService) MoveBooking(ctx context.Context, id int, req *models.BookingMove) (*models.Booking, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	b, err := s.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsOpenBookingStatus(b.Status) {
+		return nil, ErrBookingNotActive
+	}
+	// строки счета перезаписываются, доли ссылаются на них
+	if len(b.Shares) > 0 {
+		return nil, ErrBookingSplit
+	}
+	if req.TableID <= 0 || req.TableID == b.TableID {
+		return nil, ErrInvalidMoveTable
+	}
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+	// сеанс еще не начался - переносится целиком
+	if at.Before(b.StartTime) {
+		at = b.StartTime
+	}
+	if !at.Before(b.EndTime) {
+		return nil, ErrInvalidBookingTime
+	}
+	ok, err := s.repo.IsTableAvailable(ctx, companyID, branchID, req.TableID, b.ID, at, b.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTableBusy
+	}
+	var tariff *models.PriceItem
+	if req.HoursItemID > 0 {
+		tariff, err = s.priceItemRepo.GetByID(ctx, req.HoursItemID)
+		if err != nil {
+			return nil, err
+		}
+		isHours, err := s.isHoursCategory(ctx, tariff.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if !isHours {
+			return nil, ErrNotHoursItem
+		}
+	}
+
+	before := *b
+	share := at.Sub(b.StartTime).Seconds() / b.EndTime.Sub(b.StartTime).Seconds()
+	var items []models.BookingItem
+	// прежняя строка для каждой новой, скидки акций переносятся на них
+	var origin []int
+	// строки часов, у которых остаток сеанса стал отдельной строкой
+	var splits []int
+	prices := make(map[int]int)
+	// отмененные строки остаются в истории и не переписываются
+	for _, it := range activeItems(b.Items) {
+		prices[it.ID] = it.Price
+		pi, err := s.priceItemRepo.GetByID(ctx, it.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		isHours, err := s.isHoursCategory(ctx, pi.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		if !isHours {
+			items = append(items, it)
+			origin = append(origin, it.ID)
+			continue
+		}
+		// прошедшее время остается в прежней строке, остаток добавляется
+		// новой строкой
+		spent := it
+		spent.Quantity = math.Round(it.Quantity*share*100) / 100
+		spent.Price = int(math.Round(float64(it.Price) * share))
+		rest := it
+		rest.ID = 0
+		rest.Quantity = it.Quantity - spent.Quantity
+		rest.Price = it.Price - spent.Price
+		if tariff != nil {
+			rest.ItemID = tariff.ID
+			rest.ItemName = tariff.Name
+			rest.ItemPrice = tariff.SalePrice
+			rest.Price = int(math.Round(tariff.SalePrice * rest.Quantity))
+		}
+		b.TotalAmount += spent.Price + rest.Price - it.Price
+		if spent.Quantity > 0 {
+			items = append(items, spent)
+			origin = append(origin, it.ID)
+			splits = append(splits, len(items))
+		} else {
+			rest.ID = it.ID
+		}
+		items = append(items, rest)
+		origin = append(origin, it.ID)
+	}
+	b.Items = items
+
+	if err := s.ensureSegment(ctx, companyID, branchID, b); err != nil {
+		return nil, err
+	}
+	b.TableID = req.TableID
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	if _, err := s.repo.UpdateWithItems(ctx, companyID, branchID, b, userID, removedLineReason); err != nil {
+		return nil, err
+	}
+	// оплата абонементом и заказы на станциях переходят на остаток сеанса:
+	// его еще можно отменить, прошедшее время - нет
+	for _, i := range splits {
+		if err := s.bookingItemRepo.MoveLineRefs(ctx, companyID, branchID, b.ID, origin[i], b.Items[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	s.moveLinePromotions(ctx, companyID, branchID, b, origin, prices)
+	if err := s.segmentRepo.CloseLast(ctx, companyID, branchID, b.ID, at); err != nil {
+		return nil, err
+	}
+	next := models.BookingTableSegment{BookingID: b.ID, TableID: req.TableID, StartTime: at, EndTime: b.EndTime}
+	if _, err := s.segmentRepo.Create(ctx, companyID, branchID, &next); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "booking", b.ID, "move", &before, req)
+	return s.GetBookingByID(ctx, b.ID)
+}
+
+// MergeBookings combines the bill of source booking into target. Items,
+// payments and table history of source go to target, source stays as a
+// merged booking pointing to target.
+func (s *BookingService) MergeBookings(ctx context.Context, req *models.BookingMerge) (*models.Booking, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if req.TargetID <= 0 || req.SourceID <= 0 || req.TargetID == req.SourceID {
+		return nil, ErrInvalidMerge
+	}
+	target, err := s.GetBookingByID(ctx, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.GetBookingByID(ctx, req.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsOpenBookingStatus(target.Status) || !models.IsOpenBookingStatus(source.Status) {
+		return nil, ErrBookingNotActive
+	}
+	if len(target.Shares) > 0 || len(source.Shares) > 0 {
+		return nil, ErrBookingSplit
+	}
+	// товары заявки с сайта еще не списаны со склада, объединять ее можно
+	// только с такой же заявкой
+	if holdsStock(target.Status) != holdsStock(source.Status) {
+		return nil, ErrInvalidMerge
+	}
+	// списанные бонусы при отмене возвращаются клиенту брони, поэтому
+	// бонусы другого клиента в чужую бронь не переносим
+	if source.BonusUsed > 0 && source.ClientID != target.ClientID {
+		return nil, ErrMergeBonusClient
+	}
+	if err := s.ensureSegment(ctx, companyID, branchID, target); err != nil {
+		return nil, err
+	}
+	if err := s.ensureSegment(ctx, companyID, branchID, source); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Merge(ctx, companyID, branchID, target.ID, source.ID); err != nil {
+		return nil, err
+	}
+	merged, err := s.GetBookingByID(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "booking", source.ID, "merge", source, merged)
+	return merged, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// RedeemPass pays an hours line of an open booking from a pass of the
+// booking client. An hours pass gives as many hours as the line has or its
+// whole remaining balance, a visits pass covers the line with one visit. The
+// covered cost is added to the booking as a pass payment.
+func (s *BookingService) RedeemPass(ctx context.Context, bookingID, lineID int, req *models.PassRedeem) (*models.PassRedemption, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	b, err := s.openBookingForLines(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	line, err := s.bookingItemRepo.GetByID(ctx, companyID, branchID, bookingID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if line.Status == models.BookingItemVoided {
+		return nil, ErrItemVoided
+	}
+	pi, err := s.priceItemRepo.GetByID(ctx, line.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	isHours, err := s.isHoursCategory(ctx, pi.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if !isHours || line.Quantity <= 0 {
+		return nil, ErrNotHoursItem
+	}
+	redeemed, err := s.passRepo.IsLineRedeemed(ctx, companyID, branchID, bookingID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if redeemed {
+		return nil, ErrLineRedeemed
+	}
+
+	pass, err := s.passRepo.GetClientPass(ctx, req.ClientPassID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if b.ClientID <= 0 || pass.ClientID != b.ClientID {
+		return nil, ErrPassNotAllowed
+	}
+	if !pass.Active {
+		return nil, ErrPassExhausted
+	}
+	allowed, err := s.passRepo.IsTableAllowed(ctx, pass.PassTypeID, b.TableID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrPassNotAllowed
+	}
+
+	cost := lineCost(*line)
+	red := &models.PassRedemption{
+		ClientPassID:  pass.ID,
+		BookingID:     bookingID,
+		BookingItemID: lineID,
+		UserID:        userID,
+	}
+	if pass.Kind == models.PassKindVisits {
+		red.Units = 1
+		red.Amount = int(math.Round(cost))
+	} else {
+		red.Units = math.Min(line.Quantity, pass.Balance)
+		red.Amount = int(math.Round(cost * red.Units / line.Quantity))
+	}
+
+	// оплата абонементом не может превышать остаток счета
+	pays, err := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	paid := 0
+	for _, p := range pays {
+		paid += p.Amount
+	}
+	if paid+red.Amount > b.TotalAmount-b.BonusUsed {
+		return nil, ErrOverpayment
+	}
+
+	ok, err := s.passRepo.Redeem(ctx, companyID, branchID, red)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrPassExhausted
+	}
+	s.audit.Record(ctx, "booking", bookingID, "redeem_pass", nil, red)
+	return red, nil
+}
+
+// returnPasses gives hours and visits taken for a booking line, or for the
+// whole booking with lineID 0, back to the client passes.
+func (s *BookingService) returnPasses(ctx context.Context, companyID, branchID, bookingID, lineID int) {
+	returned, err := s.passRepo.ReturnRedemptions(ctx, companyID, branchID, bookingID, lineID)
+	if err != nil {
+		log.Printf("return pass redemptions error: %v", err)
+		return
+	}
+	for _, red := range returned {
+		s.audit.Record(ctx, "client_pass", red.ClientPassID, "return", red, nil)
+	}
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// promoDiscount is a discount produced by one promotion for a bill line,
+// line -1 stands for the discount of the whole booking.
+type promoDiscount struct {
+	line        int
+	promotionID int
+	amount      int
+}
+
+// promotionPlan is the result of applying a set of promotions to a bill.
+type promotionPlan struct {
+	percent     []int
+	linePromo   []int
+	amount      int
+	amountPromo int
+	names       []string
+	discounts   []promoDiscount
+	benefit     int
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// billCost is what the bill costs after line and booking discounts.
+func billCost(b *models.Booking) int {
+	cost := 0.0
+	for _, it := range b.Items {
+		cost += lineCost(it)
+	}
+	return int(math.Round(cost)) - b.Discount
+}
+
+// promotionMatches checks the conditions of a promotion which concern the
+// whole booking: promo code, dates, week days, time window, min spend and
+// client visits.
+func promotionMatches(p *models.Promotion, b *models.Booking, subtotal, visits int) bool {
+	if p.Code != "" && p.Code != b.PromoCode {
+		return false
+	}
+	loc, err := time.LoadLocation("Asia/Almaty")
+	if err != nil {
+		loc = time.Local
+	}
+	start := b.StartTime.In(loc)
+	if p.ValidFrom != nil && start.Before(*p.ValidFrom) {
+		return false
+	}
+	if p.ValidTo != nil && start.After(*p.ValidTo) {
+		return false
+	}
+	if p.DaysOfWeek != "" {
+		day := int(start.Weekday())
+		if day == 0 {
+			day = 7
+		}
+		if !strings.ContainsRune(p.DaysOfWeek, rune('0'+day)) {
+			return false
+		}
+	}
+	hm := start.Format("15:04")
+	if p.TimeFrom != "" && hm < p.TimeFrom {
+		return false
+	}
+	if p.TimeTo != "" && hm >= p.TimeTo {
+		return false
+	}
+	return subtotal >= p.MinSpend && visits >= p.MinClientVisits
+}
+
+// planPromotions applies promotions together. Percent promotions add up on
+// lines without a manual discount up to 100%, amount promotions are taken
+// off the booking when it has no manual discount.
+func planPromotions(list []models.Promotion, b *models.Booking, categories []int) *promotionPlan {
+	plan := &promotionPlan{
+		percent:   make([]int, len(b.Items)),
+		linePromo: make([]int, len(b.Items)),
+	}
+	cost := 0.0
+	for _, p := range list {
+		if p.Kind != models.PromotionPercent {
+			continue
+		}
+		used := false
+		for i, it := range b.Items {
+			if it.Discount > 0 || it.Price <= 0 || plan.percent[i] >= 100 {
+				continue
+			}
+			if (p.ItemID > 0 && p.ItemID != it.ItemID) || (p.CategoryID > 0 && p.CategoryID != categories[i]) {
+				continue
+			}
+			pct := p.Value
+			if plan.percent[i]+pct > 100 {
+				pct = 100 - plan.percent[i]
+			}
+			plan.percent[i] += pct
+			if plan.linePromo[i] == 0 {
+				plan.linePromo[i] = p.ID
+			}
+			amount := int(math.Round(float64(it.Price) * float64(pct) / 100))
+			plan.discounts = append(plan.discounts, promoDiscount{line: i, promotionID: p.ID, amount: amount})
+			plan.benefit += amount
+			used = true
+		}
+		if used {
+			plan.names = append(plan.names, p.Name)
+		}
+	}
+	for i, it := range b.Items {
+		it.Discount += plan.percent[i]
+		cost += lineCost(it)
+	}
+	if b.Discount > 0 {
+		return plan
+	}
+	rest := int(math.Round(cost))
+	for _, p := range list {
+		if p.Kind != models.PromotionAmount || rest <= 0 {
+			continue
+		}
+		amount := p.Value
+		if amount > rest {
+			amount = rest
+		}
+		rest -= amount
+		plan.amount += amount
+		if plan.amountPromo == 0 {
+			plan.amountPromo = p.ID
+		}
+		plan.names = append(plan.names, p.Name)
+		plan.discounts = append(plan.discounts, promoDiscount{line: -1, promotionID: p.ID, amount: amount})
+		plan.benefit += amount
+	}
+	return plan
+}
+
+// bestPlan picks promotions for the bill. A non-stackable promotion is
+// never combined with others: the best of each such promotion alone and of
+// all stackable ones together wins.
+func bestPlan(matched []models.Promotion, b *models.Booking, categories []int) *promotionPlan {
+	// акции отсортированы по приоритету, при равной выгоде остается первая
+	var best *promotionPlan
+	var stackable []models.Promotion
+	for _, p := range matched {
+		if p.Stackable {
+			stackable = append(stackable, p)
+			continue
+		}
+		if plan := planPromotions([]models.Promotion{p}, b, categories); best == nil || plan.benefit > best.benefit {
+			best = plan
+		}
+	}
+	if len(stackable) > 0 {
+		if plan := planPromotions(stackable, b, categories); best == nil || plan.benefit > best.benefit {
+			best = plan
+		}
+	}
+	return best
+}
+
+// applyPromotions recalculates discounts given by promotions. Discounts set
+// by hand stay as they are. A non-stackable promotion is never combined with
+// others: the best of each such promotion alone and of all stackable ones
+// together wins, on a tie the one with higher priority. The booking total
+// changes by the difference in discounts.
+func (s *BookingService) applyPromotions(ctx context.Context, b *models.Booking) ([]promoDiscount, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	before := billCost(b)
+	for i := range b.Items {
+		if b.Items[i].PromotionID > 0 {
+			b.Items[i].Discount = 0
+			b.Items[i].PromotionID = 0
+		}
+	}
+	if b.PromotionID > 0 {
+		b.Discount = 0
+		b.DiscountReason = ""
+		b.PromotionID = 0
+	}
+	b.PromoCode = normalizePromoCode(b.PromoCode)
+
+	promos, err := s.promotionRepo.GetActive(ctx, companyID, branchID, b.PromoCode)
+	if err != nil {
+		return nil, err
+	}
+	subtotal := 0
+	for _, it := range b.Items {
+		subtotal += it.Price
+	}
+	visits := -1
+	var matched []models.Promotion
+	for _, p := range promos {
+		if p.MinClientVisits > 0 && visits < 0 {
+			visits = 0
+			if b.ClientID > 0 {
+				if c, err := s.clientRepo.GetByID(ctx, b.ClientID); err == nil {
+					visits = c.Visits
+				}
+			}
+		}
+		if promotionMatches(&p, b, subtotal, visits) {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) == 0 {
+		b.TotalAmount += billCost(b) - before
+		return nil, nil
+	}
+
+	categories := make([]int, len(b.Items))
+	for i, it := range b.Items {
+		pi, err := s.priceItemRepo.GetByID(ctx, it.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		categories[i] = pi.CategoryID
+	}
+	best := bestPlan(matched, b, categories)
+	for i := range b.Items {
+		if best.percent[i] > 0 {
+			b.Items[i].Discount = best.percent[i]
+			b.Items[i].PromotionID = best.linePromo[i]
+		}
+	}
+	if best.amount > 0 {
+		b.Discount = best.amount
+		b.DiscountReason = strings.Join(best.names, ", ")
+		b.PromotionID = best.amountPromo
+	}
+	b.TotalAmount += billCost(b) - before
+	return best.discounts, nil
+}
+
+// promoteLine prices a line added to a running booking by promotions. The
+// rest of the bill keeps its discounts. Returned discounts refer to the
+// line as index 0.
+func (s *BookingService) promoteLine(ctx context.Context, companyID, branchID int, b *models.Booking, it *models.BookingItem) ([]promoDiscount, error) {
+	if it.Discount > 0 {
+		return nil, nil
+	}
+	items, err := s.bookingItemRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	bill := *b
+	bill.Items = append(activeItems(items), *it)
+	discounts, err := s.applyPromotions(ctx, &bill)
+	if err != nil {
+		return nil, err
+	}
+	last := len(bill.Items) - 1
+	it.Discount = bill.Items[last].Discount
+	it.PromotionID = bill.Items[last].PromotionID
+	var res []promoDiscount
+	for _, d := range discounts {
+		if d.line == last {
+			d.line = 0
+			res = append(res, d)
+		}
+	}
+	return res, nil
+}
+
+// moveLinePromotions points discounts of rewritten bill lines to the lines
+// they turned into, in proportion to the line prices. origin holds the former
+// line id of each line, prices the former line prices.
+func (s *BookingService) moveLinePromotions(ctx context.Context, companyID, branchID int, b *models.Booking, origin []int, prices map[int]int) {
+	old, err := s.promotionRepo.GetBookingPromotions(ctx, companyID, branchID, b.ID)
+	if err != nil || len(old) == 0 {
+		return
+	}
+	var list []models.BookingPromotion
+	for _, rec := range old {
+		price, moved := prices[rec.BookingItemID]
+		if rec.BookingItemID == 0 || !moved {
+			list = append(list, rec)
+			continue
+		}
+		for i, id := range origin {
+			if id != rec.BookingItemID || price == 0 {
+				continue
+			}
+			part := rec
+			part.BookingItemID = b.Items[i].ID
+			part.Amount = int(math.Round(float64(rec.Amount) * float64(b.Items[i].Price) / float64(price)))
+			list = append(list, part)
+		}
+	}
+	if err := s.promotionRepo.ReplaceBookingPromotions(ctx, companyID, branchID, b.ID, list); err != nil {
+		log.Printf("move booking promotions error: %v", err)
+	}
+}
+
+// takePromoCode takes a use of a promo code newly entered for a booking and
+// gives back the use of the code it replaces.
+func (s *BookingService) takePromoCode(ctx context.Context, code, oldCode string) error {
+	if code == oldCode {
+		return nil
+	}
+	if code != "" {
+		companyID := ctx.Value(common.CtxCompanyID).(int)
+		p, err := s.promotionRepo.GetByCode(ctx, companyID, code)
+		if err != nil {
+			return err
+		}
+		if p == nil || !p.IsActive {
+			return ErrInvalidPromoCode
+		}
+		ok, err := s.promotionRepo.UseCode(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrPromoCodeExhausted
+		}
+	}
+	s.releasePromoCode(ctx, oldCode)
+	return nil
+}
+
+// keepPromotionMarks carries over which discounts of an edited booking were
+// given by promotions, so they are recalculated and not taken for manual
+// ones when the client does not send promotion ids back.
+func keepPromotionMarks(b, current *models.Booking, currentItems []models.BookingItem) {
+	if b.PromotionID == 0 && current.PromotionID > 0 && b.Discount == current.Discount {
+		b.PromotionID = current.PromotionID
+	}
+	marks := make(map[int]models.BookingItem)
+	for _, it := range currentItems {
+		if it.PromotionID > 0 {
+			marks[it.ID] = it
+		}
+	}
+	for i := range b.Items {
+		it := &b.Items[i]
+		if o, ok := marks[it.ID]; ok && it.ID > 0 && it.PromotionID == 0 && it.Discount == o.Discount {
+			it.PromotionID = o.PromotionID
+		}
+	}
+}
+
+// releasePromoCode gives a use of the code back, e.g. when the booking is
+// cancelled.
+func (s *BookingService) releasePromoCode(ctx context.Context, code string) {
+	if code == "" {
+		return
+	}
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	p, err := s.promotionRepo.GetByCode(ctx, companyID, code)
+	if err != nil || p == nil {
+		return
+	}
+	if err := s.promotionRepo.ReleaseCode(ctx, p.ID); err != nil {
+		log.Printf("release promo code error: %v", err)
+	}
+}
+
+// savePromotions records which promotion produced each discount of a saved
+// booking. Line indexes are resolved to the line ids given on save.
+func (s *BookingService) savePromotions(ctx context.Context, b *models.Booking, discounts []promoDiscount) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if err := s.promotionRepo.ReplaceBookingPromotions(ctx, companyID, branchID, b.ID, promotionRecords(b, discounts)); err != nil {
+		log.Printf("save booking promotions error: %v", err)
+	}
+}
+
+func promotionRecords(b *models.Booking, discounts []promoDiscount) []models.BookingPromotion {
+	var list []models.BookingPromotion
+	for _, d := range discounts {
+		rec := models.BookingPromotion{BookingID: b.ID, PromotionID: d.promotionID, Amount: d.amount}
+		if d.line >= 0 {
+			rec.BookingItemID = b.Items[d.line].ID
+		}
+		list = append(list, rec)
+	}
+	return list
+}
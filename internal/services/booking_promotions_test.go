@@ -0,0 +1,238 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+// clubTime returns the time in the zone promotions are checked in.
+func clubTime(s string) time.Time {
+	loc, err := time.LoadLocation("Asia/Almaty")
+	if err != nil {
+		loc = time.Local
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestPromotionMatches(t *testing.T) {
+	from := clubTime("2026-10-01 00:00")
+	to := clubTime("2026-10-31 23:59")
+	tests := []struct {
+		name     string
+		p        models.Promotion
+		start    string
+		code     string
+		subtotal int
+		visits   int
+		want     bool
+	}{
+		{"no conditions", models.Promotion{}, "2026-10-19 12:00", "", 0, 0, true},
+		{"code entered", models.Promotion{Code: "AUTUMN"}, "2026-10-19 12:00", "AUTUMN", 0, 0, true},
+		{"code missing", models.Promotion{Code: "AUTUMN"}, "2026-10-19 12:00", "", 0, 0, false},
+		{"code other", models.Promotion{Code: "AUTUMN"}, "2026-10-19 12:00", "SPRING", 0, 0, false},
+		{"inside dates", models.Promotion{ValidFrom: &from, ValidTo: &to}, "2026-10-19 12:00", "", 0, 0, true},
+		{"before dates", models.Promotion{ValidFrom: &from, ValidTo: &to}, "2026-09-30 23:00", "", 0, 0, false},
+		{"after dates", models.Promotion{ValidFrom: &from, ValidTo: &to}, "2026-11-01 10:00", "", 0, 0, false},
+		{"weekday", models.Promotion{DaysOfWeek: "12345"}, "2026-10-19 12:00", "", 0, 0, true},
+		{"weekend only on monday", models.Promotion{DaysOfWeek: "67"}, "2026-10-19 12:00", "", 0, 0, false},
+		{"sunday is 7", models.Promotion{DaysOfWeek: "67"}, "2026-10-25 12:00", "", 0, 0, true},
+		{"window start", models.Promotion{TimeFrom: "10:00", TimeTo: "16:00"}, "2026-10-19 10:00", "", 0, 0, true},
+		{"window inside", models.Promotion{TimeFrom: "10:00", TimeTo: "16:00"}, "2026-10-19 15:59", "", 0, 0, true},
+		{"before window", models.Promotion{TimeFrom: "10:00", TimeTo: "16:00"}, "2026-10-19 09:59", "", 0, 0, false},
+		{"window end excluded", models.Promotion{TimeFrom: "10:00", TimeTo: "16:00"}, "2026-10-19 16:00", "", 0, 0, false},
+		{"only start of window", models.Promotion{TimeFrom: "22:00"}, "2026-10-19 23:30", "", 0, 0, true},
+		{"min spend reached", models.Promotion{MinSpend: 5000}, "2026-10-19 12:00", "", 5000, 0, true},
+		{"min spend not reached", models.Promotion{MinSpend: 5000}, "2026-10-19 12:00", "", 4999, 0, false},
+		{"enough visits", models.Promotion{MinClientVisits: 5}, "2026-10-19 12:00", "", 0, 5, true},
+		{"not enough visits", models.Promotion{MinClientVisits: 5}, "2026-10-19 12:00", "", 0, 4, false},
+	}
+	for _, tt := range tests {
+		b := &models.Booking{StartTime: clubTime(tt.start), PromoCode: tt.code}
+		if got := promotionMatches(&tt.p, b, tt.subtotal, tt.visits); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanPromotions(t *testing.T) {
+	items := func(lines ...models.BookingItem) *models.Booking {
+		return &models.Booking{Items: lines}
+	}
+	tests := []struct {
+		name       string
+		list       []models.Promotion
+		b          *models.Booking
+		categories []int
+		percent    []int
+		amount     int
+		benefit    int
+	}{
+		{
+			name:       "percent on every line",
+			list:       []models.Promotion{{ID: 1, Kind: models.PromotionPercent, Value: 10}},
+			b:          items(models.BookingItem{ItemID: 1, Price: 1000}, models.BookingItem{ItemID: 2, Price: 2000}),
+			categories: []int{1, 1},
+			percent:    []int{10, 10},
+			benefit:    300,
+		},
+		{
+			name:       "percent for an item",
+			list:       []models.Promotion{{ID: 1, Kind: models.PromotionPercent, Value: 10, ItemID: 2}},
+			b:          items(models.BookingItem{ItemID: 1, Price: 1000}, models.BookingItem{ItemID: 2, Price: 2000}),
+			categories: []int{1, 1},
+			percent:    []int{0, 10},
+			benefit:    200,
+		},
+		{
+			name:       "percent for a category",
+			list:       []models.Promotion{{ID: 1, Kind: models.PromotionPercent, Value: 20, CategoryID: 5}},
+			b:          items(models.BookingItem{ItemID: 1, Price: 1000}, models.BookingItem{ItemID: 2, Price: 2000}),
+			categories: []int{5, 6},
+			percent:    []int{20, 0},
+			benefit:    200,
+		},
+		{
+			name:       "manual discount is kept",
+			list:       []models.Promotion{{ID: 1, Kind: models.PromotionPercent, Value: 10}},
+			b:          items(models.BookingItem{ItemID: 1, Price: 1000, Discount: 5}, models.BookingItem{ItemID: 2, Price: 2000}),
+			categories: []int{1, 1},
+			percent:    []int{0, 10},
+			benefit:    200,
+		},
+		{
+			name: "percents add up to 100",
+			list: []models.Promotion{
+				{ID: 1, Kind: models.PromotionPercent, Value: 60},
+				{ID: 2, Kind: models.PromotionPercent, Value: 60},
+			},
+			b:          items(models.BookingItem{ItemID: 1, Price: 1000}),
+			categories: []int{1},
+			percent:    []int{100},
+			benefit:    1000,
+		},
+		{
+			name:       "amount off the booking",
+			list:       []models.Promotion{{ID: 1, Kind: models.PromotionAmount, Value: 500}},
+			b:          items(models.BookingItem{ItemID: 1, Price: 3000}),
+			categories: []int{1},
+			percent:    []int{0},
+			amount:     500,
+			benefit:    500,
+		},
+		{
+			name: "amount after percents is limited by the rest",
+			list: []models.Promotion{
+				{ID: 1, Kind: models.PromotionPercent, Value: 50},
+				{ID: 2, Kind: models.PromotionAmount, Value: 800},
+			},
+			b:          items(models.BookingItem{ItemID: 1, Price: 1000}),
+			categories: []int{1},
+			percent:    []int{50},
+			amount:     500,
+			benefit:    1000,
+		},
+		{
+			name:       "no amount over a manual booking discount",
+			list:       []models.Promotion{{ID: 1, Kind: models.PromotionAmount, Value: 500}},
+			b:          &models.Booking{Discount: 100, Items: []models.BookingItem{{ItemID: 1, Price: 3000}}},
+			categories: []int{1},
+			percent:    []int{0},
+		},
+	}
+	for _, tt := range tests {
+		plan := planPromotions(tt.list, tt.b, tt.categories)
+		for i, pct := range tt.percent {
+			if plan.percent[i] != pct {
+				t.Errorf("%s: line %d percent = %d, want %d", tt.name, i, plan.percent[i], pct)
+			}
+		}
+		if plan.amount != tt.amount {
+			t.Errorf("%s: amount = %d, want %d", tt.name, plan.amount, tt.amount)
+		}
+		if plan.benefit != tt.benefit {
+			t.Errorf("%s: benefit = %d, want %d", tt.name, plan.benefit, tt.benefit)
+		}
+	}
+}
+
+func TestBestPlanStacking(t *testing.T) {
+	b := &models.Booking{Items: []models.BookingItem{{ItemID: 1, Price: 1000}, {ItemID: 2, Price: 2000}}}
+	categories := []int{1, 1}
+	tests := []struct {
+		name    string
+		matched []models.Promotion
+		names   []string
+		benefit int
+	}{
+		{
+			name: "stackable ones add up",
+			matched: []models.Promotion{
+				{ID: 1, Name: "A", Kind: models.PromotionPercent, Value: 10, Stackable: true},
+				{ID: 2, Name: "B", Kind: models.PromotionAmount, Value: 500, Stackable: true},
+			},
+			names:   []string{"A", "B"},
+			benefit: 800,
+		},
+		{
+			name: "better single promotion wins over the stack",
+			matched: []models.Promotion{
+				{ID: 1, Name: "A", Kind: models.PromotionPercent, Value: 10, Stackable: true},
+				{ID: 2, Name: "B", Kind: models.PromotionAmount, Value: 500, Stackable: true},
+				{ID: 3, Name: "C", Kind: models.PromotionPercent, Value: 30},
+			},
+			names:   []string{"C"},
+			benefit: 900,
+		},
+		{
+			name: "stack wins over a weaker single promotion",
+			matched: []models.Promotion{
+				{ID: 1, Name: "A", Kind: models.PromotionPercent, Value: 10, Stackable: true},
+				{ID: 2, Name: "B", Kind: models.PromotionAmount, Value: 500, Stackable: true},
+				{ID: 3, Name: "C", Kind: models.PromotionPercent, Value: 20},
+			},
+			names:   []string{"A", "B"},
+			benefit: 800,
+		},
+		{
+			name: "single promotions are not combined",
+			matched: []models.Promotion{
+				{ID: 1, Name: "A", Kind: models.PromotionPercent, Value: 10},
+				{ID: 2, Name: "B", Kind: models.PromotionPercent, Value: 15},
+			},
+			names:   []string{"B"},
+			benefit: 450,
+		},
+		{
+			name: "on a tie the higher priority stays",
+			matched: []models.Promotion{
+				{ID: 1, Name: "A", Kind: models.PromotionAmount, Value: 300},
+				{ID: 2, Name: "B", Kind: models.PromotionPercent, Value: 10},
+				{ID: 3, Name: "C", Kind: models.PromotionAmount, Value: 300, Stackable: true},
+			},
+			names:   []string{"A"},
+			benefit: 300,
+		},
+	}
+	for _, tt := range tests {
+		plan := bestPlan(tt.matched, b, categories)
+		if plan.benefit != tt.benefit {
+			t.Errorf("%s: benefit = %d, want %d", tt.name, plan.benefit, tt.benefit)
+		}
+		if len(plan.names) != len(tt.names) {
+			t.Errorf("%s: promotions = %v, want %v", tt.name, plan.names, tt.names)
+			continue
+		}
+		for i, n := range tt.names {
+			if plan.names[i] != n {
+				t.Errorf("%s: promotions = %v, want %v", tt.name, plan.names, tt.names)
+				break
+			}
+		}
+	}
+}
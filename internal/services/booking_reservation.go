@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// refundsFor builds refund lines returning everything paid for the booking.
+// forfeitPercent of each deposit is kept by the club. Pass and certificate
+// payments are not refunded in money, they go back to the pass or
+// certificate.
+func refundsFor(b *models.Booking, pays []models.BookingPayment, forfeitPercent int) []models.BookingRefund {
+	paid := strings.ToLower(b.PaymentStatus) == "paid"
+	byType := make(map[int]int)
+	var order []int
+	for _, p := range pays {
+		if p.Method != "" {
+			continue
+		}
+		amount := 0
+		switch {
+		case p.IsDeposit:
+			amount = p.Amount * (100 - forfeitPercent) / 100
+		case paid:
+			amount = p.Amount
+		}
+		if amount <= 0 {
+			continue
+		}
+		if _, ok := byType[p.PaymentTypeID]; !ok {
+			order = append(order, p.PaymentTypeID)
+		}
+		byType[p.PaymentTypeID] += amount
+	}
+	var refunds []models.BookingRefund
+	for _, id := range order {
+		refunds = append(refunds, models.BookingRefund{PaymentTypeID: id, Amount: byType[id]})
+	}
+	return refunds
+}
+
+// AddDeposit records a prepayment for a reservation. Cash deposits go to the
+// cashbox right away under a separate operation.
+func (s *BookingService) AddDeposit(ctx context.Context, id int, p *models.BookingPayment) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if p.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	b, err := s.repo.GetByID(ctx, companyID, branchID, id)
+	if err != nil {
+		return err
+	}
+	if b.Status != models.BookingStatusReserved && b.Status != models.BookingStatusSeated {
+		return ErrInvalidBookingStatus
+	}
+	if _, err := s.paymentTypeRepo.GetByID(ctx, p.PaymentTypeID); err != nil {
+		return err
+	}
+	p.BookingID = id
+	p.IsDeposit = true
+	if err := s.paymentRepo.Create(ctx, companyID, branchID, id, []models.BookingPayment{*p}); err != nil {
+		return err
+	}
+	if s.cashboxService != nil && s.isCashPayment(ctx, p.PaymentTypeID) {
+		_ = s.cashboxService.AddDeposit(ctx, float64(p.Amount))
+	}
+	s.audit.Record(ctx, "booking", id, "deposit", nil, p)
+	return nil
+}
+
+// holdsStock reports whether the items of a booking with the status are
+// taken from stock. A pending online request takes nothing until an admin
+// confirms it.
+func holdsStock(status string) bool {
+	return status != models.BookingStatusPending
+}
+
+// ConfirmBooking turns a pending online request into a reservation. The
+// table is checked again with the current cleanup buffer and items of the
+// request are taken from stock at this point.
+func (s *BookingService) ConfirmBooking(ctx context.Context, id int) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	b, err := s.repo.GetByID(ctx, companyID, branchID, id)
+	if err != nil {
+		return err
+	}
+	if b.Status != models.BookingStatusPending {
+		return ErrInvalidBookingStatus
+	}
+	if b.TableID > 0 {
+		ok, err := s.repo.IsTableAvailable(ctx, companyID, branchID, b.TableID, b.ID, b.StartTime, b.EndTime)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrTableBusy
+		}
+	}
+	items, err := s.bookingItemRepo.GetByBookingID(ctx, companyID, branchID, id)
+	if err != nil {
+		return err
+	}
+	items = activeItems(items)
+	if err := s.checkStock(ctx, items); err != nil {
+		return err
+	}
+	if err := s.decreaseStock(ctx, items); err != nil {
+		return err
+	}
+	if err := s.moveStatus(ctx, id, models.BookingStatusPending, models.BookingStatusReserved); err != nil {
+		s.increaseStock(ctx, items)
+		return err
+	}
+	return nil
+}
+
+// SeatBooking marks that the guests of a reservation have arrived. Prepared
+// items ordered in advance go to the stations now.
+func (s *BookingService) SeatBooking(ctx context.Context, id int) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if err := s.moveStatus(ctx, id, models.BookingStatusReserved, models.BookingStatusSeated); err != nil {
+		return err
+	}
+	b, err := s.repo.GetByID(ctx, companyID, branchID, id)
+	if err != nil {
+		return err
+	}
+	items, err := s.bookingItemRepo.GetByBookingID(ctx, companyID, branchID, id)
+	if err != nil {
+		return err
+	}
+	s.tickets.CreateForLines(ctx, b, items)
+	return nil
+}
+
+// CompleteBooking closes a seated reservation. Bonuses and the visit are
+// counted for the client at this point.
+func (s *BookingService) CompleteBooking(ctx context.Context, id int) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if err := s.moveStatus(ctx, id, models.BookingStatusSeated, models.BookingStatusCompleted); err != nil {
+		return err
+	}
+	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
+	if err != nil {
+		return err
+	}
+	b, err := s.repo.GetByID(ctx, companyID, branchID, id)
+	if err != nil {
+		return err
+	}
+	b.Payments, _ = s.paymentRepo.GetByBookingID(ctx, companyID, branchID, id)
+	if err := s.loadShares(ctx, companyID, branchID, b); err != nil {
+		return err
+	}
+	s.applyClientTotals(ctx, b, settings)
+	return nil
+}
+
+// MarkNoShow closes a reservation whose guests did not come. It is allowed
+// once the grace period after the start time has passed. The deposit is
+// refunded except for the forfeit percent from settings.
+func (s *BookingService) MarkNoShow(ctx context.Context, id int, reason string) (*models.Booking, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.repo.GetByID(ctx, companyID, branchID, id)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status != models.BookingStatusReserved {
+		return nil, ErrInvalidBookingStatus
+	}
+	grace := b.StartTime.Add(time.Duration(settings.ReservationGraceMinutes) * time.Minute)
+	if time.Now().Before(grace) {
+		return nil, ErrNoShowTooEarly
+	}
+	pays, err := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, id)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = "Неявка"
+	}
+	return s.CancelBooking(ctx, id, &models.BookingCancellation{
+		Status:  models.BookingStatusNoShow,
+		Reason:  reason,
+		Refunds: refundsFor(b, pays, settings.DepositForfeitPercent),
+	})
+}
+
+func (s *BookingService) moveStatus(ctx context.Context, id int, from, to string) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	ok, err := s.repo.SetStatus(ctx, companyID, branchID, id, from, to)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// бронь не найдена или находится в другом статусе
+		if _, err := s.repo.GetByID(ctx, companyID, branchID, id); err != nil {
+			return err
+		}
+		return ErrInvalidBookingStatus
+	}
+	s.audit.Record(ctx, "booking", id, to, map[string]string{"status": from}, map[string]string{"status": to})
+	return nil
+}
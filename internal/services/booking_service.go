@@ -23,10 +23,20 @@ type BookingService struct {
 	categoryRepo    *repositories.CategoryRepository
 	paymentRepo     *repositories.BookingPaymentRepository
 	paymentTypeRepo *repositories.PaymentTypeRepository
+	refundRepo      *repositories.BookingRefundRepository
+	ledgerRepo      *repositories.BookingLedgerRepository
+	segmentRepo     *repositories.BookingSegmentRepository
+	shareRepo       *repositories.BookingShareRepository
+	passRepo        *repositories.PassRepository
+	certificateRepo *repositories.GiftCertificateRepository
+	promotionRepo   *repositories.PromotionRepository
+	walletRepo      *repositories.WalletRepository
 	cashboxService  *CashboxService
+	audit           *AuditService
+	tickets         *TicketService
 }
 
-func NewBookingService(r *repositories.BookingRepository, itemRepo *repositories.BookingItemRepository, clientRepo *repositories.ClientRepository, settingsRepo *repositories.SettingsRepository, priceRepo *repositories.PriceItemRepository, setRepo *repositories.PriceSetRepository, categoryRepo *repositories.CategoryRepository, paymentRepo *repositories.BookingPaymentRepository, ptRepo *repositories.PaymentTypeRepository, cbService *CashboxService) *BookingService {
+func NewBookingService(r *repositories.BookingRepository, itemRepo *repositories.BookingItemRepository, clientRepo *repositories.ClientRepository, settingsRepo *repositories.SettingsRepository, priceRepo *repositories.PriceItemRepository, setRepo *repositories.PriceSetRepository, categoryRepo *repositories.CategoryRepository, paymentRepo *repositories.BookingPaymentRepository, ptRepo *repositories.PaymentTypeRepository, refundRepo *repositories.BookingRefundRepository, ledgerRepo *repositories.BookingLedgerRepository, segmentRepo *repositories.BookingSegmentRepository, shareRepo *repositories.BookingShareRepository, passRepo *repositories.PassRepository, certRepo *repositories.GiftCertificateRepository, promotionRepo *repositories.PromotionRepository, walletRepo *repositories.WalletRepository, cbService *CashboxService, audit *AuditService, tickets *TicketService) *BookingService {
 	return &BookingService{
 		repo:            r,
 		bookingItemRepo: itemRepo,
@@ -37,7 +47,17 @@ func NewBookingService(r *repositories.BookingRepository, itemRepo *repositories
 		categoryRepo:    categoryRepo,
 		paymentRepo:     paymentRepo,
 		paymentTypeRepo: ptRepo,
+		refundRepo:      refundRepo,
+		ledgerRepo:      ledgerRepo,
+		segmentRepo:     segmentRepo,
+		shareRepo:       shareRepo,
+		passRepo:        passRepo,
+		certificateRepo: certRepo,
+		promotionRepo:   promotionRepo,
+		walletRepo:      walletRepo,
 		cashboxService:  cbService,
+		audit:           audit,
+		tickets:         tickets,
 	}
 }
 
@@ -55,6 +75,8 @@ func bookingsEqual(old *models.Booking, newB *models.Booking, oldItems []models.
 		old.Note != newB.Note ||
 		old.Discount != newB.Discount ||
 		old.DiscountReason != newB.DiscountReason ||
+		old.PromoCode != newB.PromoCode ||
+		old.PromotionID != newB.PromotionID ||
 		old.TotalAmount != newB.TotalAmount ||
 		old.BonusUsed != newB.BonusUsed ||
 		old.PaymentStatus != newB.PaymentStatus ||
@@ -66,13 +88,14 @@ func bookingsEqual(old *models.Booking, newB *models.Booking, oldItems []models.
 		return false
 	}
 
+	// одна позиция может быть в счете несколькими строками
 	m := make(map[int]models.BookingItem)
 	for _, it := range oldItems {
-		m[it.ItemID] = it
+		m[it.ID] = it
 	}
 	for _, it := range newB.Items {
-		o, ok := m[it.ItemID]
-		if !ok || o.Quantity != it.Quantity || o.Price != it.Price || o.Discount != it.Discount {
+		o, ok := m[it.ID]
+		if !ok || it.ID == 0 || o.ItemID != it.ItemID || o.Quantity != it.Quantity || o.Price != it.Price || o.Discount != it.Discount || o.PromotionID != it.PromotionID {
 			return false
 		}
 	}
@@ -123,9 +146,29 @@ func (s *BookingService) isPastDayBlockedForCreate(start time.Time, blockMinutes
 func (s *BookingService) CreateBooking(ctx context.Context, b *models.Booking) (int, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
+	switch b.Status {
+	case "":
+		b.Status = models.BookingStatusActive
+	case models.BookingStatusActive:
+	case models.BookingStatusReserved:
+		// оплаты при создании предварительной брони считаются депозитом
+		for i := range b.Payments {
+			b.Payments[i].IsDeposit = true
+		}
+	case models.BookingStatusPending:
+		// заявка с сайта ничего не оплачивает до подтверждения
+		b.Payments = nil
+		b.PaymentStatus = "UNPAID"
+	default:
+		return 0, ErrInvalidBookingStatus
+	}
+	// оплата со счета клиента списывается с него после создания брони
+	var walletAmount int
+	b.Payments, walletAmount = splitWalletPayments(b.Payments)
 	if len(b.Payments) > 0 {
 		b.PaymentTypeID = b.Payments[0].PaymentTypeID
 	}
+	stampItems(ctx, b.Items)
 	// получить настройки для бонуса
 	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
 	if err != nil {
@@ -141,59 +184,109 @@ func (s *BookingService) CreateBooking(ctx context.Context, b *models.Booking) (
 			return 0, err
 		}
 		if !ok {
-			return 0, errors.New("выбранное время занято")
+			return 0, ErrTableBusy
 		}
 	}
 	if err := s.checkStock(ctx, b.Items); err != nil {
 		log.Printf("check stock error: %v", err)
 		return 0, err
 	}
+	discounts, err := s.applyPromotions(ctx, b)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.checkWallet(ctx, b.ClientID, walletAmount); err != nil {
+		return 0, err
+	}
 	id, err := s.repo.CreateWithItems(ctx, companyID, branchID, b)
 	if err != nil {
 		log.Printf("repository create error: %v", err)
 		return 0, err
 	}
-	if err := s.decreaseStock(ctx, b.Items); err != nil {
-		_ = s.repo.Delete(ctx, companyID, branchID, id)
-		log.Printf("decrease stock error: %v", err)
+	b.ID = id
+	// при ошибке все сделанное для брони отменяется в обратном порядке
+	var undo []func()
+	fail := func(err error) (int, error) {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
 		return 0, err
 	}
-	if b.ClientID > 0 {
-		// Списываем использованные бонусы
-		if b.BonusUsed > 0 {
-			_ = s.clientRepo.AddBonus(ctx, b.ClientID, -b.BonusUsed)
+	undo = append(undo, func() { _ = s.repo.Delete(ctx, companyID, branchID, id) })
+	if err := s.takePromoCode(ctx, b.PromoCode, ""); err != nil {
+		return fail(err)
+	}
+	undo = append(undo, func() { s.releasePromoCode(ctx, b.PromoCode) })
+	// заявка с сайта списывает товары со склада только при подтверждении
+	if holdsStock(b.Status) {
+		if err := s.decreaseStock(ctx, b.Items); err != nil {
+			log.Printf("decrease stock error: %v", err)
+			return fail(err)
 		}
-		// Начисляем бонусы с суммы, оплаченной деньгами
-		paid := b.TotalAmount - b.BonusUsed
-		if paid < 0 {
-			paid = 0
+		undo = append(undo, func() { s.increaseStock(ctx, b.Items) })
+	}
+	var walletPay *models.BookingPayment
+	if walletAmount > 0 {
+		if walletPay, err = s.chargeWallet(ctx, b, walletAmount); err != nil {
+			return fail(err)
 		}
-		bonus := int(float64(paid) * float64(settings.BonusPercent) / 100)
-		_ = s.clientRepo.AddBonus(ctx, b.ClientID, bonus)
-		_ = s.clientRepo.AddVisits(ctx, b.ClientID, 1)
-		_ = s.clientRepo.AddIncome(ctx, b.ClientID, b.TotalAmount)
+		undo = append(undo, func() { s.returnWallet(ctx, companyID, branchID, id) })
 	}
-
 	if err := s.paymentRepo.Create(ctx, companyID, branchID, id, b.Payments); err != nil {
-		_ = s.repo.Delete(ctx, companyID, branchID, id)
-		return 0, err
+		return fail(err)
+	}
+	// визит предварительной брони засчитывается клиенту при ее закрытии
+	if b.Status == models.BookingStatusActive {
+		s.applyClientTotals(ctx, b, settings)
 	}
 
-	if strings.ToLower(b.PaymentStatus) == "paid" && s.cashboxService != nil {
+	if s.cashboxService != nil && b.Status == models.BookingStatusReserved {
 		for _, p := range b.Payments {
-			pt, err := s.paymentTypeRepo.GetByID(ctx, p.PaymentTypeID)
-			if err != nil {
-				continue
+			if s.isCashPayment(ctx, p.PaymentTypeID) {
+				_ = s.cashboxService.AddDeposit(ctx, float64(p.Amount))
 			}
-			name := strings.ToLower(pt.Name)
-			if strings.Contains(name, "наличными") {
+		}
+	} else if strings.ToLower(b.PaymentStatus) == "paid" && s.cashboxService != nil {
+		for _, p := range b.Payments {
+			if s.isCashPayment(ctx, p.PaymentTypeID) {
 				_ = s.cashboxService.AddIncome(ctx, float64(p.Amount))
 			}
 		}
 	}
+	if walletPay != nil {
+		b.Payments = append(b.Payments, *walletPay)
+	}
+	s.savePromotions(ctx, b, discounts)
+	if b.Status == models.BookingStatusActive {
+		s.tickets.CreateForLines(ctx, b, b.Items)
+	}
+	s.audit.Record(ctx, "booking", id, "create", nil, b)
 	return id, nil
 }
 
+// applyClientTotals writes off used bonuses, accrues new ones and counts the
+// visit for the booking client or for every participant of a split bill.
+func (s *BookingService) applyClientTotals(ctx context.Context, b *models.Booking, settings *models.Settings) {
+	for _, p := range participants(b) {
+		if p.ClientID <= 0 {
+			continue
+		}
+		// Списываем использованные бонусы
+		if p.BonusUsed > 0 {
+			_ = s.clientRepo.AddBonus(ctx, p.ClientID, -p.BonusUsed)
+		}
+		// Начисляем бонусы с суммы, оплаченной деньгами
+		paid := p.Amount - p.BonusUsed
+		if paid < 0 {
+			paid = 0
+		}
+		bonus := int(float64(paid) * float64(settings.BonusPercent) / 100)
+		_ = s.clientRepo.AddBonus(ctx, p.ClientID, bonus)
+		_ = s.clientRepo.AddVisits(ctx, p.ClientID, 1)
+		_ = s.clientRepo.AddIncome(ctx, p.ClientID, p.Amount)
+	}
+}
+
 func (s *BookingService) GetAllBookings(ctx context.Context) ([]models.Booking, error) {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
@@ -263,6 +356,12 @@ func (s *BookingService) GetBookingByID(ctx context.Context, id int) (*models.Bo
 	if len(pays) > 0 {
 		b.PaymentType = pays[0].PaymentType
 	}
+	if b.Status != models.BookingStatusActive {
+		b.Refunds, _ = s.refundRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
+	}
+	b.Segments, _ = s.segmentRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
+	b.Promotions, _ = s.promotionRepo.GetBookingPromotions(ctx, companyID, branchID, b.ID)
+	_ = s.loadShares(ctx, companyID, branchID, b)
 	return b, nil
 }
 
@@ -277,6 +376,15 @@ func (s *BookingService) UpdateBooking(ctx context.Context, b *models.Booking) e
 	if err != nil {
 		return err
 	}
+	if !models.IsOpenBookingStatus(current.Status) {
+		return ErrBookingNotActive
+	}
+	if shares, err := s.shareRepo.GetByBookingID(ctx, companyID, branchID, b.ID); err != nil {
+		return err
+	} else if len(shares) > 0 {
+		return ErrBookingSplit
+	}
+	b.Status = current.Status
 	if s.isPastDayBlocked(current.StartTime, settings.BlockTime) {
 		return errors.New("изменение брони невозможно, дата прошла и время заблокировано")
 	}
@@ -286,12 +394,47 @@ func (s *BookingService) UpdateBooking(ctx context.Context, b *models.Booking) e
 			return err
 		}
 		if !ok {
-			return errors.New("выбранное время занято")
+			return ErrTableBusy
+		}
+	}
+	allItems, _ := s.bookingItemRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
+	currentItems := activeItems(allItems)
+	b.Items = activeItems(b.Items)
+	keepLineAuthors(b.Items, currentItems)
+	keepLaterLines(b, currentItems)
+	stampItems(ctx, b.Items)
+	allPays, _ := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
+	current.Payments = allPays
+	// депозиты и оплаты абонементом, сертификатом или со счета клиента
+	// вносятся отдельно и при изменении брони сохраняются
+	var deposits, currentPays []models.BookingPayment
+	for _, p := range allPays {
+		if p.IsDeposit || p.Method != "" {
+			deposits = append(deposits, p)
+		} else {
+			currentPays = append(currentPays, p)
+		}
+	}
+	var regular []models.BookingPayment
+	walletAmount := 0
+	for _, p := range b.Payments {
+		switch {
+		case p.Method == models.PaymentMethodWallet && p.ID == 0:
+			// новая оплата со счета клиента
+			walletAmount += p.Amount
+		case !p.IsDeposit && p.Method == "":
+			regular = append(regular, p)
 		}
 	}
-	currentItems, _ := s.bookingItemRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
-	currentPays, _ := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
-	current.Payments = currentPays
+	b.Payments = regular
+	if err := s.checkWallet(ctx, b.ClientID, walletAmount); err != nil {
+		return err
+	}
+	keepPromotionMarks(b, current, currentItems)
+	discounts, err := s.applyPromotions(ctx, b)
+	if err != nil {
+		return err
+	}
 
 	equal := bookingsEqual(current, b, currentItems)
 	if equal && len(currentPays) == len(b.Payments) {
@@ -302,34 +445,72 @@ func (s *BookingService) UpdateBooking(ctx context.Context, b *models.Booking) e
 				break
 			}
 		}
-		if eq {
+		if eq && walletAmount == 0 {
 			return nil
 		}
 	}
 
 	if len(b.Payments) > 0 {
 		b.PaymentTypeID = b.Payments[0].PaymentTypeID
+	} else {
+		for _, p := range deposits {
+			if p.PaymentTypeID > 0 {
+				b.PaymentTypeID = p.PaymentTypeID
+				break
+			}
+		}
 	}
 	limit := current.EndTime.Add(time.Duration(settings.BlockTime) * time.Minute)
 	if time.Now().After(limit) {
 		return errors.New("удаление брони невозможно, дата прошла и время заблокировано")
 	}
 
-	s.increaseStock(ctx, currentItems)
-
-	if err := s.checkStock(ctx, b.Items); err != nil {
-		err := s.decreaseStock(ctx, currentItems)
-		if err != nil {
+	if err := s.takePromoCode(ctx, b.PromoCode, current.PromoCode); err != nil {
+		return err
+	}
+	saved := false
+	defer func() {
+		// вернуть прежний промокод, если бронь не сохранилась
+		if !saved {
+			_ = s.takePromoCode(ctx, current.PromoCode, b.PromoCode)
+		}
+	}()
+	// оплата со счета клиента списывается до сохранения брони и
+	// возвращается, если бронь не сохранилась
+	var walletPay *models.BookingPayment
+	if walletAmount > 0 {
+		if walletPay, err = s.chargeWallet(ctx, b, walletAmount); err != nil {
 			return err
 		}
-		return err
+		defer func() {
+			if !saved {
+				s.returnWalletPayment(ctx, companyID, branchID, b.ID, walletPay.ID)
+			}
+		}()
 	}
-	if err := s.decreaseStock(ctx, b.Items); err != nil {
-		err := s.decreaseStock(ctx, currentItems)
-		if err != nil {
+
+	// новые строки и прибавленное количество уходят на станции после сохранения
+	toStations := ticketQuantities(b.Items, currentItems)
+
+	// склад двигается только по добавленным, измененным и удаленным строкам;
+	// заявка с сайта не держит товары на складе до подтверждения
+	released, taken := stockChanges(b.Items, currentItems)
+	hold := holdsStock(current.Status)
+	if hold {
+		s.increaseStock(ctx, released)
+
+		if err := s.checkStock(ctx, taken); err != nil {
+			if rerr := s.decreaseStock(ctx, released); rerr != nil {
+				return rerr
+			}
+			return err
+		}
+		if err := s.decreaseStock(ctx, taken); err != nil {
+			if rerr := s.decreaseStock(ctx, released); rerr != nil {
+				return rerr
+			}
 			return err
 		}
-		return err
 	}
 	oldCash := 0.0
 	newCash := 0.0
@@ -338,17 +519,30 @@ func (s *BookingService) UpdateBooking(ctx context.Context, b *models.Booking) e
 		newCash = s.getCashAmount(ctx, b)
 	}
 
-	if err := s.repo.UpdateWithItems(ctx, companyID, branchID, b); err != nil {
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	removed, err := s.repo.UpdateWithItems(ctx, companyID, branchID, b, userID, removedLineReason)
+	if err != nil {
 		// rollback stock on failure
-		s.increaseStock(ctx, b.Items)
-		err := s.decreaseStock(ctx, currentItems)
-		if err != nil {
-			return err
+		if hold {
+			s.increaseStock(ctx, taken)
+			if rerr := s.decreaseStock(ctx, released); rerr != nil {
+				return rerr
+			}
 		}
 		return err
 	}
-	_ = s.paymentRepo.DeleteByBookingID(ctx, companyID, branchID, b.ID)
+	saved = true
+	s.releaseLines(ctx, companyID, branchID, b.ID, removed)
+	s.savePromotions(ctx, b, discounts)
+	_ = s.paymentRepo.DeleteRegularByBookingID(ctx, companyID, branchID, b.ID)
 	_ = s.paymentRepo.Create(ctx, companyID, branchID, b.ID, b.Payments)
+	if walletPay != nil {
+		deposits = append(deposits, *walletPay)
+	}
+	b.Payments = append(deposits, b.Payments...)
+	if b.TableID > 0 {
+		_ = s.segmentRepo.SyncBounds(ctx, companyID, branchID, b)
+	}
 	if s.cashboxService != nil {
 		diff := newCash - oldCash
 		if diff > 0 {
@@ -357,11 +551,27 @@ func (s *BookingService) UpdateBooking(ctx context.Context, b *models.Booking) e
 			_ = s.cashboxService.RemoveIncome(ctx, -diff)
 		}
 	}
+	if b.Status == models.BookingStatusActive || b.Status == models.BookingStatusSeated {
+		var added []models.BookingItem
+		for i := range b.Items {
+			if qty := toStations[i]; qty > 0 {
+				line := b.Items[i]
+				line.Quantity = qty
+				added = append(added, line)
+			}
+		}
+		s.tickets.CreateForLines(ctx, b, added)
+	}
+	current.Items = currentItems
+	s.audit.Record(ctx, "booking", b.ID, "update", current, b)
 	return nil
 
 }
 
-func (s *BookingService) DeleteBooking(ctx context.Context, id int) error {
+// DeleteBooking cancels booking and refunds everything paid except the part
+// of the deposit forfeited when the booking is cancelled too late.
+// Бронь больше не удаляется физически, чтобы история оставалась в отчетах.
+func (s *BookingService) DeleteBooking(ctx context.Context, id int, reason string) error {
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
 	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
@@ -372,47 +582,32 @@ func (s *BookingService) DeleteBooking(ctx context.Context, id int) error {
 	if err != nil {
 		return err
 	}
-	pays, _ := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, id)
-	b.Payments = pays
 	if s.isPastDayBlocked(b.StartTime, settings.BlockTime) {
 		return errors.New("booking can no longer be removed")
 	}
-	cash := 0.0
-	if s.cashboxService != nil {
-		cash = s.getCashAmount(ctx, b)
-	}
-	items, err := s.bookingItemRepo.GetByBookingID(ctx, companyID, branchID, id)
-	if err != nil {
-		return err
-	}
 	limit := b.EndTime.Add(time.Duration(settings.BlockTime) * time.Minute)
 	if time.Now().After(limit) {
 		return errors.New("booking can no longer be removed")
 	}
-	if err := s.repo.Delete(ctx, companyID, branchID, id); err != nil {
+	if reason == "" {
+		reason = "Удаление брони"
+	}
+	pays, err := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, id)
+	if err != nil {
 		return err
 	}
-	_ = s.paymentRepo.DeleteByBookingID(ctx, companyID, branchID, id)
-	s.increaseStock(ctx, items)
-
-	if b.ClientID > 0 {
-		// отменяем начисленные бонусы и возвращаем использованные
-		paid := b.TotalAmount - b.BonusUsed
-		if paid < 0 {
-			paid = 0
-		}
-		bonus := int(float64(paid) * float64(settings.BonusPercent) / 100)
-		_ = s.clientRepo.AddBonus(ctx, b.ClientID, -bonus)
-		if b.BonusUsed > 0 {
-			_ = s.clientRepo.AddBonus(ctx, b.ClientID, b.BonusUsed)
-		}
-		_ = s.clientRepo.AddVisits(ctx, b.ClientID, -1)
-		_ = s.clientRepo.AddIncome(ctx, b.ClientID, -b.TotalAmount)
+	// депозит возвращается полностью только при отмене заранее
+	forfeit := 0
+	if b.StartTime.Sub(time.Now()) < time.Duration(settings.DepositRefundHours)*time.Hour {
+		forfeit = settings.DepositForfeitPercent
 	}
-	if s.cashboxService != nil && cash > 0 {
-		_ = s.cashboxService.RemoveIncome(ctx, cash)
+	req := models.BookingCancellation{
+		Status:  models.BookingStatusCancelled,
+		Reason:  reason,
+		Refunds: refundsFor(b, pays, forfeit),
 	}
-	return nil
+	_, err = s.CancelBooking(ctx, id, &req)
+	return err
 }
 
 func (s *BookingService) decreaseStock(ctx context.Context, items []models.BookingItem) error {
@@ -556,6 +751,44 @@ func (s *BookingService) calculateSetQuantity(ctx context.Context, ps *models.Pr
 	return qty, nil
 }
 
+// EstimateBooking prices booking items by the price list and sets the total.
+// Hours items without quantity are charged for the booking duration.
+func (s *BookingService) EstimateBooking(ctx context.Context, b *models.Booking) error {
+	if !b.EndTime.After(b.StartTime) {
+		return ErrInvalidBookingTime
+	}
+	hours := b.EndTime.Sub(b.StartTime).Hours()
+	total := 0
+	for i := range b.Items {
+		it := &b.Items[i]
+		if it.Quantity < 0 {
+			return ErrInvalidAmount
+		}
+		pi, err := s.priceItemRepo.GetByID(ctx, it.ItemID)
+		if err != nil {
+			return err
+		}
+		isHours, err := s.isHoursCategory(ctx, pi.CategoryID)
+		if err != nil {
+			return err
+		}
+		if isHours && it.Quantity == 0 {
+			it.Quantity = hours
+		}
+		it.ItemName = pi.Name
+		it.ItemPrice = pi.SalePrice
+		it.Price = int(math.Round(pi.SalePrice * it.Quantity))
+		it.Discount = 0
+		it.PromotionID = 0
+		total += it.Price
+	}
+	b.TotalAmount = total
+	b.Discount = 0
+	b.PromotionID = 0
+	_, err := s.applyPromotions(ctx, b)
+	return err
+}
+
 func (s *BookingService) checkStock(ctx context.Context, items []models.BookingItem) error {
 	for _, it := range items {
 		pi, err := s.priceItemRepo.GetByID(ctx, it.ItemID)
@@ -598,8 +831,11 @@ func (s *BookingService) restoreChanges(ctx context.Context, changes []stockChan
 	_ = s.updateSetQuantities(ctx, affected)
 }
 
-func (s *BookingService) increaseStock(ctx context.Context, items []models.BookingItem) {
+// increaseStock returns items to stock and reports which of them were
+// actually restored (hours are not tracked in stock).
+func (s *BookingService) increaseStock(ctx context.Context, items []models.BookingItem) []models.BookingItem {
 	affected := make(map[int]struct{})
+	var restored []models.BookingItem
 
 	for _, it := range items {
 		pi, err := s.priceItemRepo.GetByID(ctx, it.ItemID)
@@ -616,6 +852,7 @@ func (s *BookingService) increaseStock(ctx context.Context, items []models.Booki
 
 		_ = s.priceItemRepo.IncreaseStock(ctx, it.ItemID, it.Quantity)
 		affected[it.ItemID] = struct{}{}
+		restored = append(restored, it)
 
 		if pi.IsSet {
 			set, err := s.priceSetRepo.GetByID(ctx, pi.ID)
@@ -638,6 +875,7 @@ func (s *BookingService) increaseStock(ctx context.Context, items []models.Booki
 	}
 
 	_ = s.updateSetQuantities(ctx, affected)
+	return restored
 }
 
 func (s *BookingService) getCashAmount(ctx context.Context, b *models.Booking) float64 {
@@ -646,14 +884,25 @@ func (s *BookingService) getCashAmount(ctx context.Context, b *models.Booking) f
 	}
 	total := 0.0
 	for _, p := range b.Payments {
-		pt, err := s.paymentTypeRepo.GetByID(ctx, p.PaymentTypeID)
-		if err != nil {
+		// депозит попадает в кассу в момент внесения
+		if p.IsDeposit {
 			continue
 		}
-		name := strings.ToLower(pt.Name)
-		if strings.Contains(name, "наличными") {
+		if s.isCashPayment(ctx, p.PaymentTypeID) {
 			total += float64(p.Amount)
 		}
 	}
 	return total
 }
+
+func (s *BookingService) isCashPayment(ctx context.Context, paymentTypeID int) bool {
+	pt, err := s.paymentTypeRepo.GetByID(ctx, paymentTypeID)
+	if err != nil {
+		return false
+	}
+	return isCashType(pt)
+}
+
+func isCashType(pt *models.PaymentType) bool {
+	return strings.Contains(strings.ToLower(pt.Name), "наличными")
+}
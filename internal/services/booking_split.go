@@ -0,0 +1,323 @@
+package services
+
+import (
+	"context"
+	"math"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// participants returns who pays the booking bill: the shares of a split
+// bill or the booking client alone.
+func participants(b *models.Booking) []models.BookingShare {
+	if len(b.Shares) > 0 {
+		return b.Shares
+	}
+	return []models.BookingShare{{ClientID: b.ClientID, Amount: b.TotalAmount, BonusUsed: b.BonusUsed}}
+}
+
+// loadShares attaches split shares to the booking, payments are taken from
+// b.Payments by share id.
+func (s *BookingService) loadShares(ctx context.Context, companyID, branchID int, b *models.Booking) error {
+	shares, err := s.shareRepo.GetByBookingID(ctx, companyID, branchID, b.ID)
+	if err != nil {
+		return err
+	}
+	for i := range shares {
+		for _, p := range b.Payments {
+			if p.ShareID == shares[i].ID {
+				shares[i].Payments = append(shares[i].Payments, p)
+			}
+		}
+	}
+	b.Shares = shares
+	return nil
+}
+
+// SplitBooking divides the bill of an open booking between several
+// participants: by items, in equal shares or by custom amounts. Every share
+// has its own client, bonus and payments, visits and income are counted per
+// participant. A repeated split replaces the previous one.
+func (s *BookingService) SplitBooking(ctx context.Context, id int, req *models.BookingSplit) (*models.Booking, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsOpenBookingStatus(b.Status) {
+		return nil, ErrBookingNotActive
+	}
+	shares := req.Shares
+	n := len(shares)
+	if n < 2 {
+		return nil, ErrInvalidSplit
+	}
+	switch req.Mode {
+	case models.SplitEqual:
+		equalAmounts(shares, b.TotalAmount)
+	case models.SplitByAmounts:
+		sum := 0
+		for i := range shares {
+			if shares[i].Amount < 0 {
+				return nil, ErrInvalidAmount
+			}
+			shares[i].Items = nil
+			sum += shares[i].Amount
+		}
+		if sum != b.TotalAmount {
+			return nil, ErrInvalidSplit
+		}
+	case models.SplitByItems:
+		if err := itemAmounts(b, shares); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidSplit
+	}
+
+	bonus, paid, deposits := 0, 0, 0
+	paymentTypeID := 0
+	for i := range shares {
+		sh := &shares[i]
+		if sh.BonusUsed < 0 || sh.BonusUsed > sh.Amount || (sh.BonusUsed > 0 && sh.ClientID <= 0) {
+			return nil, ErrInvalidAmount
+		}
+		if sh.ClientID > 0 {
+			if _, err := s.clientRepo.GetByID(ctx, sh.ClientID); err != nil {
+				return nil, err
+			}
+		}
+		sharePaid := 0
+		for j := range sh.Payments {
+			p := &sh.Payments[j]
+			if p.Amount <= 0 {
+				return nil, ErrInvalidAmount
+			}
+			if _, err := s.paymentTypeRepo.GetByID(ctx, p.PaymentTypeID); err != nil {
+				return nil, err
+			}
+			p.IsDeposit = false
+			if paymentTypeID == 0 {
+				paymentTypeID = p.PaymentTypeID
+			}
+			sharePaid += p.Amount
+		}
+		if sharePaid > sh.Amount-sh.BonusUsed {
+			return nil, ErrOverpayment
+		}
+		bonus += sh.BonusUsed
+		paid += sharePaid
+	}
+	var newPayments []models.BookingPayment
+	// депозиты и оплаты абонементом или сертификатом остаются за бронью
+	for _, p := range b.Payments {
+		if p.IsDeposit || p.Method != "" {
+			deposits += p.Amount
+			newPayments = append(newPayments, p)
+			if paymentTypeID == 0 {
+				paymentTypeID = p.PaymentTypeID
+			}
+		}
+	}
+	if paid+deposits > b.TotalAmount-bonus {
+		return nil, ErrOverpayment
+	}
+	for _, sh := range shares {
+		newPayments = append(newPayments, sh.Payments...)
+	}
+
+	before := *b
+	oldCash := 0.0
+	if s.cashboxService != nil {
+		oldCash = s.getCashAmount(ctx, b)
+	}
+	b.Shares = shares
+	b.BonusUsed = bonus
+	if paymentTypeID > 0 {
+		b.PaymentTypeID = paymentTypeID
+	}
+	if err := s.shareRepo.Replace(ctx, companyID, branchID, b); err != nil {
+		return nil, err
+	}
+	b.Payments = newPayments
+	// визит и бонусы по открытой брони уже начислены ее клиенту
+	if b.Status == models.BookingStatusActive {
+		s.reapplyClientTotals(ctx, companyID, branchID, &before, b, settings)
+	}
+	if s.cashboxService != nil {
+		diff := s.getCashAmount(ctx, b) - oldCash
+		if diff > 0 {
+			_ = s.cashboxService.AddIncome(ctx, diff)
+		} else if diff < 0 {
+			_ = s.cashboxService.RemoveIncome(ctx, -diff)
+		}
+	}
+	s.audit.Record(ctx, "booking", b.ID, "split", &before, b)
+	return s.GetBookingByID(ctx, b.ID)
+}
+
+// RemoveSplit joins a split bill back. Payments stay with the booking, bonus
+// redemptions of the participants are cancelled.
+func (s *BookingService) RemoveSplit(ctx context.Context, id int) (*models.Booking, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	settings, err := s.settingsRepo.Get(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.GetBookingByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsOpenBookingStatus(b.Status) {
+		return nil, ErrBookingNotActive
+	}
+	if len(b.Shares) == 0 {
+		return b, nil
+	}
+	if err := s.shareRepo.Remove(ctx, companyID, branchID, b.ID); err != nil {
+		return nil, err
+	}
+	before := *b
+	b.Shares = nil
+	b.BonusUsed = 0
+	if b.Status == models.BookingStatusActive {
+		s.reapplyClientTotals(ctx, companyID, branchID, &before, b, settings)
+	}
+	s.audit.Record(ctx, "booking", b.ID, "unsplit", &before, b)
+	return s.GetBookingByID(ctx, b.ID)
+}
+
+// clientTotals is what an open booking has counted for one client: the
+// bonus balance change, visits and income.
+type clientTotals struct {
+	bonus  int
+	visits int
+	income int
+}
+
+// totalsByClient returns what applyClientTotals counts for every client of
+// the booking.
+func totalsByClient(b *models.Booking, settings *models.Settings) map[int]clientTotals {
+	res := make(map[int]clientTotals)
+	for _, p := range participants(b) {
+		if p.ClientID <= 0 {
+			continue
+		}
+		paid := p.Amount - p.BonusUsed
+		if paid < 0 {
+			paid = 0
+		}
+		t := res[p.ClientID]
+		t.bonus += int(float64(paid)*float64(settings.BonusPercent)/100) - p.BonusUsed
+		t.visits++
+		t.income += p.Amount
+		res[p.ClientID] = t
+	}
+	return res
+}
+
+// reapplyClientTotals moves bonuses, visits and income of an open booking
+// from the old participants to the new ones. Only the difference per client
+// is applied, so a client who stays in the bill gets one ledger entry for
+// the recount instead of a cancel and a new accrual.
+func (s *BookingService) reapplyClientTotals(ctx context.Context, companyID, branchID int, before, after *models.Booking, settings *models.Settings) {
+	old := totalsByClient(before, settings)
+	cur := totalsByClient(after, settings)
+	for id := range old {
+		if _, ok := cur[id]; !ok {
+			cur[id] = clientTotals{}
+		}
+	}
+	for id, t := range cur {
+		o := old[id]
+		if d := t.bonus - o.bonus; d != 0 {
+			_ = s.clientRepo.AddBonus(ctx, id, d)
+			s.writeLedger(ctx, companyID, branchID, &models.BookingLedgerEntry{
+				BookingID: after.ID,
+				EntryType: models.LedgerBonus,
+				ClientID:  id,
+				Amount:    d,
+				Note:      "Перерасчет бонусов при разделении счета",
+			})
+		}
+		if d := t.visits - o.visits; d != 0 {
+			_ = s.clientRepo.AddVisits(ctx, id, d)
+		}
+		if d := t.income - o.income; d != 0 {
+			_ = s.clientRepo.AddIncome(ctx, id, d)
+		}
+	}
+}
+
+// equalAmounts divides total between shares, the remainder goes to the first.
+func equalAmounts(shares []models.BookingShare, total int) {
+	n := len(shares)
+	part := total / n
+	for i := range shares {
+		shares[i].Amount = part
+		shares[i].Items = nil
+	}
+	shares[0].Amount += total - part*n
+}
+
+// itemAmounts prices shares by the booking lines they took. Every line must
+// be distributed completely. The booking total (with its discount) is
+// divided in proportion to the lines cost.
+func itemAmounts(b *models.Booking, shares []models.BookingShare) error {
+	lines := make(map[int]models.BookingItem)
+	netTotal := 0.0
+	items := activeItems(b.Items)
+	for _, it := range items {
+		lines[it.ID] = it
+		netTotal += lineCost(it)
+	}
+	taken := make(map[int]float64)
+	costs := make([]float64, len(shares))
+	for i := range shares {
+		for j := range shares[i].Items {
+			si := &shares[i].Items[j]
+			it, ok := lines[si.BookingItemID]
+			if !ok || si.Quantity <= 0 {
+				return ErrInvalidSplit
+			}
+			taken[it.ID] += si.Quantity
+			cost := 0.0
+			if it.Quantity > 0 {
+				cost = lineCost(it) * si.Quantity / it.Quantity
+			}
+			si.Amount = int(math.Round(cost))
+			costs[i] += cost
+		}
+	}
+	for _, it := range items {
+		if math.Abs(taken[it.ID]-it.Quantity) > 0.001 {
+			return ErrInvalidSplit
+		}
+	}
+	if netTotal <= 0 {
+		equalAmounts(shares, b.TotalAmount)
+		return nil
+	}
+	rest := b.TotalAmount
+	for i := range shares {
+		if i == len(shares)-1 {
+			shares[i].Amount = rest
+			break
+		}
+		shares[i].Amount = int(math.Round(float64(b.TotalAmount) * costs[i] / netTotal))
+		rest -= shares[i].Amount
+	}
+	return nil
+}
+
+// lineCost is the price of a booking line after its percent discount.
+func lineCost(it models.BookingItem) float64 {
+	return float64(it.Price) * float64(100-it.Discount) / 100
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// splitWalletPayments separates payments from the client wallet, they are
+// taken from the wallet instead of being written as is.
+func splitWalletPayments(pays []models.BookingPayment) (regular []models.BookingPayment, wallet int) {
+	for _, p := range pays {
+		if p.Method == models.PaymentMethodWallet {
+			wallet += p.Amount
+			continue
+		}
+		regular = append(regular, p)
+	}
+	return regular, wallet
+}
+
+// checkWallet makes sure the booking client has enough money on account.
+func (s *BookingService) checkWallet(ctx context.Context, clientID, amount int) error {
+	if amount < 0 {
+		return ErrInvalidAmount
+	}
+	if amount == 0 {
+		return nil
+	}
+	if clientID <= 0 {
+		return ErrWalletNotAllowed
+	}
+	c, err := s.clientRepo.GetByID(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if c.WalletBalance < amount {
+		return ErrWalletExhausted
+	}
+	return nil
+}
+
+// chargeWallet takes amount from the wallet of the booking client and adds
+// it to the booking as a wallet payment.
+func (s *BookingService) chargeWallet(ctx context.Context, b *models.Booking, amount int) (*models.BookingPayment, error) {
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	e := &models.WalletEntry{
+		CompanyID: ctx.Value(common.CtxCompanyID).(int),
+		BranchID:  ctx.Value(common.CtxBranchID).(int),
+		ClientID:  b.ClientID,
+		Kind:      models.WalletPayment,
+		Amount:    -amount,
+		BookingID: b.ID,
+		UserID:    userID,
+	}
+	payID, err := s.walletRepo.Pay(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	if payID == 0 {
+		return nil, ErrWalletExhausted
+	}
+	s.audit.Record(ctx, "client_wallet", b.ClientID, "payment", nil, e)
+	return &models.BookingPayment{
+		ID:             payID,
+		BookingID:      b.ID,
+		Amount:         amount,
+		Method:         models.PaymentMethodWallet,
+		WalletClientID: b.ClientID,
+	}, nil
+}
+
+// PayFromWallet pays part of an open booking bill from the money the
+// booking client keeps on account.
+func (s *BookingService) PayFromWallet(ctx context.Context, bookingID int, req *models.WalletPay) (*models.BookingPayment, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	b, err := s.openBookingForLines(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWallet(ctx, b.ClientID, req.Amount); err != nil {
+		return nil, err
+	}
+	pays, err := s.paymentRepo.GetByBookingID(ctx, companyID, branchID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	paid := 0
+	for _, p := range pays {
+		paid += p.Amount
+	}
+	if paid+req.Amount > b.TotalAmount-b.BonusUsed {
+		return nil, ErrOverpayment
+	}
+	p, err := s.chargeWallet(ctx, b, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "booking", bookingID, "pay_wallet", nil, req)
+	return p, nil
+}
+
+// returnWallet puts money paid from client wallets for a cancelled booking
+// back on account.
+func (s *BookingService) returnWallet(ctx context.Context, companyID, branchID, bookingID int) {
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	returned, err := s.walletRepo.ReturnForBooking(ctx, companyID, branchID, bookingID, userID)
+	s.recordWalletReturn(ctx, returned, err)
+}
+
+// returnWalletPayment puts back one wallet payment, made for a change of
+// the booking which was not saved.
+func (s *BookingService) returnWalletPayment(ctx context.Context, companyID, branchID, bookingID, paymentID int) {
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	returned, err := s.walletRepo.ReturnPayment(ctx, companyID, branchID, bookingID, paymentID, userID)
+	s.recordWalletReturn(ctx, returned, err)
+}
+
+func (s *BookingService) recordWalletReturn(ctx context.Context, returned []models.WalletEntry, err error) {
+	if err != nil {
+		log.Printf("return wallet payments error: %v", err)
+		return
+	}
+	for _, e := range returned {
+		s.audit.Record(ctx, "client_wallet", e.ClientID, "return", nil, e)
+	}
+}
@@ -20,10 +20,16 @@ type CashboxService struct {
 	expenseSvc   *ExpenseService
 	expCatSvc    *ExpenseCategoryService
 	settingsRepo *repositories.SettingsRepository
+	audit        *AuditService
 }
 
-func NewCashboxService(r *repositories.CashboxRepository, hr *repositories.CashboxHistoryRepository, es *ExpenseService, ec *ExpenseCategoryService, sr *repositories.SettingsRepository) *CashboxService {
-	return &CashboxService{repo: r, histRepo: hr, expenseSvc: es, expCatSvc: ec, settingsRepo: sr}
+func NewCashboxService(r *repositories.CashboxRepository, hr *repositories.CashboxHistoryRepository, es *ExpenseService, ec *ExpenseCategoryService, sr *repositories.SettingsRepository, audit *AuditService) *CashboxService {
+	return &CashboxService{repo: r, histRepo: hr, expenseSvc: es, expCatSvc: ec, settingsRepo: sr, audit: audit}
+}
+
+// recordAmount writes cashbox balance change to the audit log.
+func (s *CashboxService) recordAmount(ctx context.Context, operation string, before float64, box *models.Cashbox) {
+	s.audit.Record(ctx, "cashbox", box.ID, operation, models.Cashbox{ID: box.ID, Amount: before}, box)
 }
 
 func (s *CashboxService) GetCashbox(ctx context.Context) (*models.Cashbox, error) {
@@ -31,7 +37,15 @@ func (s *CashboxService) GetCashbox(ctx context.Context) (*models.Cashbox, error
 }
 
 func (s *CashboxService) UpdateCashbox(ctx context.Context, c *models.Cashbox) error {
-	return s.repo.Update(ctx, c)
+	before, err := s.repo.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, c); err != nil {
+		return err
+	}
+	s.recordAmount(ctx, "update", before.Amount, c)
+	return nil
 }
 
 // Inventory sets cashbox amount to zero and saves history record
@@ -57,27 +71,61 @@ func (s *CashboxService) InventoryAmount(ctx context.Context, amount float64) er
 	if _, err := s.histRepo.Create(ctx, &hist); err != nil {
 		return err
 	}
+	before := box.Amount
 	box.Amount -= amount
-	return s.repo.Update(ctx, box)
+	if err := s.repo.Update(ctx, box); err != nil {
+		return err
+	}
+	s.recordAmount(ctx, "Инвентаризация", before, box)
+	return nil
 }
 
 // AddIncome increases cashbox amount and records history without creating an expense entry
 func (s *CashboxService) AddIncome(ctx context.Context, amount float64) error {
+	return s.receive(ctx, amount, "Оплата брони")
+}
+
+// AddDeposit puts a reservation deposit paid in cash into cashbox. Deposits
+// are recorded under their own operation so they can be told apart from
+// regular booking payments.
+func (s *CashboxService) AddDeposit(ctx context.Context, amount float64) error {
+	return s.receive(ctx, amount, "Депозит брони")
+}
+
+// AddPassSale puts money for a pass sold in cash into cashbox.
+func (s *CashboxService) AddPassSale(ctx context.Context, amount float64) error {
+	return s.receive(ctx, amount, "Продажа абонемента")
+}
+
+// AddCertificateSale puts money for a gift certificate sold in cash into
+// cashbox.
+func (s *CashboxService) AddCertificateSale(ctx context.Context, amount float64) error {
+	return s.receive(ctx, amount, "Продажа сертификата")
+}
+
+// AddWalletTopUp puts cash a client left on account into cashbox.
+func (s *CashboxService) AddWalletTopUp(ctx context.Context, amount float64) error {
+	return s.receive(ctx, amount, "Пополнение счета клиента")
+}
+
+func (s *CashboxService) receive(ctx context.Context, amount float64, operation string) error {
 	box, err := s.repo.Get(ctx)
 	if err != nil {
 		return err
 	}
+	before := box.Amount
 	box.Amount += amount
 	if err := s.repo.Update(ctx, box); err != nil {
 		return err
 	}
 	hist := models.CashboxHistory{
-		Operation: "Оплата брони",
+		Operation: operation,
 		Amount:    amount,
 	}
 	if _, err := s.histRepo.Create(ctx, &hist); err != nil {
 		return err
 	}
+	s.recordAmount(ctx, hist.Operation, before, box)
 	return nil
 }
 
@@ -87,6 +135,7 @@ func (s *CashboxService) RemoveIncome(ctx context.Context, amount float64) error
 	if err != nil {
 		return err
 	}
+	before := box.Amount
 	box.Amount -= amount
 	if err := s.repo.Update(ctx, box); err != nil {
 		return err
@@ -98,6 +147,33 @@ func (s *CashboxService) RemoveIncome(ctx context.Context, amount float64) error
 	if _, err := s.histRepo.Create(ctx, &hist); err != nil {
 		return err
 	}
+	s.recordAmount(ctx, hist.Operation, before, box)
+	return nil
+}
+
+// Withdraw takes money from cashbox for an outgoing payment and records
+// history entry with the given operation name.
+func (s *CashboxService) Withdraw(ctx context.Context, amount float64, operation string) error {
+	box, err := s.repo.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if amount > box.Amount {
+		return ErrInsufficientFunds
+	}
+	before := box.Amount
+	box.Amount -= amount
+	if err := s.repo.Update(ctx, box); err != nil {
+		return err
+	}
+	hist := models.CashboxHistory{
+		Operation: operation,
+		Amount:    -amount,
+	}
+	if _, err := s.histRepo.Create(ctx, &hist); err != nil {
+		return err
+	}
+	s.recordAmount(ctx, hist.Operation, before, box)
 	return nil
 }
 
@@ -107,6 +183,7 @@ func (s *CashboxService) Replenish(ctx context.Context, amount float64) error {
 	if err != nil {
 		return err
 	}
+	before := box.Amount
 	box.Amount += amount
 	if err := s.repo.Update(ctx, box); err != nil {
 		return err
@@ -118,6 +195,7 @@ func (s *CashboxService) Replenish(ctx context.Context, amount float64) error {
 	if _, err := s.histRepo.Create(ctx, &hist); err != nil {
 		return err
 	}
+	s.recordAmount(ctx, hist.Operation, before, box)
 
 	companyID := ctx.Value(common.CtxCompanyID).(int)
 	branchID := ctx.Value(common.CtxBranchID).(int)
@@ -200,7 +278,7 @@ func (s *CashboxService) GetDay(ctx context.Context) (float64, []models.CashboxH
 		switch h.Operation {
 		case "Инвентаризация":
 			startAmount += h.Amount
-		case "Оплата брони", "Пополнение", "Возврат брони":
+		case "Оплата брони", "Депозит брони", "Пополнение", "Возврат брони":
 			startAmount -= h.Amount
 		default:
 			startAmount -= h.Amount
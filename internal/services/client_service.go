@@ -3,16 +3,18 @@ package services
 import (
 	"context"
 	"errors"
+	"psclub-crm/internal/common"
 	"psclub-crm/internal/models"
 	"psclub-crm/internal/repositories"
 )
 
 type ClientService struct {
-	repo *repositories.ClientRepository
+	repo     *repositories.ClientRepository
+	passRepo *repositories.PassRepository
 }
 
-func NewClientService(r *repositories.ClientRepository) *ClientService {
-	return &ClientService{repo: r}
+func NewClientService(r *repositories.ClientRepository, passRepo *repositories.PassRepository) *ClientService {
+	return &ClientService{repo: r, passRepo: passRepo}
 }
 
 func (s *ClientService) CreateClient(ctx context.Context, client *models.Client) (int, error) {
@@ -33,8 +35,19 @@ func (s *ClientService) GetAllClients(ctx context.Context) ([]models.Client, err
 	return s.repo.GetAll(ctx)
 }
 
+// GetClientByID returns the client card with remaining pass balances.
 func (s *ClientService) GetClientByID(ctx context.Context, id int) (*models.Client, error) {
-	return s.repo.GetByID(ctx, id)
+	client, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	client.Passes, err = s.passRepo.GetClientPasses(ctx, companyID, branchID, id)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
 }
 
 func (s *ClientService) UpdateClient(ctx context.Context, client *models.Client) error {
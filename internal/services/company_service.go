@@ -2,7 +2,8 @@ package services
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
+	"strings"
 
 	"psclub-crm/internal/common"
 	"psclub-crm/internal/models"
@@ -10,107 +11,95 @@ import (
 )
 
 type CompanyService struct {
-	repo            *repositories.CompanyRepository
-	channelRepo     *repositories.ChannelRepository
-	tableCatRepo    *repositories.TableCategoryRepository
-	tableRepo       *repositories.TableRepository
-	categoryRepo    *repositories.CategoryRepository
-	paymentTypeRepo *repositories.PaymentTypeRepository
-	settingsRepo    *repositories.SettingsRepository
-	expenseCatRepo  *repositories.ExpenseCategoryRepository
+	repo           *repositories.CompanyRepository
+	userBranchRepo *repositories.UserBranchRepository
+	templates      *TemplateService
 }
 
-func NewCompanyService(
-	repo *repositories.CompanyRepository,
-	channelRepo *repositories.ChannelRepository,
-	tableCatRepo *repositories.TableCategoryRepository,
-	tableRepo *repositories.TableRepository,
-	categoryRepo *repositories.CategoryRepository,
-	paymentTypeRepo *repositories.PaymentTypeRepository,
-	settingsRepo *repositories.SettingsRepository,
-	expenseCatRepo *repositories.ExpenseCategoryRepository,
-) *CompanyService {
-	return &CompanyService{
-		repo:            repo,
-		channelRepo:     channelRepo,
-		tableCatRepo:    tableCatRepo,
-		tableRepo:       tableRepo,
-		categoryRepo:    categoryRepo,
-		paymentTypeRepo: paymentTypeRepo,
-		settingsRepo:    settingsRepo,
-		expenseCatRepo:  expenseCatRepo,
-	}
-}
-
-type CreateCompanyInput struct {
-	Name       string
-	BranchName string
+func NewCompanyService(repo *repositories.CompanyRepository, userBranchRepo *repositories.UserBranchRepository, templates *TemplateService) *CompanyService {
+	return &CompanyService{repo: repo, userBranchRepo: userBranchRepo, templates: templates}
 }
 
-func (s *CompanyService) CreateCompany(ctx context.Context, name, branchName string) (int, int, error) {
-	companyID, err := s.repo.CreateCompany(ctx, name)
+func (s *CompanyService) GetBranches(ctx context.Context, companyID int) ([]models.Branch, error) {
+	list, err := s.repo.GetBranches(ctx, companyID)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
-	branchID, err := s.repo.CreateBranch(ctx, companyID, branchName)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	tenantCtx := context.WithValue(ctx, common.CtxCompanyID, companyID)
-	tenantCtx = context.WithValue(tenantCtx, common.CtxBranchID, branchID)
-
-	if _, err := s.channelRepo.Create(tenantCtx, &models.Channel{Name: "- не указано"}); err != nil {
-		return 0, 0, err
+	if list == nil {
+		list = []models.Branch{}
 	}
+	return list, nil
+}
 
-	tableCatID, err := s.tableCatRepo.Create(tenantCtx, &models.TableCategory{Name: "Основной зал", CompanyID: companyID, BranchID: branchID})
+func (s *CompanyService) GetBranch(ctx context.Context, companyID, id int) (*models.Branch, error) {
+	b, err := s.repo.GetBranch(ctx, id)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
-	for i := 1; i <= 6; i++ {
-		if _, err := s.tableRepo.Create(tenantCtx, &models.Table{CategoryID: tableCatID, Name: fmt.Sprintf("Стол %d", i), CompanyID: companyID, BranchID: branchID}); err != nil {
-			return 0, 0, err
-		}
+	if b.CompanyID != companyID {
+		return nil, sql.ErrNoRows
 	}
+	return b, nil
+}
 
-	for _, c := range []string{"Бар", "Кальян", "Сеты", "Часы"} {
-		if _, err := s.categoryRepo.Create(tenantCtx, &models.Category{Name: c, CompanyID: companyID, BranchID: branchID}); err != nil {
-			return 0, 0, err
-		}
+// CreateBranch opens a new branch of the company. With cloneFrom the
+// catalog is copied from that branch, otherwise it is set up from the
+// template.
+// The user who opens the branch gets access to it with the same role.
+func (s *CompanyService) CreateBranch(ctx context.Context, companyID int, name string, cloneFrom int, template string) (*models.Branch, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidBranch
 	}
-
-	paymentNames := []string{"Наличными", "Картой", "Каспи QR"}
-	firstPTID := 0
-	for i, p := range paymentNames {
-		id, err := s.paymentTypeRepo.Create(tenantCtx, &models.PaymentType{Name: p, HoldPercent: 0})
-		if err != nil {
-			return 0, 0, err
+	var id int
+	var err error
+	if cloneFrom > 0 {
+		if _, err := s.GetBranch(ctx, companyID, cloneFrom); err != nil {
+			return nil, err
 		}
-		if i == 0 {
-			firstPTID = id
+		id, err = s.repo.CreateBranchFrom(ctx, companyID, name, cloneFrom)
+	} else {
+		var t *models.SeedTemplate
+		if t, err = s.templates.Get(template); err != nil {
+			return nil, err
 		}
+		id, err = s.repo.CreateBranch(ctx, companyID, name, t)
 	}
-
-	for _, e := range []string{"Бар", "Кальян", "Ремонт", "Инвентаризация", "Зарплата", "Касса"} {
-		if _, err := s.expenseCatRepo.Create(tenantCtx, &models.ExpenseCategory{Name: e}); err != nil {
-			return 0, 0, err
+	if err != nil {
+		return nil, err
+	}
+	if userID, ok := ctx.Value(common.CtxUserID).(int); ok && userID > 0 {
+		role, _ := ctx.Value(common.CtxRole).(string)
+		if err := s.userBranchRepo.Save(ctx, &models.UserBranch{UserID: userID, CompanyID: companyID, BranchID: id, Role: role}); err != nil {
+			return nil, err
 		}
 	}
+	return &models.Branch{ID: id, CompanyID: companyID, Name: name}, nil
+}
 
-	if _, err := s.settingsRepo.Create(tenantCtx, &models.Settings{
-		PaymentType:      firstPTID,
-		BlockTime:        120,
-		BonusPercent:     0,
-		WorkTimeFrom:     "10:00",
-		WorkTimeTo:       "23:00",
-		TablesCount:      6,
-		NotificationTime: 5,
-		CompanyID:        companyID,
-		BranchID:         branchID,
-	}); err != nil {
-		return 0, 0, err
+func (s *CompanyService) UpdateBranch(ctx context.Context, b *models.Branch) error {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		return ErrInvalidBranch
 	}
+	return s.repo.UpdateBranch(ctx, b)
+}
 
-	return companyID, branchID, nil
+// DeleteBranch removes a branch opened by mistake. A branch which already
+// has bookings, expenses or staff, or the one the user works in, is kept.
+func (s *CompanyService) DeleteBranch(ctx context.Context, companyID, id int) error {
+	if current, _ := ctx.Value(common.CtxBranchID).(int); current == id {
+		return ErrBranchInUse
+	}
+	if _, err := s.GetBranch(ctx, companyID, id); err != nil {
+		return err
+	}
+	used, err := s.repo.BranchInUse(ctx, companyID, id)
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrBranchInUse
+	}
+	return s.repo.DeleteBranch(ctx, companyID, id)
 }
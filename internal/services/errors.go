@@ -3,3 +3,99 @@ package services
 import "errors"
 
 var ErrNameExists = errors.New("name already exists")
+
+var ErrInvalidSettings = errors.New("settings must not be negative, percents must not exceed 100")
+
+var (
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrOverpayment       = errors.New("amount exceeds remaining debt")
+	ErrInsufficientFunds = errors.New("not enough money in cashbox")
+)
+
+var (
+	ErrBookingNotActive     = errors.New("booking is not active")
+	ErrCancelReasonRequired = errors.New("cancel reason is required")
+	ErrInvalidBookingStatus = errors.New("invalid booking status")
+	ErrRefundExceedsPaid    = errors.New("refund exceeds paid amount")
+	ErrNoShowTooEarly       = errors.New("reservation grace period has not passed yet")
+	ErrTableBusy            = errors.New("выбранное время занято")
+	ErrInvalidBookingTime   = errors.New("invalid booking time")
+	ErrInvalidMoveTable     = errors.New("booking is already at this table")
+	ErrNotHoursItem         = errors.New("tariff must be an hours item")
+	ErrInvalidMerge         = errors.New("bookings cannot be merged")
+	ErrMergeBonusClient     = errors.New("booking paid with bonuses of another client cannot be merged")
+	ErrInvalidSplit         = errors.New("invalid bill split")
+	ErrBookingSplit         = errors.New("booking bill is split")
+	ErrVoidReasonRequired   = errors.New("void reason is required")
+	ErrItemVoided           = errors.New("booking line is already voided")
+)
+
+var ErrInvalidTicketStatus = errors.New("invalid ticket status")
+
+var (
+	ErrInvalidPassType = errors.New("invalid pass type")
+	ErrPassExhausted   = errors.New("pass is expired or has no balance left")
+	ErrPassNotAllowed  = errors.New("pass is not valid for this booking")
+	ErrLineRedeemed    = errors.New("booking line is already paid by a pass")
+)
+
+var (
+	ErrInvalidCertificate    = errors.New("invalid certificate")
+	ErrCertificateCodeExists = errors.New("certificate code already exists")
+	ErrCertificateExhausted  = errors.New("certificate is expired or its balance is not enough")
+)
+
+var (
+	ErrInvalidPromotion   = errors.New("invalid promotion")
+	ErrInvalidPromoCode   = errors.New("invalid promo code")
+	ErrPromoCodeExists    = errors.New("promo code already exists")
+	ErrPromoCodeExhausted = errors.New("promo code usage limit is reached")
+)
+
+var (
+	ErrWalletNotAllowed = errors.New("booking has no client to pay from wallet")
+	ErrWalletExhausted  = errors.New("client wallet balance is not enough")
+)
+
+var (
+	ErrInvalidPhone    = errors.New("invalid phone")
+	ErrInvalidOTP      = errors.New("invalid or expired code")
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+var (
+	ErrBranchForbidden   = errors.New("no access to the branch")
+	ErrInvalidUserBranch = errors.New("branch does not belong to the company")
+	ErrInvalidBranch     = errors.New("branch name is required")
+	ErrBranchInUse       = errors.New("branch is in use")
+)
+
+var (
+	ErrInvalidSignup   = errors.New("company name, owner name, phone and a password of at least 6 characters are required")
+	ErrUserExists      = errors.New("user already exists")
+	ErrUnknownTemplate = errors.New("unknown club template")
+)
+
+var (
+	ErrInvalidRole   = errors.New("unknown role")
+	ErrRoleForbidden = errors.New("role is above your own")
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+var (
+	ErrInvalidDevice  = errors.New("device name is required")
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrUnknownDevice  = errors.New("device is not enrolled")
+	ErrInvalidPinCode = errors.New("PIN must be 4 to 6 digits")
+	ErrWrongPin       = errors.New("invalid PIN")
+	ErrPinLocked      = errors.New("too many wrong PINs, try again later")
+	ErrWrongPassword  = errors.New("invalid password")
+)
+
+var ErrWeakPassword = errors.New("password must be at least 6 characters")
+
+var (
+	ErrInvalidCredentials = errors.New("invalid phone or password")
+	ErrLoginThrottled     = errors.New("too many login attempts, try again later")
+)
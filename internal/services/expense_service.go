@@ -15,6 +15,9 @@ func NewExpenseService(r *repositories.ExpenseRepository) *ExpenseService {
 }
 
 func (s *ExpenseService) CreateExpense(ctx context.Context, e *models.Expense) (int, error) {
+	if e.Paid {
+		e.PaidAmount = e.Total
+	}
 	return s.repo.Create(ctx, e)
 }
 
@@ -27,6 +30,9 @@ func (s *ExpenseService) GetExpenseByID(ctx context.Context, id int) (*models.Ex
 }
 
 func (s *ExpenseService) UpdateExpense(ctx context.Context, e *models.Expense) error {
+	if e.Paid {
+		e.PaidAmount = e.Total
+	}
 	return s.repo.Update(ctx, e)
 }
 
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"math/big"
+	"strings"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// символы кода без похожих друг на друга 0/O и 1/I
+const certificateCodeChars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const certificateCodeLen = 10
+
+// GiftCertificateService sells gift certificates. Their balances are spent
+// on bookings, see BookingService.RedeemCertificate.
+type GiftCertificateService struct {
+	repo            *repositories.GiftCertificateRepository
+	clientRepo      *repositories.ClientRepository
+	paymentTypeRepo *repositories.PaymentTypeRepository
+	cashboxService  *CashboxService
+	audit           *AuditService
+}
+
+func NewGiftCertificateService(r *repositories.GiftCertificateRepository, clientRepo *repositories.ClientRepository, ptRepo *repositories.PaymentTypeRepository, cbService *CashboxService, audit *AuditService) *GiftCertificateService {
+	return &GiftCertificateService{
+		repo:            r,
+		clientRepo:      clientRepo,
+		paymentTypeRepo: ptRepo,
+		cashboxService:  cbService,
+		audit:           audit,
+	}
+}
+
+func normalizeCertificateCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func generateCertificateCode() (string, error) {
+	var b strings.Builder
+	max := big.NewInt(int64(len(certificateCodeChars)))
+	for i := 0; i < certificateCodeLen; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(certificateCodeChars[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// codeTaken reports whether the company already has a certificate with the
+// code.
+func (s *GiftCertificateService) codeTaken(ctx context.Context, companyID int, code string) (bool, error) {
+	_, err := s.repo.GetByCode(ctx, companyID, code)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SellCertificate registers a sold certificate. A printed code may be
+// given, otherwise a new one is generated. The nominal is the income of the
+// sale, cash goes to the cashbox right away.
+func (s *GiftCertificateService) SellCertificate(ctx context.Context, req *models.GiftCertificateSale) (*models.GiftCertificate, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	if req.Nominal <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	now := time.Now()
+	expires := now.AddDate(1, 0, 0)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, ErrInvalidCertificate
+		}
+		expires = *req.ExpiresAt
+	}
+	if req.PurchaserClientID > 0 {
+		client, err := s.clientRepo.GetByID(ctx, req.PurchaserClientID)
+		if err != nil {
+			return nil, err
+		}
+		if req.PurchaserName == "" {
+			req.PurchaserName = client.Name
+		}
+	}
+	pt, err := s.paymentTypeRepo.GetByID(ctx, req.PaymentTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	code := normalizeCertificateCode(req.Code)
+	if code != "" {
+		taken, err := s.codeTaken(ctx, companyID, code)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrCertificateCodeExists
+		}
+	} else {
+		for {
+			if code, err = generateCertificateCode(); err != nil {
+				return nil, err
+			}
+			taken, err := s.codeTaken(ctx, companyID, code)
+			if err != nil {
+				return nil, err
+			}
+			if !taken {
+				break
+			}
+		}
+	}
+
+	g := &models.GiftCertificate{
+		CompanyID:         companyID,
+		BranchID:          branchID,
+		Code:              code,
+		Nominal:           req.Nominal,
+		Balance:           req.Nominal,
+		PurchaserClientID: req.PurchaserClientID,
+		PurchaserName:     strings.TrimSpace(req.PurchaserName),
+		PaymentTypeID:     pt.ID,
+		UserID:            userID,
+		SoldAt:            now,
+		ExpiresAt:         expires,
+		Active:            true,
+	}
+	g.ID, err = s.repo.Create(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	if s.cashboxService != nil && isCashType(pt) {
+		_ = s.cashboxService.AddCertificateSale(ctx, float64(g.Nominal))
+	}
+	s.audit.Record(ctx, "gift_certificate", g.ID, "sell", nil, g)
+	return g, nil
+}
+
+func (s *GiftCertificateService) GetAllCertificates(ctx context.Context) ([]models.GiftCertificate, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return s.repo.GetAll(ctx, companyID, branchID)
+}
+
+// LookupCertificate finds a certificate by code to check its balance.
+func (s *GiftCertificateService) LookupCertificate(ctx context.Context, code string) (*models.GiftCertificate, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	return s.repo.GetByCode(ctx, companyID, normalizeCertificateCode(code))
+}
@@ -21,10 +21,11 @@ type InventoryService struct {
 	historyRepo *repositories.InventoryHistoryRepository
 	expenseSvc  *ExpenseService
 	expCatSvc   *ExpenseCategoryService
+	audit       *AuditService
 }
 
-func NewInventoryService(pr *repositories.PriceItemRepository, hr *repositories.InventoryHistoryRepository, es *ExpenseService, ec *ExpenseCategoryService) *InventoryService {
-	return &InventoryService{priceRepo: pr, historyRepo: hr, expenseSvc: es, expCatSvc: ec}
+func NewInventoryService(pr *repositories.PriceItemRepository, hr *repositories.InventoryHistoryRepository, es *ExpenseService, ec *ExpenseCategoryService, audit *AuditService) *InventoryService {
+	return &InventoryService{priceRepo: pr, historyRepo: hr, expenseSvc: es, expCatSvc: ec, audit: audit}
 }
 
 func (s *InventoryService) PerformInventory(ctx context.Context, items []InventoryItem) error {
@@ -53,6 +54,11 @@ func (s *InventoryService) PerformInventory(ctx context.Context, items []Invento
 		if _, err := s.historyRepo.Create(ctx, &hist); err != nil {
 			return err
 		}
+		if diff != 0 {
+			after := *pi
+			after.Quantity = it.Actual
+			s.audit.Record(ctx, "price_item", pi.ID, "inventory", pi, &after)
+		}
 		if diff < 0 {
 			// shortage -> record expense
 			exp := models.Expense{
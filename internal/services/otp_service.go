@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// OTPSender delivers confirmation codes to a phone (SMS, messenger, ...).
+type OTPSender interface {
+	Send(ctx context.Context, phone, message string) error
+}
+
+// LogOTPSender is a stub sender which only writes codes to the log.
+type LogOTPSender struct{}
+
+func (LogOTPSender) Send(ctx context.Context, phone, message string) error {
+	log.Printf("otp to %s: %s", phone, message)
+	return nil
+}
+
+const (
+	otpTTL         = 5 * time.Minute
+	otpMaxAttempts = 5
+)
+
+// OTPService issues and verifies one-time phone confirmation codes.
+type OTPService struct {
+	repo   *repositories.OTPRepository
+	sender OTPSender
+}
+
+func NewOTPService(r *repositories.OTPRepository, sender OTPSender) *OTPService {
+	return &OTPService{repo: r, sender: sender}
+}
+
+// normalizePhone keeps digits and leading plus only.
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if (r >= '0' && r <= '9') || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func hashOTP(code string) string {
+	h := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(h[:])
+}
+
+// Send generates a new code for the phone and purpose and delivers it.
+func (s *OTPService) Send(ctx context.Context, companyID, branchID int, phone, purpose string) error {
+	code, err := s.Issue(ctx, companyID, branchID, phone, purpose, otpTTL)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, phone, "Код подтверждения: "+code)
+}
+
+// Issue generates a new code valid for ttl and returns it without sending,
+// the caller passes it on by itself.
+func (s *OTPService) Issue(ctx context.Context, companyID, branchID int, phone, purpose string, ttl time.Duration) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	o := models.OTPCode{
+		CompanyID: companyID,
+		BranchID:  branchID,
+		Phone:     phone,
+		Purpose:   purpose,
+		CodeHash:  hashOTP(code),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if _, err := s.repo.Create(ctx, &o); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Verify checks the code and marks it used on success.
+func (s *OTPService) Verify(ctx context.Context, companyID, branchID int, phone, purpose, code string) error {
+	o, err := s.repo.GetActive(ctx, companyID, branchID, phone, purpose)
+	if err != nil {
+		return err
+	}
+	if o == nil || o.Attempts >= otpMaxAttempts {
+		return ErrInvalidOTP
+	}
+	if subtle.ConstantTimeCompare([]byte(hashOTP(strings.TrimSpace(code))), []byte(o.CodeHash)) != 1 {
+		_ = s.repo.IncAttempts(ctx, o.ID)
+		return ErrInvalidOTP
+	}
+	ok, err := s.repo.MarkUsed(ctx, o.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidOTP
+	}
+	return nil
+}
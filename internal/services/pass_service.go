@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// PassService manages membership passes (абонементы): pass types on sale and
+// passes sold to clients. Redemption happens on booking lines, see
+// BookingService.RedeemPass.
+type PassService struct {
+	repo            *repositories.PassRepository
+	clientRepo      *repositories.ClientRepository
+	paymentTypeRepo *repositories.PaymentTypeRepository
+	cashboxService  *CashboxService
+	audit           *AuditService
+}
+
+func NewPassService(r *repositories.PassRepository, clientRepo *repositories.ClientRepository, ptRepo *repositories.PaymentTypeRepository, cbService *CashboxService, audit *AuditService) *PassService {
+	return &PassService{
+		repo:            r,
+		clientRepo:      clientRepo,
+		paymentTypeRepo: ptRepo,
+		cashboxService:  cbService,
+		audit:           audit,
+	}
+}
+
+func validatePassType(t *models.PassType) error {
+	if t.Kind == "" {
+		t.Kind = models.PassKindHours
+	}
+	if t.Kind != models.PassKindHours && t.Kind != models.PassKindVisits {
+		return ErrInvalidPassType
+	}
+	if t.Amount <= 0 || t.Price < 0 || t.ValidityDays <= 0 {
+		return ErrInvalidPassType
+	}
+	return nil
+}
+
+func (s *PassService) CreatePassType(ctx context.Context, t *models.PassType) (int, error) {
+	t.CompanyID = ctx.Value(common.CtxCompanyID).(int)
+	t.BranchID = ctx.Value(common.CtxBranchID).(int)
+	if err := validatePassType(t); err != nil {
+		return 0, err
+	}
+	t.IsActive = true
+	return s.repo.CreateType(ctx, t)
+}
+
+func (s *PassService) GetPassTypes(ctx context.Context) ([]models.PassType, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return s.repo.GetTypes(ctx, companyID, branchID)
+}
+
+func (s *PassService) UpdatePassType(ctx context.Context, t *models.PassType) error {
+	t.CompanyID = ctx.Value(common.CtxCompanyID).(int)
+	t.BranchID = ctx.Value(common.CtxBranchID).(int)
+	if err := validatePassType(t); err != nil {
+		return err
+	}
+	return s.repo.UpdateType(ctx, t)
+}
+
+// DeletePassType takes the type off sale, sold passes stay valid.
+func (s *PassService) DeletePassType(ctx context.Context, id int) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return s.repo.DeactivateType(ctx, id, companyID, branchID)
+}
+
+// SellPass sells a pass to a client. The price is the income of the sale,
+// cash goes to the cashbox right away.
+func (s *PassService) SellPass(ctx context.Context, clientID int, req *models.PassSale) (*models.ClientPass, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	if _, err := s.clientRepo.GetByID(ctx, clientID); err != nil {
+		return nil, err
+	}
+	t, err := s.repo.GetTypeByID(ctx, req.PassTypeID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsActive {
+		return nil, ErrInvalidPassType
+	}
+	pt, err := s.paymentTypeRepo.GetByID(ctx, req.PaymentTypeID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	p := &models.ClientPass{
+		CompanyID:     companyID,
+		BranchID:      branchID,
+		ClientID:      clientID,
+		PassTypeID:    t.ID,
+		PassTypeName:  t.Name,
+		Kind:          t.Kind,
+		Total:         t.Amount,
+		Balance:       t.Amount,
+		Price:         t.Price,
+		PaymentTypeID: pt.ID,
+		UserID:        userID,
+		SoldAt:        now,
+		ExpiresAt:     now.AddDate(0, 0, t.ValidityDays),
+		Active:        true,
+	}
+	p.ID, err = s.repo.CreateClientPass(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if s.cashboxService != nil && p.Price > 0 && isCashType(pt) {
+		_ = s.cashboxService.AddPassSale(ctx, float64(p.Price))
+	}
+	s.audit.Record(ctx, "client_pass", p.ID, "sell", nil, p)
+	return p, nil
+}
+
+// ClientPasses returns passes of a client with remaining balances.
+func (s *PassService) ClientPasses(ctx context.Context, clientID int) ([]models.ClientPass, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return s.repo.GetClientPasses(ctx, companyID, branchID, clientID)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"psclub-crm/internal/ratelimit"
+	"psclub-crm/internal/repositories"
+)
+
+const (
+	otpPurposePasswordReset = "password_reset"
+	// коды директора хранятся отдельно, чтобы запрос кода по SMS их не
+	// перекрывал
+	otpPurposeIssuedReset = "password_reset_issued"
+	// resetCodeTTL - срок кода сброса, выданного директором
+	resetCodeTTL      = 24 * time.Hour
+	minPasswordLength = 6
+)
+
+// PasswordService changes and resets passwords of users. Every new password
+// ends all sessions of the user.
+type PasswordService struct {
+	userRepo     *repositories.UserRepository
+	tokenRepo    *repositories.TokenRepository
+	otp          *OTPService
+	phoneLimiter *ratelimit.Limiter
+}
+
+func NewPasswordService(userRepo *repositories.UserRepository, tokenRepo *repositories.TokenRepository, otp *OTPService, phoneLimiter *ratelimit.Limiter) *PasswordService {
+	return &PasswordService{userRepo: userRepo, tokenRepo: tokenRepo, otp: otp, phoneLimiter: phoneLimiter}
+}
+
+// ChangePassword sets a new password of the user, the old one is required.
+func (s *PasswordService) ChangePassword(ctx context.Context, userID int, oldPassword, newPassword string) error {
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	u, err := s.userRepo.GetByIDNoTenant(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(oldPassword)); err != nil {
+		return ErrWrongPassword
+	}
+	return s.setPassword(ctx, userID, newPassword)
+}
+
+// IssueResetCode gives the director a one-time code for an employee who
+// forgot the password. The employee sets a new password with it. Codes for
+// users with a role above the one of the current user are not issued.
+func (s *PasswordService) IssueResetCode(ctx context.Context, id, companyID, branchID int) (string, time.Time, error) {
+	u, err := s.userRepo.GetByID(ctx, id, companyID, branchID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if roleRanks[u.Role] > callerRank(ctx) {
+		return "", time.Time{}, ErrRoleForbidden
+	}
+	code, err := s.otp.Issue(ctx, u.CompanyID, u.BranchID, u.Phone, otpPurposeIssuedReset, resetCodeTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return code, time.Now().Add(resetCodeTTL), nil
+}
+
+// RequestReset sends a reset code to the phone of the user. An unknown
+// phone is not reported so that phones of staff can not be probed.
+func (s *PasswordService) RequestReset(ctx context.Context, phone string) error {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return ErrInvalidPhone
+	}
+	if !s.phoneLimiter.Allow("password:" + phone) {
+		return ErrTooManyRequests
+	}
+	u, err := s.userRepo.GetByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return nil
+	}
+	return s.otp.Send(ctx, u.CompanyID, u.BranchID, u.Phone, otpPurposePasswordReset)
+}
+
+// ResetPassword sets a new password with a reset code, sent to the phone or
+// issued by the director.
+func (s *PasswordService) ResetPassword(ctx context.Context, phone, code, newPassword string) error {
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	u, err := s.userRepo.GetByPhone(ctx, strings.TrimSpace(phone))
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrInvalidOTP
+	}
+	err = s.otp.Verify(ctx, u.CompanyID, u.BranchID, u.Phone, otpPurposePasswordReset, code)
+	if errors.Is(err, ErrInvalidOTP) {
+		err = s.otp.Verify(ctx, u.CompanyID, u.BranchID, u.Phone, otpPurposeIssuedReset, code)
+	}
+	if err != nil {
+		return err
+	}
+	return s.setPassword(ctx, u.ID, newPassword)
+}
+
+func (s *PasswordService) setPassword(ctx context.Context, userID int, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPassword(ctx, userID, string(hashed)); err != nil {
+		return err
+	}
+	return s.tokenRepo.DeleteByUser(ctx, userID)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// PayableService tracks unpaid expenses (supplier debts) and payments made
+// against them. Cash payments can optionally be taken from the cashbox.
+type PayableService struct {
+	expenseRepo *repositories.ExpenseRepository
+	paymentRepo *repositories.ExpensePaymentRepository
+	cashbox     *CashboxService
+	audit       *AuditService
+}
+
+func NewPayableService(er *repositories.ExpenseRepository, pr *repositories.ExpensePaymentRepository, cs *CashboxService, audit *AuditService) *PayableService {
+	return &PayableService{expenseRepo: er, paymentRepo: pr, cashbox: cs, audit: audit}
+}
+
+// GetPayables lists expenses that still have an outstanding amount.
+func (s *PayableService) GetPayables(ctx context.Context) ([]models.Expense, error) {
+	return s.expenseRepo.GetPayables(ctx)
+}
+
+// GetPayments returns payments recorded for the expense.
+func (s *PayableService) GetPayments(ctx context.Context, expenseID int) ([]models.ExpensePayment, error) {
+	if _, err := s.expenseRepo.GetByID(ctx, expenseID); err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.GetByExpense(ctx, expenseID)
+}
+
+// AddPayment records a partial or full payment for the expense. The payment
+// and the paid amount are saved together and only while the debt covers the
+// payment. When FromCashbox is set the amount is withdrawn from the cashbox
+// afterwards, the payment is cancelled if there is not enough cash.
+func (s *PayableService) AddPayment(ctx context.Context, p *models.ExpensePayment) (int, error) {
+	if _, err := s.expenseRepo.GetByID(ctx, p.ExpenseID); err != nil {
+		return 0, err
+	}
+	if p.Amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+	if p.PaidAt.IsZero() {
+		p.PaidAt = time.Now()
+	}
+	id, ok, err := s.paymentRepo.Pay(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrOverpayment
+	}
+	p.ID = id
+	if p.FromCashbox {
+		if err := s.cashbox.Withdraw(ctx, p.Amount, "Оплата расхода"); err != nil {
+			if cerr := s.paymentRepo.Cancel(ctx, p); cerr != nil {
+				log.Printf("cancel expense payment %d: %v", id, cerr)
+			}
+			return 0, err
+		}
+	}
+	s.audit.Record(ctx, "expense_payment", id, "create", nil, p)
+	return id, nil
+}
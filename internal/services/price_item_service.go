@@ -11,10 +11,11 @@ type PriceItemService struct {
 	repo          *repositories.PriceItemRepository
 	historyRepo   *repositories.PriceItemHistoryRepository
 	plHistoryRepo *repositories.PricelistHistoryRepository
+	audit         *AuditService
 }
 
-func NewPriceItemService(r *repositories.PriceItemRepository, hr *repositories.PriceItemHistoryRepository, plhr *repositories.PricelistHistoryRepository) *PriceItemService {
-	return &PriceItemService{repo: r, historyRepo: hr, plHistoryRepo: plhr}
+func NewPriceItemService(r *repositories.PriceItemRepository, hr *repositories.PriceItemHistoryRepository, plhr *repositories.PricelistHistoryRepository, audit *AuditService) *PriceItemService {
+	return &PriceItemService{repo: r, historyRepo: hr, plHistoryRepo: plhr, audit: audit}
 }
 
 func (s *PriceItemService) CreatePriceItem(ctx context.Context, item *models.PriceItem) (int, error) {
@@ -25,7 +26,13 @@ func (s *PriceItemService) CreatePriceItem(ctx context.Context, item *models.Pri
 	if ex != nil {
 		return 0, ErrNameExists
 	}
-	return s.repo.Create(ctx, item)
+	id, err := s.repo.Create(ctx, item)
+	if err != nil {
+		return 0, err
+	}
+	item.ID = id
+	s.audit.Record(ctx, "price_item", id, "create", nil, item)
+	return id, nil
 }
 
 func (s *PriceItemService) GetAllPriceItems(ctx context.Context) ([]models.PriceItem, error) {
@@ -44,11 +51,22 @@ func (s *PriceItemService) UpdatePriceItem(ctx context.Context, item *models.Pri
 	if ex != nil && ex.ID != item.ID {
 		return ErrNameExists
 	}
-	return s.repo.Update(ctx, item)
+	before, _ := s.repo.GetByID(ctx, item.ID)
+	if err := s.repo.Update(ctx, item); err != nil {
+		return err
+	}
+	after, _ := s.repo.GetByID(ctx, item.ID)
+	s.audit.Record(ctx, "price_item", item.ID, "update", before, after)
+	return nil
 }
 
 func (s *PriceItemService) DeletePriceItem(ctx context.Context, id int) error {
-	return s.repo.Delete(ctx, id)
+	before, _ := s.repo.GetByID(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "price_item", id, "delete", before, nil)
+	return nil
 }
 
 func (s *PriceItemService) GetPriceItemsByCategoryName(ctx context.Context, categoryName string) ([]models.PriceItem, error) {
@@ -66,7 +84,9 @@ func (s *PriceItemService) AddIncome(ctx context.Context, history *models.PriceI
 		return err
 	}
 	// 2. Увеличиваем остаток в PriceItem
-	return s.repo.IncreaseStock(ctx, history.PriceItemID, history.Quantity)
+	return s.changeStock(ctx, history.PriceItemID, "income", func() error {
+		return s.repo.IncreaseStock(ctx, history.PriceItemID, history.Quantity)
+	})
 }
 
 // Списание/Продажа товара — запись в истории и уменьшение остатка
@@ -78,7 +98,20 @@ func (s *PriceItemService) AddOutcome(ctx context.Context, history *models.Price
 	if err != nil {
 		return err
 	}
-	return s.repo.DecreaseStock(ctx, history.PriceItemID, history.Quantity)
+	return s.changeStock(ctx, history.PriceItemID, "outcome", func() error {
+		return s.repo.DecreaseStock(ctx, history.PriceItemID, history.Quantity)
+	})
+}
+
+// changeStock applies a stock change and records the item before and after it.
+func (s *PriceItemService) changeStock(ctx context.Context, id int, action string, apply func() error) error {
+	before, _ := s.repo.GetByID(ctx, id)
+	if err := apply(); err != nil {
+		return err
+	}
+	after, _ := s.repo.GetByID(ctx, id)
+	s.audit.Record(ctx, "price_item", id, action, before, after)
+	return nil
 }
 
 // Получить историю по товару
@@ -105,11 +138,12 @@ func (s *PriceItemService) Replenish(ctx context.Context, hist *models.Pricelist
 		return err
 	}
 
-	if err := s.repo.UpdateBuyPrice(ctx, hist.PriceItemID, hist.BuyPrice); err != nil {
-		return err
-	}
-
-	return s.repo.IncreaseStock(ctx, hist.PriceItemID, float64(hist.Quantity))
+	return s.changeStock(ctx, hist.PriceItemID, "replenish", func() error {
+		if err := s.repo.UpdateBuyPrice(ctx, hist.PriceItemID, hist.BuyPrice); err != nil {
+			return err
+		}
+		return s.repo.IncreaseStock(ctx, hist.PriceItemID, float64(hist.Quantity))
+	})
 }
 
 // GetPricelistHistoryByItem returns replenish history for one price item
@@ -11,6 +11,7 @@ type PriceSetService struct {
 	repo         *repositories.PriceSetRepository
 	itemRepo     *repositories.PriceItemRepository
 	categoryRepo *repositories.CategoryRepository
+	audit        *AuditService
 }
 
 const hoursCategoryName = "\u0427\u0430\u0441\u044b"
@@ -23,8 +24,8 @@ func (s *PriceSetService) isHoursCategory(ctx context.Context, categoryID int) (
 	return cat.Name == hoursCategoryName, nil
 }
 
-func NewPriceSetService(r *repositories.PriceSetRepository, ir *repositories.PriceItemRepository, cr *repositories.CategoryRepository) *PriceSetService {
-	return &PriceSetService{repo: r, itemRepo: ir, categoryRepo: cr}
+func NewPriceSetService(r *repositories.PriceSetRepository, ir *repositories.PriceItemRepository, cr *repositories.CategoryRepository, audit *AuditService) *PriceSetService {
+	return &PriceSetService{repo: r, itemRepo: ir, categoryRepo: cr, audit: audit}
 }
 
 func (s *PriceSetService) CreatePriceSet(ctx context.Context, ps *models.PriceSet) (int, error) {
@@ -55,6 +56,7 @@ func (s *PriceSetService) CreatePriceSet(ctx context.Context, ps *models.PriceSe
 			return id, err
 		}
 	}
+	s.audit.Record(ctx, "price_set", id, "create", nil, ps)
 	return id, nil
 }
 
@@ -99,6 +101,7 @@ func (s *PriceSetService) UpdatePriceSet(ctx context.Context, ps *models.PriceSe
 		SalePrice:     float64(ps.Price),
 		IsSet:         true,
 	}
+	before, _ := s.GetPriceSetByID(ctx, ps.ID)
 	if err := s.itemRepo.Update(ctx, &item); err != nil {
 		return err
 	}
@@ -112,14 +115,20 @@ func (s *PriceSetService) UpdatePriceSet(ctx context.Context, ps *models.PriceSe
 			return err
 		}
 	}
+	s.audit.Record(ctx, "price_set", ps.ID, "update", before, ps)
 	return nil
 }
 
 func (s *PriceSetService) DeletePriceSet(ctx context.Context, id int) error {
+	before, _ := s.GetPriceSetByID(ctx, id)
 	if err := s.repo.Delete(ctx, id); err != nil {
 		return err
 	}
-	return s.itemRepo.Delete(ctx, id)
+	if err := s.itemRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "price_set", id, "delete", before, nil)
+	return nil
 }
 
 func (s *PriceSetService) calculateQuantity(ctx context.Context, ps *models.PriceSet) (int, error) {
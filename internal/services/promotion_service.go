@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+var promotionTimeRe = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// PromotionService manages promotions and promo codes. They are applied to
+// bookings by BookingService.
+type PromotionService struct {
+	repo  *repositories.PromotionRepository
+	audit *AuditService
+}
+
+func NewPromotionService(r *repositories.PromotionRepository, audit *AuditService) *PromotionService {
+	return &PromotionService{repo: r, audit: audit}
+}
+
+func validatePromotion(p *models.Promotion) error {
+	if p.Kind == "" {
+		p.Kind = models.PromotionPercent
+	}
+	switch p.Kind {
+	case models.PromotionPercent:
+		if p.Value <= 0 || p.Value > 100 {
+			return ErrInvalidPromotion
+		}
+	case models.PromotionAmount:
+		// сумма скидки относится ко всей брони
+		if p.Value <= 0 || p.ItemID > 0 || p.CategoryID > 0 {
+			return ErrInvalidPromotion
+		}
+	default:
+		return ErrInvalidPromotion
+	}
+	for _, d := range p.DaysOfWeek {
+		if d < '1' || d > '7' {
+			return ErrInvalidPromotion
+		}
+	}
+	if (p.TimeFrom != "" && !promotionTimeRe.MatchString(p.TimeFrom)) || (p.TimeTo != "" && !promotionTimeRe.MatchString(p.TimeTo)) {
+		return ErrInvalidPromotion
+	}
+	if p.ValidFrom != nil && p.ValidTo != nil && p.ValidTo.Before(*p.ValidFrom) {
+		return ErrInvalidPromotion
+	}
+	if p.MinSpend < 0 || p.MinClientVisits < 0 || p.UsageLimit < 0 {
+		return ErrInvalidPromotion
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	p.Code = normalizePromoCode(p.Code)
+	return nil
+}
+
+// checkPromoCode makes sure a promo code is not taken by another promotion
+// of the company.
+func (s *PromotionService) checkPromoCode(ctx context.Context, p *models.Promotion) error {
+	if p.Code == "" {
+		return nil
+	}
+	other, err := s.repo.GetByCode(ctx, p.CompanyID, p.Code)
+	if err != nil {
+		return err
+	}
+	if other != nil && other.ID != p.ID {
+		return ErrPromoCodeExists
+	}
+	return nil
+}
+
+func (s *PromotionService) CreatePromotion(ctx context.Context, p *models.Promotion) (int, error) {
+	p.CompanyID = ctx.Value(common.CtxCompanyID).(int)
+	p.BranchID = ctx.Value(common.CtxBranchID).(int)
+	p.ID = 0
+	if err := validatePromotion(p); err != nil {
+		return 0, err
+	}
+	if err := s.checkPromoCode(ctx, p); err != nil {
+		return 0, err
+	}
+	p.IsActive = true
+	id, err := s.repo.Create(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	s.audit.Record(ctx, "promotion", id, "create", nil, p)
+	return id, nil
+}
+
+func (s *PromotionService) GetPromotions(ctx context.Context) ([]models.Promotion, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return s.repo.GetAll(ctx, companyID, branchID)
+}
+
+func (s *PromotionService) GetPromotionByID(ctx context.Context, id int) (*models.Promotion, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return s.repo.GetByID(ctx, id, companyID, branchID)
+}
+
+// UpdatePromotion changes the rules of a promotion. Discounts already given
+// to bookings stay as they are until the booking is changed.
+func (s *PromotionService) UpdatePromotion(ctx context.Context, p *models.Promotion) error {
+	current, err := s.GetPromotionByID(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	p.CompanyID = current.CompanyID
+	p.BranchID = current.BranchID
+	p.UsedCount = current.UsedCount
+	if err := validatePromotion(p); err != nil {
+		return err
+	}
+	if err := s.checkPromoCode(ctx, p); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, p); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "promotion", p.ID, "update", current, p)
+	return nil
+}
+
+// DeletePromotion stops the promotion, the discounts it gave stay in the
+// history of bookings.
+func (s *PromotionService) DeletePromotion(ctx context.Context, id int) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	return s.repo.Deactivate(ctx, id, companyID, branchID)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/ratelimit"
+	"psclub-crm/internal/repositories"
+)
+
+const otpPurposeBooking = "booking"
+
+// PublicBookingService serves guests booking a table themselves. Requests
+// land as pending bookings that an admin confirms.
+type PublicBookingService struct {
+	companyRepo  *repositories.CompanyRepository
+	clientRepo   *repositories.ClientRepository
+	tableRepo    *repositories.TableRepository
+	bookings     *BookingService
+	otp          *OTPService
+	phoneLimiter *ratelimit.Limiter
+}
+
+func NewPublicBookingService(cr *repositories.CompanyRepository, clientRepo *repositories.ClientRepository, tr *repositories.TableRepository, bs *BookingService, otp *OTPService, phoneLimiter *ratelimit.Limiter) *PublicBookingService {
+	return &PublicBookingService{
+		companyRepo:  cr,
+		clientRepo:   clientRepo,
+		tableRepo:    tr,
+		bookings:     bs,
+		otp:          otp,
+		phoneLimiter: phoneLimiter,
+	}
+}
+
+// Branch returns branch available for public booking.
+func (s *PublicBookingService) Branch(ctx context.Context, id int) (*models.Branch, error) {
+	return s.companyRepo.GetBranch(ctx, id)
+}
+
+func (s *PublicBookingService) phone(raw, action string) (string, error) {
+	phone := normalizePhone(raw)
+	if len(phone) < 10 {
+		return "", ErrInvalidPhone
+	}
+	if !s.phoneLimiter.Allow(action + ":" + phone) {
+		return "", ErrTooManyRequests
+	}
+	return phone, nil
+}
+
+// RequestCode sends a confirmation code to the guest phone.
+func (s *PublicBookingService) RequestCode(ctx context.Context, rawPhone string) error {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	phone, err := s.phone(rawPhone, "otp")
+	if err != nil {
+		return err
+	}
+	return s.otp.Send(ctx, companyID, branchID, phone, otpPurposeBooking)
+}
+
+// Estimate calculates the price of a future booking.
+func (s *PublicBookingService) Estimate(ctx context.Context, req *models.PublicReservation) (*models.Booking, error) {
+	b := &models.Booking{
+		TableID:   req.TableID,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Items:     req.Items,
+	}
+	if err := s.bookings.EstimateBooking(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reserve verifies the phone and creates a pending booking. The client is
+// matched by phone or created.
+func (s *PublicBookingService) Reserve(ctx context.Context, req *models.PublicReservation) (*models.Booking, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	phone, err := s.phone(req.Phone, "reserve")
+	if err != nil {
+		return nil, err
+	}
+	if req.TableID <= 0 || !req.StartTime.After(time.Now()) || !req.EndTime.After(req.StartTime) {
+		return nil, ErrInvalidBookingTime
+	}
+	if _, err := s.tableRepo.GetByID(ctx, req.TableID, companyID, branchID); err != nil {
+		return nil, err
+	}
+	if err := s.otp.Verify(ctx, companyID, branchID, phone, otpPurposeBooking, req.Code); err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientRepo.GetByPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = &models.Client{Name: req.Name, Phone: phone, Status: "active"}
+		if client.Name == "" {
+			client.Name = phone
+		}
+		client.ID, err = s.clientRepo.Create(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := &models.Booking{
+		ClientID:  client.ID,
+		TableID:   req.TableID,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Note:      req.Note,
+		Items:     req.Items,
+		Status:    models.BookingStatusPending,
+	}
+	if err := s.bookings.EstimateBooking(ctx, b); err != nil {
+		return nil, err
+	}
+	if _, err := s.bookings.CreateBooking(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
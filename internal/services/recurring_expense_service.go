@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// RecurringExpenseService manages recurring expense templates and generates
+// expenses from them on schedule.
+type RecurringExpenseService struct {
+	repo        *repositories.RecurringExpenseRepository
+	expenseRepo *repositories.ExpenseRepository
+	expenseSvc  *ExpenseService
+}
+
+func NewRecurringExpenseService(r *repositories.RecurringExpenseRepository, er *repositories.ExpenseRepository, es *ExpenseService) *RecurringExpenseService {
+	return &RecurringExpenseService{repo: r, expenseRepo: er, expenseSvc: es}
+}
+
+func (s *RecurringExpenseService) validate(e *models.RecurringExpense) error {
+	if e.Title == "" {
+		return errors.New("title is required")
+	}
+	if e.Total <= 0 {
+		return ErrInvalidAmount
+	}
+	return validateSchedule(e)
+}
+
+// Create saves a template. The first charge is planned on NextRun if it is
+// given, otherwise on the first matching day starting from today.
+func (s *RecurringExpenseService) Create(ctx context.Context, e *models.RecurringExpense) (int, error) {
+	if err := s.validate(e); err != nil {
+		return 0, err
+	}
+	from := e.NextRun
+	if from.IsZero() {
+		from = time.Now()
+	}
+	next, err := nextOccurrence(e, from)
+	if err != nil {
+		return 0, err
+	}
+	e.NextRun = next
+	return s.repo.Create(ctx, e)
+}
+
+func (s *RecurringExpenseService) GetAll(ctx context.Context) ([]models.RecurringExpense, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *RecurringExpenseService) GetByID(ctx context.Context, id int) (*models.RecurringExpense, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Update changes the template and recalculates next run for the new schedule.
+// Already generated expenses are not touched.
+func (s *RecurringExpenseService) Update(ctx context.Context, e *models.RecurringExpense) error {
+	if err := s.validate(e); err != nil {
+		return err
+	}
+	current, err := s.repo.GetByID(ctx, e.ID)
+	if err != nil {
+		return err
+	}
+	from := e.NextRun
+	if from.IsZero() {
+		from = time.Now()
+		if current.LastRun != nil && !current.LastRun.Before(dateOnly(from)) {
+			from = current.LastRun.AddDate(0, 0, 1)
+		}
+	}
+	next, err := nextOccurrence(e, from)
+	if err != nil {
+		return err
+	}
+	e.NextRun = next
+	e.Paused = current.Paused
+	return s.repo.Update(ctx, e)
+}
+
+func (s *RecurringExpenseService) Delete(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Pause stops generation of expenses for the template.
+func (s *RecurringExpenseService) Pause(ctx context.Context, id int) error {
+	e, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetPaused(ctx, id, true, e.NextRun)
+}
+
+// Resume continues generation from today, charges missed while the template
+// was paused are skipped.
+func (s *RecurringExpenseService) Resume(ctx context.Context, id int) error {
+	e, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	from := time.Now()
+	if e.NextRun.After(dateOnly(from)) {
+		from = e.NextRun
+	}
+	next, err := nextOccurrence(e, from)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetPaused(ctx, id, false, next)
+}
+
+// Upcoming returns planned charges of active templates between from and to
+// inclusive, ordered by date.
+func (s *RecurringExpenseService) Upcoming(ctx context.Context, from, to time.Time) ([]models.UpcomingExpense, error) {
+	list, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from = dateOnly(from)
+	to = dateOnly(to)
+	var result []models.UpcomingExpense
+	for i := range list {
+		e := &list[i]
+		if e.Paused {
+			continue
+		}
+		day := e.NextRun
+		if day.Before(from) {
+			day = from
+		}
+		for !day.After(to) {
+			next, err := nextOccurrence(e, day)
+			if err != nil || next.After(to) {
+				break
+			}
+			result = append(result, models.UpcomingExpense{
+				RecurringExpenseID: e.ID,
+				Title:              e.Title,
+				Category:           e.Category,
+				Date:               next,
+				Total:              e.Total,
+			})
+			day = next.AddDate(0, 0, 1)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	return result, nil
+}
+
+// RunDue generates expenses for all templates whose run date has come. Runs
+// missed while the server was down are caught up one by one.
+func (s *RecurringExpenseService) RunDue(ctx context.Context, now time.Time) error {
+	today := dateOnly(now)
+	list, err := s.repo.GetDue(ctx, today)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		if err := s.runTemplate(ctx, &list[i], today); err != nil {
+			log.Printf("recurring expense %d: %v", list[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *RecurringExpenseService) runTemplate(ctx context.Context, e *models.RecurringExpense, today time.Time) error {
+	tctx := context.WithValue(ctx, common.CtxCompanyID, e.CompanyID)
+	tctx = context.WithValue(tctx, common.CtxBranchID, e.BranchID)
+	runs, next, err := dueRuns(e, today)
+	if err != nil {
+		return err
+	}
+	var last time.Time
+	for _, run := range runs {
+		if err := s.generate(tctx, e, run); err != nil {
+			return err
+		}
+		last = run
+	}
+	return s.repo.UpdateRun(ctx, e.ID, next, last)
+}
+
+func (s *RecurringExpenseService) generate(ctx context.Context, e *models.RecurringExpense, day time.Time) error {
+	exists, err := s.expenseRepo.ExistsForRecurring(ctx, e.ID, day)
+	if err != nil || exists {
+		return err
+	}
+	exp := models.Expense{
+		Date:               day,
+		Title:              e.Title,
+		CategoryID:         e.CategoryID,
+		Total:              e.Total,
+		Description:        e.Description,
+		Paid:               e.MarkPaid,
+		Counterparty:       e.Counterparty,
+		RecurringExpenseID: e.ID,
+	}
+	if !e.MarkPaid {
+		due := day.AddDate(0, 0, e.DueDays)
+		exp.DueDate = &due
+	}
+	_, err = s.expenseSvc.CreateExpense(ctx, &exp)
+	return err
+}
+
+// StartScheduler runs RunDue immediately and then every interval until ctx is
+// cancelled.
+func (s *RecurringExpenseService) StartScheduler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.RunDue(ctx, time.Now()); err != nil {
+				log.Printf("recurring expenses: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// maxScheduleLookahead limits search of the next occurrence so that an
+// expression that never matches (e.g. "31 2 *") does not loop forever.
+const maxScheduleLookahead = 5 * 366
+
+// cronSpec is a day-level cron expression: "day-of-month month day-of-week".
+// Classic five-field expressions are accepted too, minutes and hours are
+// ignored because expenses are generated once per day.
+type cronSpec struct {
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+	domAny bool
+	dowAny bool
+}
+
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 5 {
+		fields = fields[2:]
+	}
+	if len(fields) != 3 {
+		return nil, ErrInvalidSchedule
+	}
+	var spec cronSpec
+	var err error
+	if spec.domAny, err = parseCronField(fields[0], 1, 31, spec.dom[:]); err != nil {
+		return nil, err
+	}
+	if _, err = parseCronField(fields[1], 1, 12, spec.month[:]); err != nil {
+		return nil, err
+	}
+	// 7 допускается как воскресенье
+	var dow [8]bool
+	if spec.dowAny, err = parseCronField(fields[2], 0, 7, dow[:]); err != nil {
+		return nil, err
+	}
+	copy(spec.dow[:], dow[:7])
+	if dow[7] {
+		spec.dow[0] = true
+	}
+	return &spec, nil
+}
+
+// parseCronField fills set for values listed in field. Supports "*", numbers,
+// ranges "a-b", lists "a,b" and steps "*/n", "a-b/n". Returns true when the
+// field is "*".
+func parseCronField(field string, min, max int, set []bool) (bool, error) {
+	if field == "*" {
+		for i := min; i <= max; i++ {
+			set[i] = true
+		}
+		return true, nil
+	}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return false, ErrInvalidSchedule
+			}
+			step = n
+			part = part[:idx]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			if idx := strings.Index(part, "-"); idx >= 0 {
+				a, err1 := strconv.Atoi(part[:idx])
+				b, err2 := strconv.Atoi(part[idx+1:])
+				if err1 != nil || err2 != nil {
+					return false, ErrInvalidSchedule
+				}
+				lo, hi = a, b
+			} else {
+				n, err := strconv.Atoi(part)
+				if err != nil {
+					return false, ErrInvalidSchedule
+				}
+				lo, hi = n, n
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return false, ErrInvalidSchedule
+		}
+		for i := lo; i <= hi; i += step {
+			set[i] = true
+		}
+	}
+	return false, nil
+}
+
+func (c *cronSpec) match(day time.Time) bool {
+	if !c.month[int(day.Month())] {
+		return false
+	}
+	domOK := c.dom[day.Day()]
+	dowOK := c.dow[int(day.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowOK
+	case c.dowAny:
+		return domOK
+	default:
+		// как в cron: при заданных обоих полях достаточно совпадения одного
+		return domOK || dowOK
+	}
+}
+
+// validateSchedule checks schedule fields of the template.
+func validateSchedule(e *models.RecurringExpense) error {
+	switch e.Schedule {
+	case "monthly":
+		if e.DayOfMonth < 1 || e.DayOfMonth > 31 {
+			return ErrInvalidSchedule
+		}
+	case "weekly":
+		if e.DayOfWeek < 0 || e.DayOfWeek > 6 {
+			return ErrInvalidSchedule
+		}
+	case "custom":
+		if _, err := parseCron(e.Cron); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+// nextOccurrence returns the first day on or after from when the template
+// has to be charged.
+func nextOccurrence(e *models.RecurringExpense, from time.Time) (time.Time, error) {
+	day := dateOnly(from)
+	var match func(time.Time) bool
+	switch e.Schedule {
+	case "monthly":
+		match = func(t time.Time) bool {
+			target := e.DayOfMonth
+			if last := daysInMonth(t); target > last {
+				target = last
+			}
+			return t.Day() == target
+		}
+	case "weekly":
+		match = func(t time.Time) bool { return int(t.Weekday()) == e.DayOfWeek }
+	case "custom":
+		spec, err := parseCron(e.Cron)
+		if err != nil {
+			return time.Time{}, err
+		}
+		match = spec.match
+	default:
+		return time.Time{}, ErrInvalidSchedule
+	}
+	for i := 0; i < maxScheduleLookahead; i++ {
+		if match(day) {
+			return day, nil
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, ErrInvalidSchedule
+}
+
+// dueRuns returns the days from NextRun up to today the template has to be
+// charged on, runs missed while the server was down included, and the next
+// run after them.
+func dueRuns(e *models.RecurringExpense, today time.Time) ([]time.Time, time.Time, error) {
+	run := e.NextRun
+	var runs []time.Time
+	for !run.After(today) {
+		runs = append(runs, run)
+		next, err := nextOccurrence(e, run.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		run = next
+	}
+	return runs, run, nil
+}
+
+func daysInMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr  string
+		valid bool
+	}{
+		{"1 * *", true},
+		{"0 0 1 * *", true},
+		{"* * 1-5", true},
+		{"*/15 * *", true},
+		{"1,15 * *", true},
+		{"1-10/3 * *", true},
+		{"* * 7", true},
+		{"", false},
+		{"1 *", false},
+		{"1 2 3 4", false},
+		{"0 * *", false},
+		{"32 * *", false},
+		{"* 13 *", false},
+		{"* * 8", false},
+		{"5-1 * *", false},
+		{"*/0 * *", false},
+		{"a * *", false},
+		{"1-x * *", false},
+	}
+	for _, tt := range tests {
+		_, err := parseCron(tt.expr)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseCron(%q) error = %v, want valid %v", tt.expr, err, tt.valid)
+		}
+	}
+}
+
+func TestCronMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		day  string
+		want bool
+	}{
+		{"* * *", "2026-10-19", true},
+		{"19 * *", "2026-10-19", true},
+		{"20 * *", "2026-10-19", false},
+		{"* 10 *", "2026-10-19", true},
+		{"* 11 *", "2026-10-19", false},
+		{"* * 1", "2026-10-19", true},
+		{"* * 1-5", "2026-10-25", false},
+		// 7 и 0 - воскресенье
+		{"* * 7", "2026-10-25", true},
+		{"* * 0", "2026-10-25", true},
+		{"*/10 * *", "2026-10-21", true},
+		{"*/10 * *", "2026-10-20", false},
+		// при заданных дне месяца и дне недели достаточно одного совпадения
+		{"13 * 5", "2026-10-02", true},
+		{"13 * 5", "2026-10-13", true},
+		{"13 * 5", "2026-10-14", false},
+		{"13 2 5", "2026-10-02", false},
+	}
+	for _, tt := range tests {
+		spec, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := spec.match(day(tt.day)); got != tt.want {
+			t.Errorf("%q match %s = %v, want %v", tt.expr, tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name    string
+		e       models.RecurringExpense
+		from    string
+		want    string
+		wantErr bool
+	}{
+		{"monthly same day", models.RecurringExpense{Schedule: "monthly", DayOfMonth: 15}, "2026-03-15", "2026-03-15", false},
+		{"monthly next month", models.RecurringExpense{Schedule: "monthly", DayOfMonth: 15}, "2026-03-16", "2026-04-15", false},
+		{"monthly short month", models.RecurringExpense{Schedule: "monthly", DayOfMonth: 31}, "2026-02-10", "2026-02-28", false},
+		{"weekly same day", models.RecurringExpense{Schedule: "weekly", DayOfWeek: 1}, "2026-10-19", "2026-10-19", false},
+		{"weekly sunday", models.RecurringExpense{Schedule: "weekly", DayOfWeek: 0}, "2026-10-19", "2026-10-25", false},
+		{"custom sunday as 7", models.RecurringExpense{Schedule: "custom", Cron: "* * 7"}, "2026-10-19", "2026-10-25", false},
+		{"custom day or weekday", models.RecurringExpense{Schedule: "custom", Cron: "13 * 5"}, "2026-10-01", "2026-10-02", false},
+		{"custom quarterly", models.RecurringExpense{Schedule: "custom", Cron: "1 */3 *"}, "2026-02-05", "2026-04-01", false},
+		{"custom never", models.RecurringExpense{Schedule: "custom", Cron: "31 2 *"}, "2026-01-01", "", true},
+		{"custom invalid", models.RecurringExpense{Schedule: "custom", Cron: "1 *"}, "2026-01-01", "", true},
+		{"unknown schedule", models.RecurringExpense{Schedule: "daily"}, "2026-01-01", "", true},
+	}
+	for _, tt := range tests {
+		// время дня не влияет на результат
+		got, err := nextOccurrence(&tt.e, day(tt.from).Add(15*time.Hour))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %s", tt.name, got.Format("2006-01-02"))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !got.Equal(day(tt.want)) {
+			t.Errorf("%s: got %s, want %s", tt.name, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestDueRuns(t *testing.T) {
+	tests := []struct {
+		name  string
+		e     models.RecurringExpense
+		today string
+		runs  []string
+		next  string
+	}{
+		{"not due yet", models.RecurringExpense{Schedule: "monthly", DayOfMonth: 25, NextRun: day("2026-10-25")}, "2026-10-19", nil, "2026-10-25"},
+		{"due today", models.RecurringExpense{Schedule: "monthly", DayOfMonth: 19, NextRun: day("2026-10-19")}, "2026-10-19", []string{"2026-10-19"}, "2026-11-19"},
+		{"missed months", models.RecurringExpense{Schedule: "monthly", DayOfMonth: 5, NextRun: day("2026-07-05")}, "2026-10-19",
+			[]string{"2026-07-05", "2026-08-05", "2026-09-05", "2026-10-05"}, "2026-11-05"},
+		{"missed weeks", models.RecurringExpense{Schedule: "weekly", DayOfWeek: 1, NextRun: day("2026-10-05")}, "2026-10-19",
+			[]string{"2026-10-05", "2026-10-12", "2026-10-19"}, "2026-10-26"},
+		{"end of month", models.RecurringExpense{Schedule: "monthly", DayOfMonth: 31, NextRun: day("2026-01-31")}, "2026-03-31",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31"}, "2026-04-30"},
+		{"missed custom", models.RecurringExpense{Schedule: "custom", Cron: "1,15 * *", NextRun: day("2026-09-15")}, "2026-10-19",
+			[]string{"2026-09-15", "2026-10-01", "2026-10-15"}, "2026-11-01"},
+	}
+	for _, tt := range tests {
+		runs, next, err := dueRuns(&tt.e, day(tt.today))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(runs) != len(tt.runs) {
+			t.Errorf("%s: got %d runs, want %d", tt.name, len(runs), len(tt.runs))
+			continue
+		}
+		for i, r := range runs {
+			if !r.Equal(day(tt.runs[i])) {
+				t.Errorf("%s: run %d = %s, want %s", tt.name, i, r.Format("2006-01-02"), tt.runs[i])
+			}
+		}
+		if !next.Equal(day(tt.next)) {
+			t.Errorf("%s: next = %s, want %s", tt.name, next.Format("2006-01-02"), tt.next)
+		}
+	}
+}
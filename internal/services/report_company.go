@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"psclub-crm/internal/models"
+)
+
+// Сводные отчеты владельца: отчеты филиалов считаются как обычно, по
+// отдельности, и складываются по компании.
+
+// CompanySummaryReport builds the summary report of every branch of the
+// company and their totals.
+func (s *ReportService) CompanySummaryReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID int) (*models.CompanySummaryReport, error) {
+	branches, err := s.companyRepo.GetBranches(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.CompanySummaryReport{Branches: []models.BranchSummary{}}
+	// загрузка компании - средняя по филиалам с весом по числу столов
+	tables := 0
+	for _, b := range branches {
+		r, err := s.SummaryReport(ctx, from, to, tFrom, tTo, userID, companyID, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		n, err := s.repo.TableCount(ctx, companyID, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		tables += n
+		res.Total += r.Total
+		res.TotalRevenue += r.TotalRevenue
+		res.TotalClients += r.TotalClients
+		res.TotalCost += r.TotalCost
+		res.LoadPercent += r.LoadPercent * float64(n)
+		res.Branches = append(res.Branches, models.BranchSummary{BranchID: b.ID, BranchName: b.Name, SummaryReport: *r})
+	}
+	if res.TotalClients > 0 {
+		res.AvgCheck = res.TotalRevenue / res.TotalClients
+	}
+	if tables > 0 {
+		res.LoadPercent /= float64(tables)
+	}
+	return res, nil
+}
+
+// CompanySalesReport builds the sales report of every branch of the company,
+// income is summed up by category and payment type.
+func (s *ReportService) CompanySalesReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID int) (*models.CompanySalesReport, error) {
+	branches, err := s.companyRepo.GetBranches(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.CompanySalesReport{
+		IncomeByCategory: []models.CategoryIncome{},
+		IncomeByPayment:  []models.CategoryIncome{},
+		Branches:         []models.BranchSales{},
+	}
+	for _, b := range branches {
+		r, err := s.SalesReport(ctx, from, to, tFrom, tTo, userID, companyID, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		res.IncomeByCategory = addIncome(res.IncomeByCategory, r.IncomeByCategory)
+		res.IncomeByPayment = addIncome(res.IncomeByPayment, r.IncomeByPayment)
+		res.TotalIncome += r.TotalIncome
+		res.TotalExpenses += r.TotalExpenses
+		res.PaidExpenses += r.PaidExpenses
+		res.UnpaidExpenses += r.UnpaidExpenses
+		res.NetProfit += r.NetProfit
+		res.ForecastTotal += r.ForecastTotal
+		res.Branches = append(res.Branches, models.BranchSales{BranchID: b.ID, BranchName: b.Name, SalesReport: *r})
+	}
+	return res, nil
+}
+
+// CompanyAdminsReport lists admins of all branches of the company.
+func (s *ReportService) CompanyAdminsReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID int) (*models.CompanyAdminsReport, error) {
+	branches, err := s.companyRepo.GetBranches(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.CompanyAdminsReport{Admins: []models.BranchAdminRow{}}
+	for _, b := range branches {
+		r, err := s.AdminsReport(ctx, from, to, tFrom, tTo, userID, companyID, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range r.Admins {
+			res.Admins = append(res.Admins, models.BranchAdminRow{BranchID: b.ID, BranchName: b.Name, AdminReportRow: a})
+		}
+	}
+	return res, nil
+}
+
+// CompanyTablesReport lists tables of all branches of the company.
+func (s *ReportService) CompanyTablesReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID int) (*models.CompanyTablesReport, error) {
+	branches, err := s.companyRepo.GetBranches(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	res := &models.CompanyTablesReport{Tables: []models.BranchTableRow{}}
+	for _, b := range branches {
+		tables, err := s.TablesReport(ctx, from, to, tFrom, tTo, userID, companyID, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tables {
+			res.Tables = append(res.Tables, models.BranchTableRow{BranchID: b.ID, BranchName: b.Name, TableReport: t})
+		}
+	}
+	return res, nil
+}
+
+// BranchComparison puts the key figures of the branches of the company side
+// by side.
+func (s *ReportService) BranchComparison(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID int) ([]models.BranchComparison, error) {
+	branches, err := s.companyRepo.GetBranches(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	list := []models.BranchComparison{}
+	revenue := 0.0
+	for _, b := range branches {
+		sum, err := s.repo.SummaryReport(ctx, from, to, tFrom, tTo, userID, companyID, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		sales, err := s.repo.SalesReport(ctx, from, to, tFrom, tTo, userID, companyID, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		revenue += sum.TotalRevenue
+		list = append(list, models.BranchComparison{
+			BranchID:     b.ID,
+			BranchName:   b.Name,
+			TotalRevenue: sum.TotalRevenue,
+			TotalClients: sum.TotalClients,
+			AvgCheck:     sum.AvgCheck,
+			LoadPercent:  sum.LoadPercent,
+			TotalIncome:  sales.TotalIncome,
+			Expenses:     sales.TotalExpenses,
+			NetProfit:    sales.NetProfit,
+		})
+	}
+	if revenue > 0 {
+		for i := range list {
+			list[i].RevenueShare = list[i].TotalRevenue * 100 / revenue
+		}
+	}
+	return list, nil
+}
+
+// addIncome adds income lines to the totals by category name.
+func addIncome(total, lines []models.CategoryIncome) []models.CategoryIncome {
+	for _, l := range lines {
+		found := false
+		for i := range total {
+			if total[i].Category == l.Category {
+				total[i].Total += l.Total
+				found = true
+				break
+			}
+		}
+		if !found {
+			total = append(total, l)
+		}
+	}
+	return total
+}
@@ -2,17 +2,20 @@ package services
 
 import (
 	"context"
+	"psclub-crm/internal/common"
 	"psclub-crm/internal/models"
 	"psclub-crm/internal/repositories"
 	"time"
 )
 
 type ReportService struct {
-	repo *repositories.ReportRepository
+	repo        *repositories.ReportRepository
+	recurring   *RecurringExpenseService
+	companyRepo *repositories.CompanyRepository
 }
 
-func NewReportService(repo *repositories.ReportRepository) *ReportService {
-	return &ReportService{repo: repo}
+func NewReportService(repo *repositories.ReportRepository, recurring *RecurringExpenseService, companyRepo *repositories.CompanyRepository) *ReportService {
+	return &ReportService{repo: repo, recurring: recurring, companyRepo: companyRepo}
 }
 
 func (s *ReportService) SummaryReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) (*models.SummaryReport, error) {
@@ -22,7 +25,32 @@ func (s *ReportService) AdminsReport(ctx context.Context, from, to time.Time, tF
 	return s.repo.AdminsReport(ctx, from, to, tFrom, tTo, userID, companyID, branchID)
 }
 func (s *ReportService) SalesReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) (*models.SalesReport, error) {
-	return s.repo.SalesReport(ctx, from, to, tFrom, tTo, userID, companyID, branchID)
+	report, err := s.repo.SalesReport(ctx, from, to, tFrom, tTo, userID, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if s.recurring == nil {
+		return report, nil
+	}
+	// Прогноз: регулярные расходы, которые будут созданы до конца периода
+	start := time.Now().AddDate(0, 0, 1)
+	if from.After(start) {
+		start = from
+	}
+	if start.After(to) {
+		return report, nil
+	}
+	tctx := context.WithValue(ctx, common.CtxCompanyID, companyID)
+	tctx = context.WithValue(tctx, common.CtxBranchID, branchID)
+	upcoming, err := s.recurring.Upcoming(tctx, start, to)
+	if err != nil {
+		return nil, err
+	}
+	report.Forecast = upcoming
+	for _, u := range upcoming {
+		report.ForecastTotal += u.Total
+	}
+	return report, nil
 }
 func (s *ReportService) AnalyticsReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) (*models.AnalyticsReport, error) {
 	return s.repo.AnalyticsReport(ctx, from, to, tFrom, tTo, userID, companyID, branchID)
@@ -34,3 +62,15 @@ func (s *ReportService) DiscountsReport(ctx context.Context, from, to time.Time,
 func (s *ReportService) TablesReport(ctx context.Context, from, to time.Time, tFrom, tTo string, userID, companyID, branchID int) ([]models.TableReport, error) {
 	return s.repo.TablesReport(ctx, from, to, tFrom, tTo, userID, companyID, branchID)
 }
+
+func (s *ReportService) PayablesReport(ctx context.Context, asOf time.Time, companyID, branchID int) (*models.PayablesReport, error) {
+	return s.repo.PayablesReport(ctx, asOf, companyID, branchID)
+}
+
+func (s *ReportService) CertificatesReport(ctx context.Context, companyID, branchID int) (*models.CertificatesReport, error) {
+	return s.repo.CertificatesReport(ctx, companyID, branchID)
+}
+
+func (s *ReportService) WalletsReport(ctx context.Context, companyID, branchID int) (*models.WalletsReport, error) {
+	return s.repo.WalletsReport(ctx, companyID, branchID)
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+)
+
+// roleRanks orders the known roles: a higher rank can do everything a lower
+// one can.
+var roleRanks = map[string]int{
+	models.RoleAdmin:    1,
+	models.RoleDirector: 2,
+	models.RoleOwner:    3,
+}
+
+// checkRole makes sure the role is known and the current user may give it:
+// nobody can grant a role above their own.
+func checkRole(ctx context.Context, role string) error {
+	rank, ok := roleRanks[role]
+	if !ok {
+		return ErrInvalidRole
+	}
+	if rank > callerRank(ctx) {
+		return ErrRoleForbidden
+	}
+	return nil
+}
+
+// callerRank returns the rank of the current user, 0 when unknown.
+func callerRank(ctx context.Context) int {
+	role, _ := ctx.Value(common.CtxRole).(string)
+	return roleRanks[role]
+}
@@ -2,32 +2,94 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"psclub-crm/internal/models"
 	"psclub-crm/internal/repositories"
+	"time"
 )
 
 type SettingsService struct {
-	repo *repositories.SettingsRepository
+	repo  *repositories.SettingsRepository
+	audit *AuditService
 }
 
-func NewSettingsService(r *repositories.SettingsRepository) *SettingsService {
-	return &SettingsService{repo: r}
+func NewSettingsService(r *repositories.SettingsRepository, audit *AuditService) *SettingsService {
+	return &SettingsService{repo: r, audit: audit}
 }
 
 func (s *SettingsService) GetSettings(ctx context.Context, companyID, branchID int) (*models.Settings, error) {
 	return s.repo.Get(ctx, companyID, branchID)
 }
 
-func (s *SettingsService) UpdateSettings(ctx context.Context, set *models.Settings) error {
-	return s.repo.Update(ctx, set)
+// UpdateSettings changes only the fields present in the update, the stored
+// values of the others are kept. Settings of another branch are not found.
+func (s *SettingsService) UpdateSettings(ctx context.Context, id, companyID, branchID int, upd *models.SettingsUpdate) (*models.Settings, error) {
+	before, err := s.repo.Get(ctx, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if before.ID != id {
+		return nil, sql.ErrNoRows
+	}
+	set := *before
+	upd.Apply(&set)
+	if err := validateSettings(&set); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, &set); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, "settings", set.ID, "update", before, &set)
+	return &set, nil
+}
+
+func validateSettings(set *models.Settings) error {
+	for _, v := range []int{set.BlockTime, set.BonusPercent, set.TablesCount, set.NotificationTime,
+		set.DepositForfeitPercent, set.ReservationGraceMinutes, set.DepositRefundHours, set.CleanupBufferMinutes} {
+		if v < 0 {
+			return ErrInvalidSettings
+		}
+	}
+	if set.BonusPercent > 100 || set.DepositForfeitPercent > 100 {
+		return ErrInvalidSettings
+	}
+	if !validClock(set.WorkTimeFrom) || !validClock(set.WorkTimeTo) {
+		return ErrInvalidSettings
+	}
+	return nil
+}
+
+// validClock reports whether s is a time of day HH:MM. The stored value is
+// read back from the database with seconds, HH:MM:SS is accepted too.
+func validClock(s string) bool {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *SettingsService) CreateSettings(ctx context.Context, set *models.Settings) (int, error) {
-	return s.repo.Create(ctx, set)
+	if err := validateSettings(set); err != nil {
+		return 0, err
+	}
+	id, err := s.repo.Create(ctx, set)
+	if err != nil {
+		return 0, err
+	}
+	set.ID = id
+	s.audit.Record(ctx, "settings", id, "create", nil, set)
+	return id, nil
 }
 
 func (s *SettingsService) DeleteSettings(ctx context.Context, id, companyID, branchID int) error {
-	return s.repo.Delete(ctx, id, companyID, branchID)
+	before, _ := s.repo.Get(ctx, companyID, branchID)
+	if err := s.repo.Delete(ctx, id, companyID, branchID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "settings", id, "delete", before, nil)
+	return nil
 }
 
 func (s *SettingsService) GetTablesCount(ctx context.Context, companyID, branchID int) (int, error) {
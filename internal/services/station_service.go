@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+type StationService struct {
+	repo *repositories.StationRepository
+}
+
+func NewStationService(r *repositories.StationRepository) *StationService {
+	return &StationService{repo: r}
+}
+
+func (s *StationService) CreateStation(ctx context.Context, st *models.Station) (int, error) {
+	existing, err := s.repo.GetByName(ctx, st.Name, st.CompanyID, st.BranchID)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		return 0, ErrNameExists
+	}
+	return s.repo.Create(ctx, st)
+}
+
+func (s *StationService) GetAllStations(ctx context.Context, companyID, branchID int) ([]models.Station, error) {
+	return s.repo.GetAll(ctx, companyID, branchID)
+}
+
+func (s *StationService) GetStationByID(ctx context.Context, id, companyID, branchID int) (*models.Station, error) {
+	return s.repo.GetByID(ctx, id, companyID, branchID)
+}
+
+func (s *StationService) UpdateStation(ctx context.Context, st *models.Station) error {
+	return s.repo.Update(ctx, st)
+}
+
+func (s *StationService) DeleteStation(ctx context.Context, id, companyID, branchID int) error {
+	return s.repo.Delete(ctx, id, companyID, branchID)
+}
@@ -0,0 +1,38 @@
+package services
+
+import "psclub-crm/internal/models"
+
+// TemplateService keeps the seed templates for new clubs loaded on startup.
+type TemplateService struct {
+	list       []models.SeedTemplate
+	defaultKey string
+}
+
+// NewTemplateService uses the template with defaultKey when none is chosen,
+// or the first one if there is no such template.
+func NewTemplateService(list []models.SeedTemplate, defaultKey string) *TemplateService {
+	return &TemplateService{list: list, defaultKey: defaultKey}
+}
+
+func (s *TemplateService) List() []models.SeedTemplate {
+	if s.list == nil {
+		return []models.SeedTemplate{}
+	}
+	return s.list
+}
+
+// Get returns the template by key, the default one for an empty key.
+func (s *TemplateService) Get(key string) (*models.SeedTemplate, error) {
+	if key == "" {
+		key = s.defaultKey
+	}
+	for i := range s.list {
+		if s.list[i].Key == key {
+			return &s.list[i], nil
+		}
+	}
+	if key == s.defaultKey && len(s.list) > 0 {
+		return &s.list[0], nil
+	}
+	return nil, ErrUnknownTemplate
+}
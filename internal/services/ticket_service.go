@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/realtime"
+	"psclub-crm/internal/repositories"
+)
+
+// preparedCategories are always prepared at the station of the same name,
+// even when the category is not configured.
+var preparedCategories = map[string]bool{"Бар": true, "Кальян": true}
+
+// TicketService turns bar and hookah lines of bookings into order tickets
+// for preparation stations and streams their changes.
+type TicketService struct {
+	repo          *repositories.OrderTicketRepository
+	stationRepo   *repositories.StationRepository
+	priceItemRepo *repositories.PriceItemRepository
+	categoryRepo  *repositories.CategoryRepository
+	broker        *realtime.Broker
+}
+
+func NewTicketService(r *repositories.OrderTicketRepository, stationRepo *repositories.StationRepository, priceRepo *repositories.PriceItemRepository, categoryRepo *repositories.CategoryRepository, broker *realtime.Broker) *TicketService {
+	return &TicketService{repo: r, stationRepo: stationRepo, priceItemRepo: priceRepo, categoryRepo: categoryRepo, broker: broker}
+}
+
+func stationTopic(stationID int) string {
+	return "station:" + strconv.Itoa(stationID)
+}
+
+func (s *TicketService) publish(t *models.OrderTicket) {
+	if s.broker != nil {
+		s.broker.Publish(stationTopic(t.StationID), *t)
+	}
+}
+
+// stationFor returns the station preparing items of the price item category,
+// zero if the item needs no preparation.
+func (s *TicketService) stationFor(ctx context.Context, companyID, branchID, itemID int) (int, error) {
+	pi, err := s.priceItemRepo.GetByID(ctx, itemID)
+	if err != nil {
+		return 0, err
+	}
+	cat, err := s.categoryRepo.GetByID(ctx, pi.CategoryID)
+	if err != nil {
+		return 0, err
+	}
+	if cat.StationID > 0 {
+		return cat.StationID, nil
+	}
+	if !cat.IsPrepared && !preparedCategories[cat.Name] {
+		return 0, nil
+	}
+	st, err := s.stationRepo.GetByName(ctx, cat.Name, companyID, branchID)
+	if err != nil || st == nil {
+		return 0, err
+	}
+	return st.ID, nil
+}
+
+// CreateForLines creates tickets for booking lines that are prepared at a
+// station. Errors are only logged: a missing ticket must not block the sale.
+func (s *TicketService) CreateForLines(ctx context.Context, b *models.Booking, lines []models.BookingItem) {
+	if s == nil {
+		return
+	}
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	for _, it := range lines {
+		if it.Status == models.BookingItemVoided {
+			continue
+		}
+		stationID, err := s.stationFor(ctx, companyID, branchID, it.ItemID)
+		if err != nil {
+			log.Printf("order ticket station error: %v", err)
+			continue
+		}
+		if stationID == 0 {
+			continue
+		}
+		t := models.OrderTicket{
+			StationID:     stationID,
+			BookingID:     b.ID,
+			BookingItemID: it.ID,
+			ItemID:        it.ItemID,
+			ItemName:      it.ItemName,
+			Quantity:      it.Quantity,
+			TableID:       b.TableID,
+			Status:        models.TicketNew,
+			UserID:        it.UserID,
+		}
+		t.ID, err = s.repo.Create(ctx, companyID, branchID, &t)
+		if err != nil {
+			log.Printf("order ticket create error: %v", err)
+			continue
+		}
+		if created, err := s.repo.GetByID(ctx, t.ID, companyID, branchID); err == nil {
+			t = *created
+		}
+		s.publish(&t)
+	}
+}
+
+// CancelOpen cancels tickets not served yet, of one line when bookingItemID
+// is set or of the whole booking.
+func (s *TicketService) CancelOpen(ctx context.Context, bookingID, bookingItemID int) {
+	if s == nil {
+		return
+	}
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	tickets, err := s.repo.GetOpenByBooking(ctx, companyID, branchID, bookingID, bookingItemID)
+	if err != nil {
+		log.Printf("order tickets get error: %v", err)
+		return
+	}
+	for _, t := range tickets {
+		ok, err := s.repo.SetStatus(ctx, companyID, branchID, t.ID, t.Status, models.TicketCancelled)
+		if err != nil || !ok {
+			continue
+		}
+		t.Status = models.TicketCancelled
+		s.publish(&t)
+	}
+}
+
+// StationTickets returns the queue of a station. Without statuses the open
+// tickets (new and in progress) are returned.
+func (s *TicketService) StationTickets(ctx context.Context, companyID, branchID, stationID int, statuses []string) ([]models.OrderTicket, error) {
+	if _, err := s.stationRepo.GetByID(ctx, stationID, companyID, branchID); err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		statuses = []string{models.TicketNew, models.TicketInProgress}
+	}
+	for _, st := range statuses {
+		switch st {
+		case models.TicketNew, models.TicketInProgress, models.TicketServed, models.TicketCancelled:
+		default:
+			return nil, ErrInvalidTicketStatus
+		}
+	}
+	return s.repo.GetByStation(ctx, companyID, branchID, stationID, statuses)
+}
+
+// Start marks that the station began preparing the ticket.
+func (s *TicketService) Start(ctx context.Context, companyID, branchID, id int) (*models.OrderTicket, error) {
+	return s.advance(ctx, companyID, branchID, id, models.TicketNew, models.TicketInProgress)
+}
+
+// Serve marks the ticket as handed to the guests. A ticket may be served
+// without being started.
+func (s *TicketService) Serve(ctx context.Context, companyID, branchID, id int) (*models.OrderTicket, error) {
+	t, err := s.repo.GetByID(ctx, id, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != models.TicketNew && t.Status != models.TicketInProgress {
+		return nil, ErrInvalidTicketStatus
+	}
+	return s.advance(ctx, companyID, branchID, id, t.Status, models.TicketServed)
+}
+
+func (s *TicketService) advance(ctx context.Context, companyID, branchID, id int, from, to string) (*models.OrderTicket, error) {
+	ok, err := s.repo.SetStatus(ctx, companyID, branchID, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.repo.GetByID(ctx, id, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTicketStatus
+	}
+	s.publish(t)
+	return t, nil
+}
+
+// Subscribe streams ticket changes of a station. The returned function must
+// be called when the listener leaves.
+func (s *TicketService) Subscribe(stationID int) (<-chan interface{}, func()) {
+	return s.broker.Subscribe(stationTopic(stationID))
+}
@@ -2,20 +2,61 @@ package services
 
 import (
 	"context"
+	"time"
+
 	"psclub-crm/internal/models"
 	"psclub-crm/internal/repositories"
 )
 
 type UserService struct {
-	repo *repositories.UserRepository
+	repo        *repositories.UserRepository
+	rateRepo    *repositories.UserSalaryRateRepository
+	branchRepo  *repositories.UserBranchRepository
+	companyRepo *repositories.CompanyRepository
+	tokenRepo   *repositories.TokenRepository
+	audit       *AuditService
+}
+
+func NewUserService(r *repositories.UserRepository, rateRepo *repositories.UserSalaryRateRepository, branchRepo *repositories.UserBranchRepository,
+	companyRepo *repositories.CompanyRepository, tokenRepo *repositories.TokenRepository, audit *AuditService) *UserService {
+	return &UserService{repo: r, rateRepo: rateRepo, branchRepo: branchRepo, companyRepo: companyRepo, tokenRepo: tokenRepo, audit: audit}
 }
 
-func NewUserService(r *repositories.UserRepository) *UserService {
-	return &UserService{repo: r}
+// auditUser returns a copy of the user suitable for the audit log: the
+// password hash is dropped, a newly set password is only marked as changed.
+func auditUser(u *models.User) *models.User {
+	if u == nil {
+		return nil
+	}
+	cp := *u
+	if cp.Password != "" {
+		cp.Password = "***"
+	}
+	return &cp
 }
 
+// CreateUser adds an employee. Without a role the employee becomes an
+// administrator, a role above the one of the current user is refused.
 func (s *UserService) CreateUser(ctx context.Context, u *models.User) (int, error) {
-	return s.repo.Create(ctx, u)
+	if u.Role == "" {
+		u.Role = models.RoleAdmin
+	}
+	if err := checkRole(ctx, u.Role); err != nil {
+		return 0, err
+	}
+	id, err := s.repo.Create(ctx, u)
+	if err != nil {
+		return 0, err
+	}
+	u.ID = id
+	s.audit.Record(ctx, "user", id, "create", nil, auditUser(u))
+	if err := s.saveHomeBranch(ctx, u); err != nil {
+		return id, err
+	}
+	if err := s.saveSalaryRate(ctx, u); err != nil {
+		return id, err
+	}
+	return id, nil
 }
 
 func (s *UserService) GetAllUsers(ctx context.Context, companyID, branchID int) ([]models.User, error) {
@@ -26,10 +67,189 @@ func (s *UserService) GetUserByID(ctx context.Context, id, companyID, branchID i
 	return s.repo.GetByID(ctx, id, companyID, branchID)
 }
 
+// UpdateUser updates user data. If salary settings were changed a new salary
+// rate is stored so that reports for past periods keep using the old values.
+// A new password or role ends all sessions of the user. Employees with a
+// role above the one of the current user cannot be changed.
 func (s *UserService) UpdateUser(ctx context.Context, u *models.User) error {
-	return s.repo.Update(ctx, u)
+	current, err := s.repo.GetByID(ctx, u.ID, u.CompanyID, u.BranchID)
+	if err != nil {
+		return err
+	}
+	if roleRanks[current.Role] > callerRank(ctx) {
+		return ErrRoleForbidden
+	}
+	if u.Role == "" {
+		u.Role = current.Role
+	}
+	if u.Role != current.Role {
+		if err := checkRole(ctx, u.Role); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.Update(ctx, u); err != nil {
+		return err
+	}
+	if u.Password != "" || u.Role != current.Role {
+		if err := s.tokenRepo.DeleteByUser(ctx, u.ID); err != nil {
+			return err
+		}
+	}
+	before := auditUser(current)
+	before.Password = ""
+	s.audit.Record(ctx, "user", u.ID, "update", before, auditUser(u))
+	if err := s.saveHomeBranch(ctx, u); err != nil {
+		return err
+	}
+	if salaryChanged(current, u) || u.SalaryEffectiveFrom != nil {
+		return s.saveSalaryRate(ctx, u)
+	}
+	return nil
 }
 
 func (s *UserService) DeleteUser(ctx context.Context, id, companyID, branchID int) error {
-	return s.repo.Delete(ctx, id, companyID, branchID)
+	current, err := s.repo.GetByID(ctx, id, companyID, branchID)
+	if err != nil {
+		return err
+	}
+	if roleRanks[current.Role] > callerRank(ctx) {
+		return ErrRoleForbidden
+	}
+	if err := s.repo.Delete(ctx, id, companyID, branchID); err != nil {
+		return err
+	}
+	if err := s.tokenRepo.DeleteByUser(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, "user", id, "delete", auditUser(current), nil)
+	return nil
+}
+
+// GetSalaryHistory returns all salary rates of the user, newest first.
+func (s *UserService) GetSalaryHistory(ctx context.Context, id, companyID, branchID int) ([]models.UserSalaryRate, error) {
+	if _, err := s.repo.GetByID(ctx, id, companyID, branchID); err != nil {
+		return nil, err
+	}
+	return s.rateRepo.GetByUser(ctx, id, companyID, branchID)
+}
+
+// GetUserBranches returns the branches the user has access to.
+func (s *UserService) GetUserBranches(ctx context.Context, id, companyID, branchID int) ([]models.UserBranch, error) {
+	if _, err := s.repo.GetByID(ctx, id, companyID, branchID); err != nil {
+		return nil, err
+	}
+	list, err := s.branchRepo.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []models.UserBranch{}
+	}
+	return list, nil
+}
+
+// SetUserBranches gives the user access to other branches of the company.
+// The home branch always stays with the role of the user. Only known roles
+// not above the one of the current user can be given. When the access
+// changes the sessions of the user end, so old tokens stop working.
+func (s *UserService) SetUserBranches(ctx context.Context, id, companyID, branchID int, list []models.UserBranch) ([]models.UserBranch, error) {
+	u, err := s.repo.GetByID(ctx, id, companyID, branchID)
+	if err != nil {
+		return nil, err
+	}
+	if roleRanks[u.Role] > callerRank(ctx) {
+		return nil, ErrRoleForbidden
+	}
+	before, err := s.branchRepo.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[int]bool{u.BranchID: true}
+	var res []models.UserBranch
+	for _, ub := range list {
+		if seen[ub.BranchID] {
+			continue
+		}
+		b, err := s.companyRepo.GetBranch(ctx, ub.BranchID)
+		if err != nil || b.CompanyID != companyID {
+			return nil, ErrInvalidUserBranch
+		}
+		seen[ub.BranchID] = true
+		if ub.Role == "" {
+			ub.Role = u.Role
+		}
+		if err := checkRole(ctx, ub.Role); err != nil {
+			return nil, err
+		}
+		res = append(res, models.UserBranch{UserID: id, CompanyID: companyID, BranchID: b.ID, BranchName: b.Name, Role: ub.Role})
+	}
+	if err := s.branchRepo.Replace(ctx, id, u.BranchID, res); err != nil {
+		return nil, err
+	}
+	after, err := s.branchRepo.GetByUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if branchesChanged(before, after) {
+		if err := s.tokenRepo.DeleteByUser(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	s.audit.Record(ctx, "user", id, "branches", before, after)
+	return after, nil
+}
+
+// branchesChanged reports whether a branch was added or removed or a role
+// in some branch is different.
+func branchesChanged(before, after []models.UserBranch) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	roles := make(map[int]string, len(before))
+	for _, ub := range before {
+		roles[ub.BranchID] = ub.Role
+	}
+	for _, ub := range after {
+		if role, ok := roles[ub.BranchID]; !ok || role != ub.Role {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *UserService) saveHomeBranch(ctx context.Context, u *models.User) error {
+	return s.branchRepo.Save(ctx, &models.UserBranch{UserID: u.ID, CompanyID: u.CompanyID, BranchID: u.BranchID, Role: u.Role})
+}
+
+func salaryChanged(old, u *models.User) bool {
+	return old.SalaryShift != u.SalaryShift ||
+		old.SalaryHookah != u.SalaryHookah ||
+		old.HookahSalaryType != u.HookahSalaryType ||
+		old.SalaryBar != u.SalaryBar
+}
+
+func (s *UserService) saveSalaryRate(ctx context.Context, u *models.User) error {
+	effective := time.Now()
+	if u.SalaryEffectiveFrom != nil {
+		effective = *u.SalaryEffectiveFrom
+	}
+	effective = dateOnly(effective)
+	hookahType := u.HookahSalaryType
+	if hookahType == "" {
+		hookahType = "percent"
+	}
+	if err := s.rateRepo.DeleteOnDate(ctx, u.ID, u.CompanyID, u.BranchID, effective.Format("2006-01-02")); err != nil {
+		return err
+	}
+	_, err := s.rateRepo.Create(ctx, &models.UserSalaryRate{
+		UserID:           u.ID,
+		CompanyID:        u.CompanyID,
+		BranchID:         u.BranchID,
+		SalaryShift:      u.SalaryShift,
+		SalaryHookah:     u.SalaryHookah,
+		HookahSalaryType: hookahType,
+		SalaryBar:        u.SalaryBar,
+		EffectiveFrom:    effective,
+	})
+	return err
 }
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"psclub-crm/internal/common"
+	"psclub-crm/internal/models"
+	"psclub-crm/internal/repositories"
+)
+
+// WalletService manages money clients keep on account. The balance is
+// spent on bookings, see BookingService.PayFromWallet.
+type WalletService struct {
+	repo            *repositories.WalletRepository
+	clientRepo      *repositories.ClientRepository
+	paymentTypeRepo *repositories.PaymentTypeRepository
+	cashboxService  *CashboxService
+	audit           *AuditService
+}
+
+func NewWalletService(r *repositories.WalletRepository, clientRepo *repositories.ClientRepository, ptRepo *repositories.PaymentTypeRepository, cbService *CashboxService, audit *AuditService) *WalletService {
+	return &WalletService{
+		repo:            r,
+		clientRepo:      clientRepo,
+		paymentTypeRepo: ptRepo,
+		cashboxService:  cbService,
+		audit:           audit,
+	}
+}
+
+// TopUp puts money on a client wallet with any payment type. The money is
+// income of the top-up, cash goes to the cashbox right away.
+func (s *WalletService) TopUp(ctx context.Context, clientID int, req *models.WalletTopUpRequest) (*models.WalletEntry, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	userID, _ := ctx.Value(common.CtxUserID).(int)
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if _, err := s.clientRepo.GetByID(ctx, clientID); err != nil {
+		return nil, err
+	}
+	pt, err := s.paymentTypeRepo.GetByID(ctx, req.PaymentTypeID)
+	if err != nil {
+		return nil, err
+	}
+	e := &models.WalletEntry{
+		CompanyID:     companyID,
+		BranchID:      branchID,
+		ClientID:      clientID,
+		Kind:          models.WalletTopUp,
+		Amount:        req.Amount,
+		PaymentTypeID: pt.ID,
+		PaymentType:   pt.Name,
+		UserID:        userID,
+		Note:          strings.TrimSpace(req.Note),
+	}
+	if err := s.repo.TopUp(ctx, e); err != nil {
+		return nil, err
+	}
+	if s.cashboxService != nil && isCashType(pt) {
+		_ = s.cashboxService.AddWalletTopUp(ctx, float64(e.Amount))
+	}
+	s.audit.Record(ctx, "client_wallet", clientID, "topup", nil, e)
+	return e, nil
+}
+
+// Entries returns the wallet journal of a client.
+func (s *WalletService) Entries(ctx context.Context, clientID int) ([]models.WalletEntry, error) {
+	companyID := ctx.Value(common.CtxCompanyID).(int)
+	branchID := ctx.Value(common.CtxBranchID).(int)
+	if _, err := s.clientRepo.GetByID(ctx, clientID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetEntries(ctx, companyID, branchID, clientID)
+}